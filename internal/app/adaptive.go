@@ -0,0 +1,351 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
+)
+
+// Adaptive defaults, used for omitted AdaptivePolicy fields.
+const (
+	defaultAdaptiveInterval   = 10 * time.Second
+	defaultAdaptiveMaxLatency = time.Second
+	defaultAdaptiveErrorRatio = 0.05
+	defaultAdaptiveDecrease   = 0.5
+	defaultAdaptiveIncrease   = 1
+	defaultAdaptiveMinSamples = 10
+)
+
+// AdaptivePolicy scales a limited route's rate with upstream health, AIMD
+// style: at the end of each interval the effective rate is multiplied by
+// Decrease when the route's 5xx ratio or mean latency exceeded its threshold,
+// and raised by Increase otherwise, always within [Floor, Ceiling].
+type AdaptivePolicy struct {
+	// Floor is the lowest effective rate; defaults to 1.
+	Floor int
+	// Ceiling is the highest effective rate; defaults to the configured rate.
+	Ceiling int
+	// Interval is how often health is evaluated; defaults to 10s.
+	Interval time.Duration
+	// MaxLatency is the mean latency above which the rate is cut; defaults to 1s.
+	MaxLatency time.Duration
+	// MaxErrorRatio is the 5xx ratio above which the rate is cut; defaults to 0.05.
+	MaxErrorRatio float64
+	// Decrease multiplies the rate on a cut; defaults to 0.5.
+	Decrease float64
+	// Increase is added to the rate after a healthy interval; defaults to 1.
+	Increase int
+	// MinSamples is how many responses an interval needs before it can cut
+	// the rate; defaults to 10.
+	MinSamples int
+}
+
+type adaptivePolicyJSON struct {
+	Floor         int     `json:"floor,omitempty"`
+	Ceiling       int     `json:"ceiling,omitempty"`
+	Interval      string  `json:"interval,omitempty"`
+	MaxLatency    string  `json:"max_latency,omitempty"`
+	MaxErrorRatio float64 `json:"max_error_ratio,omitempty"`
+	Decrease      float64 `json:"decrease,omitempty"`
+	Increase      int     `json:"increase,omitempty"`
+	MinSamples    int     `json:"min_samples,omitempty"`
+}
+
+func (p AdaptivePolicy) MarshalJSON() ([]byte, error) {
+	out := adaptivePolicyJSON{
+		Floor:         p.Floor,
+		Ceiling:       p.Ceiling,
+		MaxErrorRatio: p.MaxErrorRatio,
+		Decrease:      p.Decrease,
+		Increase:      p.Increase,
+		MinSamples:    p.MinSamples,
+	}
+	if p.Interval > 0 {
+		out.Interval = p.Interval.String()
+	}
+	if p.MaxLatency > 0 {
+		out.MaxLatency = p.MaxLatency.String()
+	}
+	return json.Marshal(out)
+}
+
+func (p *AdaptivePolicy) UnmarshalJSON(data []byte) error {
+	var in adaptivePolicyJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*p = AdaptivePolicy{
+		Floor:         in.Floor,
+		Ceiling:       in.Ceiling,
+		MaxErrorRatio: in.MaxErrorRatio,
+		Decrease:      in.Decrease,
+		Increase:      in.Increase,
+		MinSamples:    in.MinSamples,
+	}
+	for _, d := range []struct {
+		name string
+		raw  string
+		dst  *time.Duration
+	}{{"interval", in.Interval, &p.Interval}, {"max_latency", in.MaxLatency, &p.MaxLatency}} {
+		if strings.TrimSpace(d.raw) == "" {
+			continue
+		}
+		v, err := time.ParseDuration(strings.TrimSpace(d.raw))
+		if err != nil {
+			return fmt.Errorf("invalid %s %q: %w", d.name, d.raw, err)
+		}
+		*d.dst = v
+	}
+	return nil
+}
+
+// Validate rejects out-of-range settings; zero values mean the default.
+func (p AdaptivePolicy) Validate() error {
+	switch {
+	case p.Floor < 0 || p.Ceiling < 0 || p.Increase < 0 || p.MinSamples < 0:
+		return fmt.Errorf("adaptive floor, ceiling, increase and min_samples must be >= 0")
+	case p.Ceiling > 0 && p.Floor > p.Ceiling:
+		return fmt.Errorf("adaptive floor %d is above ceiling %d", p.Floor, p.Ceiling)
+	case p.Interval < 0 || p.MaxLatency < 0:
+		return fmt.Errorf("adaptive interval and max_latency must be >= 0")
+	case p.MaxErrorRatio < 0 || p.MaxErrorRatio > 1:
+		return fmt.Errorf("adaptive max_error_ratio must be between 0 and 1, got %g", p.MaxErrorRatio)
+	case p.Decrease < 0 || p.Decrease >= 1:
+		return fmt.Errorf("adaptive decrease must be between 0 and 1, got %g", p.Decrease)
+	}
+	return nil
+}
+
+// withDefaults fills omitted fields; rate is the route's configured rate.
+func (p AdaptivePolicy) withDefaults(rate int) AdaptivePolicy {
+	if p.Ceiling == 0 {
+		p.Ceiling = rate
+	}
+	if p.Floor == 0 {
+		p.Floor = 1
+	}
+	if p.Floor > p.Ceiling {
+		p.Floor = p.Ceiling
+	}
+	if p.Interval == 0 {
+		p.Interval = defaultAdaptiveInterval
+	}
+	if p.MaxLatency == 0 {
+		p.MaxLatency = defaultAdaptiveMaxLatency
+	}
+	if p.MaxErrorRatio == 0 {
+		p.MaxErrorRatio = defaultAdaptiveErrorRatio
+	}
+	if p.Decrease == 0 {
+		p.Decrease = defaultAdaptiveDecrease
+	}
+	if p.Increase == 0 {
+		p.Increase = defaultAdaptiveIncrease
+	}
+	if p.MinSamples == 0 {
+		p.MinSamples = defaultAdaptiveMinSamples
+	}
+	return p
+}
+
+// AdaptiveState is a route's current adaptive limit and the health of its
+// last completed interval.
+type AdaptiveState struct {
+	Rate          int       `json:"effective_rate"`
+	Floor         int       `json:"floor"`
+	Ceiling       int       `json:"ceiling"`
+	Samples       int       `json:"last_samples"`
+	ErrorRatio    float64   `json:"last_error_ratio"`
+	MeanLatencyMS float64   `json:"last_mean_latency_ms"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// adaptiveController tracks one route's health and effective rate. Intervals
+// are measured on the gateway clock, so runs under a virtual clock are
+// deterministic.
+type adaptiveController struct {
+	mu     sync.Mutex
+	policy AdaptivePolicy
+	clk    chronoclock.Clock
+
+	rate        int
+	windowStart time.Time
+	samples     int
+	errors      int
+	latency     time.Duration
+	last        AdaptiveState
+}
+
+func newAdaptiveController(policy AdaptivePolicy, rate int, clk chronoclock.Clock) *adaptiveController {
+	policy = policy.withDefaults(rate)
+	now := clk.Now()
+	return &adaptiveController{
+		policy:      policy,
+		clk:         clk,
+		rate:        policy.Ceiling,
+		windowStart: now,
+		last:        AdaptiveState{Rate: policy.Ceiling, Floor: policy.Floor, Ceiling: policy.Ceiling, UpdatedAt: now},
+	}
+}
+
+// advance closes every interval that ended by now. Only the first holds the
+// collected samples; later ones were idle and count as healthy. Callers hold
+// c.mu.
+func (c *adaptiveController) advance(now time.Time) {
+	for !now.Before(c.windowStart.Add(c.policy.Interval)) {
+		c.windowStart = c.windowStart.Add(c.policy.Interval)
+
+		var ratio float64
+		var mean time.Duration
+		if c.samples > 0 {
+			ratio = float64(c.errors) / float64(c.samples)
+			mean = c.latency / time.Duration(c.samples)
+		}
+		unhealthy := c.samples >= c.policy.MinSamples &&
+			(ratio > c.policy.MaxErrorRatio || mean > c.policy.MaxLatency)
+		if unhealthy {
+			c.rate = max(c.policy.Floor, int(float64(c.rate)*c.policy.Decrease))
+		} else {
+			c.rate = min(c.policy.Ceiling, c.rate+c.policy.Increase)
+		}
+
+		c.last = AdaptiveState{
+			Rate:          c.rate,
+			Floor:         c.policy.Floor,
+			Ceiling:       c.policy.Ceiling,
+			Samples:       c.samples,
+			ErrorRatio:    ratio,
+			MeanLatencyMS: float64(mean) / float64(time.Millisecond),
+			UpdatedAt:     c.windowStart,
+		}
+		c.samples, c.errors, c.latency = 0, 0, 0
+	}
+}
+
+func (c *adaptiveController) observe(status int, latency time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.advance(c.clk.Now())
+	c.samples++
+	if status >= 500 {
+		c.errors++
+	}
+	c.latency += latency
+}
+
+// State returns the current effective rate and last interval's health.
+func (c *adaptiveController) State() AdaptiveState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.advance(c.clk.Now())
+	return c.last
+}
+
+// scale maps a limit expressed at the ceiling to the effective rate.
+func (c *adaptiveController) scale(limit int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.advance(c.clk.Now())
+	if limit == c.policy.Ceiling {
+		return c.rate
+	}
+	return max(1, limit*c.rate/c.policy.Ceiling)
+}
+
+// Middleware measures the status and latency of requests that passed the
+// limiter.
+func (c *adaptiveController) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		start := c.clk.Now()
+		next.ServeHTTP(sw, r)
+		c.observe(sw.status, c.clk.Now().Sub(start))
+	})
+}
+
+// adaptiveLimiter narrows the wrapped limiter, configured at the ceiling,
+// to the effective rate: a request is denied once the window's usage passes
+// the scaled limit.
+type adaptiveLimiter struct {
+	next limiter.Limiter
+	ctrl *adaptiveController
+}
+
+func (l *adaptiveLimiter) Allow(ctx context.Context, key string) limiter.Decision {
+	d := l.next.Allow(ctx, key)
+	limit := l.ctrl.scale(d.Limit)
+	used := d.Limit - d.Remaining
+	if d.Allowed && used > limit {
+		d.Allowed = false
+		d.RetryAt = d.ResetAt
+	}
+	d.Limit = limit
+	d.Remaining = max(0, limit-used)
+	return d
+}
+
+// statusWriter remembers the status code written by a handler.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	wrote  bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wrote {
+		w.status, w.wrote = status, true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	w.wrote = true
+	return w.ResponseWriter.Write(p)
+}
+
+// routeLimitState is one limited route in GET /api/limits.
+type routeLimitState struct {
+	Endpoint  string            `json:"endpoint"`
+	Algorithm limiter.Algorithm `json:"algorithm"`
+	Rate      int               `json:"rate"`
+	Window    string            `json:"window"`
+	Adaptive  *AdaptiveState    `json:"adaptive,omitempty"`
+}
+
+func limitsHandler(cfg Config, adaptive map[string]*adaptiveController, shedder *Shedder) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		routes := make([]routeLimitState, 0)
+		for _, route := range cfg.RouteTable() {
+			if !route.Limited {
+				continue
+			}
+			state := routeLimitState{
+				Endpoint:  route.Endpoint,
+				Algorithm: cfg.Algorithm,
+				Rate:      cfg.Rate,
+				Window:    cfg.Window.String(),
+			}
+			if route.Algorithm != "" {
+				state.Algorithm = route.Algorithm
+			}
+			if ctrl := adaptive[route.Endpoint]; ctrl != nil {
+				s := ctrl.State()
+				state.Rate = s.Rate
+				state.Adaptive = &s
+			}
+			routes = append(routes, state)
+		}
+		body := map[string]any{"routes": routes}
+		if shedder != nil {
+			body["shedding"] = shedder.Stats()
+		}
+		writeJSON(w, http.StatusOK, body)
+	}
+}
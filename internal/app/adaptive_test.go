@@ -0,0 +1,131 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
+)
+
+func TestAdaptiveControllerAIMD(t *testing.T) {
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 2, 8, 21, 0, 0, 0, time.UTC))
+	ctrl := newAdaptiveController(AdaptivePolicy{Floor: 2, Interval: 10 * time.Second, MinSamples: 3}, 10, vc)
+
+	failing := ctrl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	slow := ctrl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		vc.Advance(2 * time.Second)
+	}))
+	serve := func(h http.Handler, n int) {
+		for i := 0; i < n; i++ {
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/profile", nil))
+		}
+	}
+
+	steps := []struct {
+		name string
+		run  func()
+		want int
+	}{
+		{"5xx cuts the rate", func() { serve(failing, 3) }, 5},
+		{"too few samples do not cut", func() { serve(failing, 2) }, 6},
+		{"slow responses cut the rate", func() { serve(slow, 3) }, 3},
+		{"the floor holds", func() { serve(failing, 4) }, 2},
+		{"idle intervals add one each", func() { vc.Advance(20 * time.Second) }, 5},
+	}
+	for _, step := range steps {
+		step.run()
+		vc.Set(vc.Now().Truncate(10 * time.Second).Add(10 * time.Second))
+		if got := ctrl.State().Rate; got != step.want {
+			t.Fatalf("%s: effective rate = %d, want %d", step.name, got, step.want)
+		}
+	}
+
+	vc.Advance(time.Hour)
+	if got := ctrl.State().Rate; got != 10 {
+		t.Fatalf("effective rate after recovery = %d, want ceiling 10", got)
+	}
+}
+
+func TestAdaptiveLimiterNarrowsToEffectiveRate(t *testing.T) {
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 2, 8, 21, 0, 0, 0, time.UTC))
+	ctrl := newAdaptiveController(AdaptivePolicy{Interval: time.Second, MinSamples: 1}, 4, vc)
+	ctrl.observe(http.StatusInternalServerError, 0)
+	vc.Advance(time.Second)
+
+	lim := &adaptiveLimiter{next: limiter.NewFixedWindow(4, time.Minute, vc), ctrl: ctrl}
+	for i := 0; i < 2; i++ {
+		if d := lim.Allow(context.Background(), "k"); !d.Allowed || d.Limit != 2 || d.Remaining != 1-i {
+			t.Fatalf("request %d = %+v, want allowed at limit 2", i, d)
+		}
+	}
+	if d := lim.Allow(context.Background(), "k"); d.Allowed || d.Remaining != 0 || d.RetryAt.IsZero() {
+		t.Fatalf("third request = %+v, want denied", d)
+	}
+}
+
+func TestAdaptiveRoutesFileAndLimitsEndpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.json")
+	body := `[{"endpoint": "GET /api/profile", "adaptive": {"floor": 1, "ceiling": 3, "interval": "5s", "max_latency": "250ms"}}]`
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatalf("write routes: %v", err)
+	}
+	routes, err := LoadRouteTable(path)
+	if err != nil {
+		t.Fatalf("LoadRouteTable() error = %v", err)
+	}
+
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.Routes = routes
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	handler := newAuthTestHandler(t, cfg)
+
+	for i := 0; i < 3; i++ {
+		resp := executeRequest(handler, http.MethodGet, "/api/profile", "adaptive-client", "", "", "")
+		assertStatus(t, resp, http.StatusOK)
+		if got := resp.Header().Get("X-RateLimit-Limit"); got != "3" {
+			t.Fatalf("X-RateLimit-Limit = %q, want ceiling 3", got)
+		}
+	}
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/api/profile", "adaptive-client", "", "", ""), http.StatusTooManyRequests)
+
+	resp := executeRequest(handler, http.MethodGet, "/api/limits", "", "", "", "")
+	assertStatus(t, resp, http.StatusOK)
+	var limits struct {
+		Routes []routeLimitState `json:"routes"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &limits); err != nil {
+		t.Fatalf("decode limits: %v", err)
+	}
+	var found bool
+	for _, route := range limits.Routes {
+		if route.Endpoint != "GET /api/profile" {
+			if route.Adaptive != nil {
+				t.Fatalf("%s reported as adaptive", route.Endpoint)
+			}
+			continue
+		}
+		found = true
+		if route.Rate != 3 || route.Adaptive == nil || route.Adaptive.Floor != 1 || route.Adaptive.Ceiling != 3 {
+			t.Fatalf("profile limits = %+v", route)
+		}
+	}
+	if !found {
+		t.Fatal("GET /api/profile missing from /api/limits")
+	}
+
+	cfg.Routes[2].Adaptive = &AdaptivePolicy{Floor: 5, Ceiling: 2}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("Validate() accepted floor above ceiling")
+	}
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
 )
@@ -26,3 +27,16 @@ func ParseAlgorithm(raw string) (limiter.Algorithm, error) {
 		return "", fmt.Errorf("invalid algorithm %q", raw)
 	}
 }
+
+// ParseAlgorithmList validates and parses a list of limiter algorithm strings.
+func ParseAlgorithmList(values []string) ([]limiter.Algorithm, error) {
+	out := make([]limiter.Algorithm, 0, len(values))
+	for _, raw := range values {
+		algo, err := ParseAlgorithm(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, algo)
+	}
+	return out, nil
+}
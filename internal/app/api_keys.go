@@ -0,0 +1,327 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+)
+
+// ScopeAdmin grants access to the key management API.
+const ScopeAdmin = "admin"
+
+// APIKey is a stored API key. Only the SHA-256 hash of the secret is kept.
+type APIKey struct {
+	ID        string    `json:"id"`
+	Hash      string    `json:"hash,omitempty"`
+	Owner     string    `json:"owner"`
+	Tier      string    `json:"tier,omitempty"`
+	Scopes    []string  `json:"scopes,omitempty"`
+	Revoked   bool      `json:"revoked,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// HasScope reports whether the key holds scope; the admin scope holds every scope.
+func (k APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyStore persists API keys.
+type APIKeyStore interface {
+	// Lookup finds the key whose hash matches secret, revoked or not.
+	Lookup(secret string) (APIKey, bool, error)
+	Get(id string) (APIKey, bool, error)
+	// List returns keys oldest first.
+	List() ([]APIKey, error)
+	Save(key APIKey) error
+	Revoke(id string) (APIKey, bool, error)
+}
+
+// HashAPIKey returns the at-rest form of an API key secret.
+func HashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// NewAPIKey creates a key with a fresh ID and secret. The secret is returned
+// once and never stored.
+func NewAPIKey(owner, tier string, scopes []string, createdAt time.Time) (APIKey, string, error) {
+	if strings.TrimSpace(owner) == "" {
+		return APIKey{}, "", fmt.Errorf("owner is required")
+	}
+	idBuf := make([]byte, 8)
+	secretBuf := make([]byte, 24)
+	if _, err := rand.Read(idBuf); err != nil {
+		return APIKey{}, "", fmt.Errorf("generate api key id: %w", err)
+	}
+	if _, err := rand.Read(secretBuf); err != nil {
+		return APIKey{}, "", fmt.Errorf("generate api key secret: %w", err)
+	}
+	secret := "cg_" + hex.EncodeToString(secretBuf)
+	return APIKey{
+		ID:        "key_" + hex.EncodeToString(idBuf),
+		Hash:      HashAPIKey(secret),
+		Owner:     strings.TrimSpace(owner),
+		Tier:      strings.TrimSpace(tier),
+		Scopes:    append([]string(nil), scopes...),
+		CreatedAt: createdAt.UTC(),
+	}, secret, nil
+}
+
+func cloneAPIKey(in APIKey) APIKey {
+	out := in
+	out.Scopes = append([]string(nil), in.Scopes...)
+	return out
+}
+
+// MemoryAPIKeyStore keeps API keys in process memory.
+type MemoryAPIKeyStore struct {
+	mu     sync.RWMutex
+	keys   map[string]APIKey
+	byHash map[string]string
+}
+
+// NewMemoryAPIKeyStore creates a memory store holding keys.
+func NewMemoryAPIKeyStore(keys ...APIKey) *MemoryAPIKeyStore {
+	s := &MemoryAPIKeyStore{keys: make(map[string]APIKey), byHash: make(map[string]string)}
+	for _, key := range keys {
+		s.put(key)
+	}
+	return s
+}
+
+func (s *MemoryAPIKeyStore) put(key APIKey) {
+	if old, ok := s.keys[key.ID]; ok {
+		delete(s.byHash, old.Hash)
+	}
+	s.keys[key.ID] = cloneAPIKey(key)
+	s.byHash[key.Hash] = key.ID
+}
+
+func (s *MemoryAPIKeyStore) Lookup(secret string) (APIKey, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	id, ok := s.byHash[HashAPIKey(secret)]
+	if !ok {
+		return APIKey{}, false, nil
+	}
+	return cloneAPIKey(s.keys[id]), true, nil
+}
+
+func (s *MemoryAPIKeyStore) Get(id string) (APIKey, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[id]
+	return cloneAPIKey(key), ok, nil
+}
+
+func (s *MemoryAPIKeyStore) List() ([]APIKey, error) {
+	s.mu.RLock()
+	out := make([]APIKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		out = append(out, cloneAPIKey(key))
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+func (s *MemoryAPIKeyStore) Save(key APIKey) error {
+	if err := validateAPIKey(key); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id, ok := s.byHash[key.Hash]; ok && id != key.ID {
+		return fmt.Errorf("api key %q has the same secret as %q", key.ID, id)
+	}
+	s.put(key)
+	return nil
+}
+
+func (s *MemoryAPIKeyStore) Revoke(id string) (APIKey, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[id]
+	if !ok {
+		return APIKey{}, false, nil
+	}
+	key.Revoked = true
+	s.keys[id] = key
+	return cloneAPIKey(key), true, nil
+}
+
+func validateAPIKey(key APIKey) error {
+	if strings.TrimSpace(key.ID) == "" {
+		return fmt.Errorf("api key id is required")
+	}
+	if strings.HasPrefix(key.ID, jwtKeyPrefix) {
+		return fmt.Errorf("api key %q: ids must not start with %q", key.ID, jwtKeyPrefix)
+	}
+	if !strings.HasPrefix(key.Hash, "sha256:") {
+		return fmt.Errorf("api key %q: hash must be sha256:<hex>", key.ID)
+	}
+	return nil
+}
+
+// apiKeysReloadInterval is how often a FileAPIKeyStore looks for changes
+// other processes made to its file.
+const apiKeysReloadInterval = 5 * time.Second
+
+// FileAPIKeyStore keeps API keys in a JSON array file. Lookups check the file
+// for changes at most every apiKeysReloadInterval, so keys added with
+// "chronogate keys" apply to a running server within that interval; its own
+// writes apply at once.
+type FileAPIKeyStore struct {
+	mu        sync.Mutex
+	path      string
+	clk       chronoclock.Clock
+	checkedAt time.Time
+	modTime   time.Time
+	size      int64
+	mem       *MemoryAPIKeyStore
+}
+
+// NewFileAPIKeyStore creates a store backed by path; a missing file is an empty store.
+func NewFileAPIKeyStore(path string, clk chronoclock.Clock) *FileAPIKeyStore {
+	return &FileAPIKeyStore{path: path, clk: clk}
+}
+
+// load refreshes the in-memory copy when the file changed. Unless fresh is
+// set, a copy checked within apiKeysReloadInterval is used as is. Callers
+// hold s.mu.
+func (s *FileAPIKeyStore) load(fresh bool) (*MemoryAPIKeyStore, error) {
+	now := s.clk.Now()
+	if !fresh && s.mem != nil && now.Sub(s.checkedAt) < apiKeysReloadInterval {
+		return s.mem, nil
+	}
+	info, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		if s.mem == nil {
+			s.mem = NewMemoryAPIKeyStore()
+		}
+		s.checkedAt = now
+		return s.mem, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("stat api keys file: %w", err)
+	}
+	if s.mem != nil && info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		s.checkedAt = now
+		return s.mem, nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("read api keys file: %w", err)
+	}
+	var keys []APIKey
+	if len(strings.TrimSpace(string(data))) > 0 {
+		if err := json.Unmarshal(data, &keys); err != nil {
+			return nil, fmt.Errorf("decode api keys file: %w", err)
+		}
+	}
+	mem := NewMemoryAPIKeyStore()
+	for _, key := range keys {
+		if err := mem.Save(key); err != nil {
+			return nil, fmt.Errorf("api keys file: %w", err)
+		}
+	}
+	s.mem, s.modTime, s.size, s.checkedAt = mem, info.ModTime(), info.Size(), now
+	return mem, nil
+}
+
+func (s *FileAPIKeyStore) write(mem *MemoryAPIKeyStore) error {
+	keys, _ := mem.List()
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode api keys: %w", err)
+	}
+	// On failure the cached copy, already changed by the caller, is dropped
+	// so the next load goes back to what is on disk.
+	if err := writeFileAtomic(s.path, data, 0o600); err != nil {
+		s.mem = nil
+		return fmt.Errorf("write api keys file: %w", err)
+	}
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime, s.size = info.ModTime(), info.Size()
+	}
+	return nil
+}
+
+func (s *FileAPIKeyStore) Lookup(secret string) (APIKey, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mem, err := s.load(false)
+	if err != nil {
+		return APIKey{}, false, err
+	}
+	return mem.Lookup(secret)
+}
+
+func (s *FileAPIKeyStore) Get(id string) (APIKey, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mem, err := s.load(false)
+	if err != nil {
+		return APIKey{}, false, err
+	}
+	return mem.Get(id)
+}
+
+func (s *FileAPIKeyStore) List() ([]APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mem, err := s.load(false)
+	if err != nil {
+		return nil, err
+	}
+	return mem.List()
+}
+
+func (s *FileAPIKeyStore) Save(key APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mem, err := s.load(true)
+	if err != nil {
+		return err
+	}
+	if err := mem.Save(key); err != nil {
+		return err
+	}
+	return s.write(mem)
+}
+
+func (s *FileAPIKeyStore) Revoke(id string) (APIKey, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mem, err := s.load(true)
+	if err != nil {
+		return APIKey{}, false, err
+	}
+	key, ok, _ := mem.Revoke(id)
+	if !ok {
+		return APIKey{}, false, nil
+	}
+	return key, true, s.write(mem)
+}
@@ -0,0 +1,264 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+)
+
+type apiKeyContextKey struct{}
+
+// WithAPIKey returns ctx carrying the authenticated key.
+func WithAPIKey(ctx context.Context, key APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, key)
+}
+
+// APIKeyFromContext returns the key authenticated for the request, if any.
+func APIKeyFromContext(ctx context.Context) (APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey{}).(APIKey)
+	return key, ok
+}
+
+// Authenticator validates X-API-Key against a key store, or bearer JWTs with
+// a JWTVerifier. An optional bootstrap admin secret is accepted without being
+// stored, so the key API can be used before any key exists.
+type Authenticator struct {
+	store     APIKeyStore
+	adminHash string
+	jwt       *JWTVerifier
+}
+
+// NewAuthenticator creates an authenticator over store; adminSecret may be
+// empty, and a nil store disables API keys.
+func NewAuthenticator(store APIKeyStore, adminSecret string) *Authenticator {
+	a := &Authenticator{store: store}
+	if adminSecret != "" {
+		a.adminHash = HashAPIKey(adminSecret)
+	}
+	return a
+}
+
+// WithJWT also accepts "Authorization: Bearer" tokens verified by v.
+func (a *Authenticator) WithJWT(v *JWTVerifier) *Authenticator {
+	a.jwt = v
+	return a
+}
+
+// Store returns the underlying key store; nil when API keys are disabled.
+func (a *Authenticator) Store() APIKeyStore {
+	return a.store
+}
+
+// Middleware authenticates requests and requires every scope in scopes. It
+// runs before recording and rate limiting, so rejected keys consume no budget.
+func (a *Authenticator) Middleware(scopes []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token, ok := bearerToken(r); ok && a.jwt != nil {
+				a.serveJWT(w, r, token, scopes, next)
+				return
+			}
+			if a.store == nil && a.adminHash == "" {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, r, http.StatusUnauthorized, ErrCodeUnauthorized, "bearer token is required")
+				return
+			}
+
+			secret := strings.TrimSpace(r.Header.Get("X-API-Key"))
+			if secret == "" {
+				w.Header().Set("WWW-Authenticate", `ApiKey header="X-API-Key"`)
+				writeError(w, r, http.StatusUnauthorized, ErrCodeUnauthorized, "X-API-Key header is required")
+				return
+			}
+
+			key, ok, err := a.lookup(secret)
+			if err != nil {
+				log.Printf("authenticate api key: %v", err)
+				writeError(w, r, http.StatusServiceUnavailable, ErrCodeAuthUnavailable, "api key store unavailable")
+				return
+			}
+			if !ok || key.Revoked {
+				w.Header().Set("WWW-Authenticate", `ApiKey header="X-API-Key"`)
+				writeError(w, r, http.StatusUnauthorized, ErrCodeUnauthorized, "invalid or revoked api key")
+				return
+			}
+			for _, scope := range scopes {
+				if !key.HasScope(scope) {
+					writeError(w, r, http.StatusForbidden, ErrCodeInsufficientScope, fmt.Sprintf("api key lacks scope %q", scope))
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithAPIKey(r.Context(), key)))
+		})
+	}
+}
+
+type apiKeysContextKey struct{}
+
+// Identify resolves X-API-Key on every route, including those that do not
+// require auth, so a secret never becomes a rate-limit, storage or usage
+// key: a valid key is put in the context, and anything else leaves the
+// request to be keyed by its client address. Routes that require auth check
+// the key again in Middleware. Without API keys it does nothing.
+func (a *Authenticator) Identify(next http.Handler) http.Handler {
+	if a.store == nil && a.adminHash == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), apiKeysContextKey{}, true)
+		if secret := strings.TrimSpace(r.Header.Get("X-API-Key")); secret != "" {
+			if key, ok, err := a.lookup(secret); err == nil && ok && !key.Revoked {
+				ctx = WithAPIKey(ctx, key)
+			}
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// apiKeysEnabled reports whether Identify saw the request, so X-API-Key is
+// a secret rather than a plain rate-limit key.
+func apiKeysEnabled(ctx context.Context) bool {
+	on, _ := ctx.Value(apiKeysContextKey{}).(bool)
+	return on
+}
+
+func (a *Authenticator) serveJWT(w http.ResponseWriter, r *http.Request, token string, scopes []string, next http.Handler) {
+	id, err := a.jwt.Verify(token)
+	if errors.Is(err, errJWKSUnavailable) {
+		log.Printf("verify bearer token: %v", err)
+		writeError(w, r, http.StatusServiceUnavailable, ErrCodeAuthUnavailable, "token verification keys unavailable")
+		return
+	}
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		writeError(w, r, http.StatusUnauthorized, ErrCodeUnauthorized, "invalid bearer token: "+err.Error())
+		return
+	}
+	for _, scope := range scopes {
+		if !id.HasScope(scope) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+			writeError(w, r, http.StatusForbidden, ErrCodeInsufficientScope, fmt.Sprintf("token lacks scope %q", scope))
+			return
+		}
+	}
+	next.ServeHTTP(w, r.WithContext(WithJWTIdentity(r.Context(), id)))
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(r.Header.Get("Authorization")), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+func (a *Authenticator) lookup(secret string) (APIKey, bool, error) {
+	if a.adminHash != "" && subtle.ConstantTimeCompare([]byte(HashAPIKey(secret)), []byte(a.adminHash)) == 1 {
+		return APIKey{ID: "bootstrap-admin", Owner: "bootstrap", Scopes: []string{ScopeAdmin}}, true, nil
+	}
+	if a.store == nil {
+		return APIKey{}, false, nil
+	}
+	return a.store.Lookup(secret)
+}
+
+func newAuthenticator(cfg Config, clk chronoclock.Clock) *Authenticator {
+	if !cfg.AuthEnabled() {
+		return nil
+	}
+	var store APIKeyStore
+	if cfg.APIKeysEnabled() {
+		store = NewMemoryAPIKeyStore()
+		if path := strings.TrimSpace(cfg.APIKeysFile); path != "" {
+			store = NewFileAPIKeyStore(path, clk)
+		}
+	}
+	auth := NewAuthenticator(store, cfg.AdminAPIKey)
+	if cfg.JWT.enabled() {
+		auth.WithJWT(NewJWTVerifier(cfg.JWT, clk))
+	}
+	return auth
+}
+
+type createAPIKeyRequest struct {
+	Owner  string   `json:"owner"`
+	Tier   string   `json:"tier"`
+	Scopes []string `json:"scopes"`
+}
+
+// publicAPIKey strips the hash before a key leaves the server.
+func publicAPIKey(key APIKey) APIKey {
+	key.Hash = ""
+	return key
+}
+
+func apiKeyListHandler(store APIKeyStore) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		keys, err := store.List()
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, ErrCodeAuthUnavailable, err.Error())
+			return
+		}
+		for i := range keys {
+			keys[i] = publicAPIKey(keys[i])
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"count": len(keys), "keys": keys})
+	}
+}
+
+func apiKeyCreateHandler(store APIKeyStore, now func() time.Time) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req createAPIKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid JSON body")
+			return
+		}
+		key, secret, err := NewAPIKey(req.Owner, req.Tier, req.Scopes, now())
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+			return
+		}
+		if err := store.Save(key); err != nil {
+			writeError(w, r, http.StatusInternalServerError, ErrCodeAuthUnavailable, err.Error())
+			return
+		}
+		w.Header().Set("Location", "/admin/keys/"+key.ID)
+		writeJSON(w, http.StatusCreated, map[string]any{"key": publicAPIKey(key), "secret": secret})
+	}
+}
+
+func apiKeyGetHandler(store APIKeyStore) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeAPIKey(w, r, store.Get)
+	}
+}
+
+func apiKeyRevokeHandler(store APIKeyStore) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeAPIKey(w, r, store.Revoke)
+	}
+}
+
+// writeAPIKey looks up the {id} key with lookup (Get or Revoke) and writes it.
+func writeAPIKey(w http.ResponseWriter, r *http.Request, lookup func(string) (APIKey, bool, error)) {
+	id := r.PathValue("id")
+	key, ok, err := lookup(id)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, ErrCodeAuthUnavailable, err.Error())
+		return
+	}
+	if !ok {
+		writeError(w, r, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("api key %q not found", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"key": publicAPIKey(key)})
+}
@@ -0,0 +1,227 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+)
+
+func newAuthTestHandler(t *testing.T, cfg Config) http.Handler {
+	t.Helper()
+	return newAuthTestHandlerAt(t, cfg, time.Date(2026, 2, 8, 19, 0, 0, 0, time.UTC))
+}
+
+func newAuthTestHandlerAt(t *testing.T, cfg Config, now time.Time) http.Handler {
+	t.Helper()
+	vc := chronoclock.NewVirtualClock(now)
+	mainLimiter, mainStorage, err := NewStorageBackedLimiter(cfg, vc)
+	if err != nil {
+		t.Fatalf("NewStorageBackedLimiter() error = %v", err)
+	}
+	t.Cleanup(func() { _ = mainStorage.Close() })
+	storageSet, cleanup := newMemoryOnlyStorageSet(t, cfg, vc)
+	t.Cleanup(cleanup)
+	return NewHandler(cfg, mainLimiter, vc, chronorecorder.New(nil), storageSet)
+}
+
+func TestAuthRejectsUnknownKeysBeforeRateLimiting(t *testing.T) {
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.Rate = 1
+	cfg.AdminAPIKey = "bootstrap-secret"
+	cfg.Routes = DefaultRouteTable()
+	for i := range cfg.Routes {
+		if cfg.Routes[i].Endpoint == "POST /api/orders" {
+			cfg.Routes[i].Scopes = []string{"orders:write"}
+		}
+	}
+	handler := newAuthTestHandler(t, cfg)
+
+	resp := executeRequest(handler, http.MethodGet, "/api/profile", "", "", "", "198.51.100.70:8080")
+	assertStatus(t, resp, http.StatusUnauthorized)
+	if resp.Header().Get("WWW-Authenticate") == "" {
+		t.Fatal("401 without WWW-Authenticate")
+	}
+	resp = executeRequest(handler, http.MethodGet, "/api/profile", "guess", "", "", "198.51.100.70:8080")
+	assertStatus(t, resp, http.StatusUnauthorized)
+	if resp.Header().Get("X-RateLimit-Limit") != "" {
+		t.Fatal("unknown key reached the rate limiter")
+	}
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/public", "", "", "", "198.51.100.70:8080"), http.StatusOK)
+
+	resp = executeRequest(handler, http.MethodPost, "/admin/keys", "bootstrap-secret", "", `{"owner":"alice","tier":"pro","scopes":["read"]}`, "198.51.100.70:8080")
+	assertStatus(t, resp, http.StatusCreated)
+	var created struct {
+		Key    APIKey `json:"key"`
+		Secret string `json:"secret"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode created key: %v", err)
+	}
+	if created.Secret == "" || created.Key.Hash != "" || created.Key.Tier != "pro" {
+		t.Fatalf("created = %+v", created)
+	}
+
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/api/profile", created.Secret, "", "", "198.51.100.70:8080"), http.StatusOK)
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/api/profile", created.Secret, "", "", "198.51.100.70:8080"), http.StatusTooManyRequests)
+
+	resp = executeRequest(handler, http.MethodPost, "/api/orders", created.Secret, "", "{}", "198.51.100.70:8080")
+	assertStatus(t, resp, http.StatusForbidden)
+	var problem map[string]any
+	if err := json.Unmarshal(resp.Body.Bytes(), &problem); err != nil || problem["error"] != ErrCodeInsufficientScope {
+		t.Fatalf("403 body = %s", resp.Body.String())
+	}
+
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/admin/keys", created.Secret, "", "", "198.51.100.70:8080"), http.StatusForbidden)
+
+	req := httptest.NewRequest(http.MethodDelete, "/admin/keys/"+created.Key.ID, nil)
+	req.Header.Set("X-API-Key", "bootstrap-secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assertStatus(t, rec, http.StatusOK)
+
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/api/fixed-window", created.Secret, "", "", "198.51.100.70:8080"), http.StatusUnauthorized)
+}
+
+func TestAuthAttachesKeyAndUsesItsIDAsRateLimitKey(t *testing.T) {
+	key, secret, err := NewAPIKey("bob", "free", nil, time.Now())
+	if err != nil {
+		t.Fatalf("NewAPIKey() error = %v", err)
+	}
+	auth := NewAuthenticator(NewMemoryAPIKeyStore(key), "")
+
+	var seen APIKey
+	var rateKey string
+	handler := auth.Middleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen, _ = APIKeyFromContext(r.Context())
+		rateKey = clientKeyFromRequest(r)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/api/profile", nil)
+	req.Header.Set("X-API-Key", secret)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if seen.Owner != "bob" || seen.Tier != "free" {
+		t.Fatalf("context key = %+v", seen)
+	}
+	if rateKey != key.ID || strings.Contains(rateKey, secret) {
+		t.Fatalf("rate-limit key = %q, want key ID %q", rateKey, key.ID)
+	}
+}
+
+func TestFileAPIKeyStoreHashesAndReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 2, 8, 19, 0, 0, 0, time.UTC))
+	store := NewFileAPIKeyStore(path, vc)
+
+	key, secret, err := NewAPIKey("carol", "", []string{"read"}, time.Now())
+	if err != nil {
+		t.Fatalf("NewAPIKey() error = %v", err)
+	}
+	if err := store.Save(key); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read keys file: %v", err)
+	}
+	if strings.Contains(string(data), secret) || !strings.Contains(string(data), HashAPIKey(secret)) {
+		t.Fatalf("keys file should hold only the hash: %s", data)
+	}
+
+	// A second store (another process) revokes the key; the first sees it
+	// once the reload interval has passed, without a stat per lookup before.
+	if _, ok, err := NewFileAPIKeyStore(path, vc).Revoke(key.ID); err != nil || !ok {
+		t.Fatalf("Revoke() = %v, %v", ok, err)
+	}
+	if got, ok, err := store.Lookup(secret); err != nil || !ok || got.Revoked {
+		t.Fatalf("Lookup() within the reload interval = %+v, %v, %v; want the cached key", got, ok, err)
+	}
+	// Writes always start from the file, so they keep the other's change.
+	other, _, err := NewAPIKey("erin", "", nil, time.Now())
+	if err != nil {
+		t.Fatalf("NewAPIKey() error = %v", err)
+	}
+	if err := store.Save(other); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if got, _, _ := NewFileAPIKeyStore(path, vc).Get(key.ID); !got.Revoked {
+		t.Fatal("Save() overwrote another process's revocation")
+	}
+	vc.Advance(apiKeysReloadInterval)
+	got, ok, err := store.Lookup(secret)
+	if err != nil || !ok || !got.Revoked {
+		t.Fatalf("Lookup() = %+v, %v, %v; want revoked key", got, ok, err)
+	}
+}
+
+func TestFileAPIKeyStoreRevokeRollsBackOnWriteFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	store := NewFileAPIKeyStore(path, chronoclock.NewVirtualClock(time.Date(2026, 2, 8, 19, 0, 0, 0, time.UTC)))
+	key, secret, err := NewAPIKey("dave", "", nil, time.Now())
+	if err != nil {
+		t.Fatalf("NewAPIKey() error = %v", err)
+	}
+	if err := store.Save(key); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// A directory in the way of the temp file makes the write fail.
+	if err := os.Mkdir(path+".tmp", 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if _, _, err := store.Revoke(key.ID); err == nil {
+		t.Fatal("Revoke() error = nil, want write failure")
+	}
+	got, ok, err := store.Lookup(secret)
+	if err != nil || !ok || got.Revoked {
+		t.Fatalf("Lookup() = %+v, %v, %v; want the key still active", got, ok, err)
+	}
+}
+
+func TestOperationalEndpointsNeedAdminScopeWithAuth(t *testing.T) {
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.AuthRequired = true
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("Validate() accepted AUTH_REQUIRED without an admin key or keys file")
+	}
+	cfg.AdminAPIKey = "bootstrap-secret"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	handler := newAuthTestHandler(t, cfg)
+
+	resp := adminRequest(handler, http.MethodPost, "/admin/keys", `{"owner":"eve"}`)
+	assertStatus(t, resp, http.StatusCreated)
+	var created struct {
+		Secret string `json:"secret"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode created key: %v", err)
+	}
+
+	for _, tc := range []struct{ method, path string }{
+		{http.MethodPost, "/api/record/start"},
+		{http.MethodPost, "/api/record/stop"},
+		{http.MethodPost, "/api/replay"},
+		{http.MethodGet, "/api/replay/last"},
+		{http.MethodGet, "/api/replay/jobs"},
+		{http.MethodGet, "/api/replays"},
+		{http.MethodGet, "/api/recordings/export"},
+		{http.MethodPut, "/api/storage/demo"},
+		{http.MethodPost, "/api/storage/demo"},
+		{http.MethodGet, "/api/limits"},
+	} {
+		assertStatus(t, executeRequest(handler, tc.method, tc.path, "", "", "", ""), http.StatusUnauthorized)
+		assertStatus(t, executeRequest(handler, tc.method, tc.path, created.Secret, "", "", ""), http.StatusForbidden)
+	}
+	assertStatus(t, adminRequest(handler, http.MethodPost, "/api/record/start", ""), http.StatusOK)
+	assertStatus(t, adminRequest(handler, http.MethodGet, "/api/limits", ""), http.StatusOK)
+}
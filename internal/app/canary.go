@@ -0,0 +1,252 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
+)
+
+// canaryBuckets is the rollout resolution: 10000 buckets allow 0.01% steps.
+const canaryBuckets = 10000
+
+// CanaryPolicy enforces a policy for Percent of clients. A key's bucket
+// comes from a stable hash, so raising the percentage only adds clients and
+// the same key stays in or out across requests and restarts.
+type CanaryPolicy struct {
+	LimitPolicy
+	Percent float64 `json:"percent"`
+}
+
+// Validate checks the policy and that Percent is within [0, 100].
+func (p CanaryPolicy) Validate() error {
+	if err := validateCanaryPercent(p.Percent); err != nil {
+		return err
+	}
+	return p.LimitPolicy.Validate()
+}
+
+func validateCanaryPercent(percent float64) error {
+	if percent < 0 || percent > 100 {
+		return fmt.Errorf("canary percent must be within [0, 100], got %g", percent)
+	}
+	return nil
+}
+
+// inCanary reports whether key falls within the first percent of buckets.
+// The hash is salted with the route's endpoint, so each route's canary
+// samples a different set of clients.
+func inCanary(endpoint, key string, percent float64) bool {
+	h := fnv.New32a()
+	h.Write([]byte(endpoint))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return float64(h.Sum32()%canaryBuckets) < percent*canaryBuckets/100
+}
+
+// CanaryState is a route's canary policy and how much traffic it enforced.
+type CanaryState struct {
+	CanaryPolicy
+	Since    time.Time `json:"since"`
+	Requests int64     `json:"requests"`
+	// Enforced counts the requests whose key was in the canary.
+	Enforced int64 `json:"enforced"`
+}
+
+type canaryPolicy struct {
+	policy   CanaryPolicy
+	lim      limiter.Limiter
+	since    time.Time
+	requests int64
+	enforced int64
+}
+
+// setCanary replaces the canary policy and resets its counters.
+func (rp *routePolicy) setCanary(p CanaryPolicy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	lim, err := rp.newLimiter("canary", p.LimitPolicy)
+	if err != nil {
+		return err
+	}
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.canary = &canaryPolicy{policy: p, lim: lim, since: rp.clk.Now()}
+	return nil
+}
+
+// setCanaryPercent changes the rollout of the current canary policy.
+func (rp *routePolicy) setCanaryPercent(percent float64) (bool, error) {
+	if err := validateCanaryPercent(percent); err != nil {
+		return false, err
+	}
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if rp.canary == nil {
+		return false, nil
+	}
+	rp.canary.policy.Percent = percent
+	return true, nil
+}
+
+func (rp *routePolicy) clearCanary() bool {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	had := rp.canary != nil
+	rp.canary = nil
+	return had
+}
+
+// promoteCanary enforces the canary policy for every client.
+func (rp *routePolicy) promoteCanary() bool {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if rp.canary == nil {
+		return false
+	}
+	p := rp.canary.policy.LimitPolicy
+	rp.promoted, rp.enforcing, rp.canary = &p, rp.canary.lim, nil
+	return true
+}
+
+// canaryChoice is the canary decision for one request.
+type canaryChoice struct {
+	lim     limiter.Limiter
+	percent float64
+	in      bool
+}
+
+type canaryContextKey struct{}
+
+// canaryFromContext returns the canary limiter chosen for the request, if
+// its key is in the canary.
+func canaryFromContext(ctx context.Context) limiter.Limiter {
+	if c, ok := ctx.Value(canaryContextKey{}).(canaryChoice); ok && c.in {
+		return c.lim
+	}
+	return nil
+}
+
+// choose places key in or out of the canary and counts the request.
+func (rp *routePolicy) choose(key string) (canaryChoice, bool) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	c := rp.canary
+	if c == nil {
+		return canaryChoice{}, false
+	}
+	choice := canaryChoice{lim: c.lim, percent: c.policy.Percent, in: inCanary(rp.endpoint, key, c.policy.Percent)}
+	c.requests++
+	if choice.in {
+		c.enforced++
+	}
+	return choice, true
+}
+
+// canaryMiddleware decides, before the rate limiter, whether the request's
+// key is in the route's canary, and reports the decision in X-Canary-Percent
+// and X-Canary. Requests with token claim or tenant limits are left out.
+func (rp *routePolicy) canaryMiddleware(overridden func(*http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isRateLimitExempt(r.Context()) || overridden(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			choice, ok := rp.choose(tenantKey(r.Context(), clientKeyFromRequest(r)))
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("X-Canary-Percent", strconv.FormatFloat(choice.percent, 'f', -1, 64))
+			w.Header().Set("X-Canary", strconv.FormatBool(choice.in))
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), canaryContextKey{}, choice)))
+		})
+	}
+}
+
+// canaryRequest is the body of the /admin/canary endpoints.
+type canaryRequest struct {
+	Endpoint string `json:"endpoint"`
+	CanaryPolicy
+}
+
+// canaryPutHandler serves PUT /admin/canary: it sets a route's canary policy.
+func canaryPutHandler(p *routePolicies) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req canaryRequest
+		rp, ok := p.lookup(w, r, &req, &req.Endpoint)
+		if !ok {
+			return
+		}
+		if err := rp.setCanary(req.CanaryPolicy); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, rp.state())
+	}
+}
+
+// canaryPatchHandler serves PATCH /admin/canary: it changes only the
+// rollout percentage of a route's canary policy.
+func canaryPatchHandler(p *routePolicies) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Endpoint string   `json:"endpoint"`
+			Percent  *float64 `json:"percent"`
+		}
+		rp, ok := p.lookup(w, r, &req, &req.Endpoint)
+		if !ok {
+			return
+		}
+		if req.Percent == nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "field 'percent' is required")
+			return
+		}
+		found, err := rp.setCanaryPercent(*req.Percent)
+		if err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+			return
+		}
+		if !found {
+			writeError(w, r, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("no canary policy for %q", req.Endpoint))
+			return
+		}
+		writeJSON(w, http.StatusOK, rp.state())
+	}
+}
+
+// canaryDeleteHandler serves DELETE /admin/canary?endpoint=.
+func canaryDeleteHandler(p *routePolicies) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		endpoint := r.URL.Query().Get("endpoint")
+		rp := p.get(endpoint)
+		if rp == nil || !rp.clearCanary() {
+			writeError(w, r, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("no canary policy for %q", endpoint))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// canaryPromoteHandler serves POST /admin/canary/promote: the route's canary
+// policy is enforced for every client.
+func canaryPromoteHandler(p *routePolicies) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req canaryRequest
+		rp, ok := p.lookup(w, r, &req, &req.Endpoint)
+		if !ok {
+			return
+		}
+		if !rp.promoteCanary() {
+			writeError(w, r, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("no canary policy for %q", req.Endpoint))
+			return
+		}
+		writeJSON(w, http.StatusOK, rp.state())
+	}
+}
@@ -0,0 +1,139 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
+)
+
+func TestCanaryEnforcesStableSubsetOfKeys(t *testing.T) {
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.Rate = 5
+	cfg.Window = time.Minute
+	cfg.AdminAPIKey = "bootstrap-secret"
+	cfg.Routes = DefaultRouteTable()
+	for i := range cfg.Routes {
+		if cfg.Routes[i].Endpoint == "GET /api/profile" {
+			cfg.Routes[i].Authenticated = false
+			cfg.Routes[i].Canary = &CanaryPolicy{LimitPolicy: LimitPolicy{Limits: PlanLimits{Rate: 2}}, Percent: 50}
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	handler := newAuthTestHandler(t, cfg)
+
+	// With auth on, clients without a valid key are keyed by address.
+	var in, out string
+	for i := 1; in == "" || out == ""; i++ {
+		key := fmt.Sprintf("198.51.100.%d", i)
+		if inCanary("GET /api/profile", key, 50) {
+			in = key
+		} else {
+			out = key
+		}
+	}
+
+	for _, tc := range []struct {
+		key       string
+		canary    string
+		limit     string
+		allowedTo int
+	}{{in, "true", "2", 2}, {out, "false", "5", 5}} {
+		for i := 0; i < tc.allowedTo; i++ {
+			resp := executeRequest(handler, http.MethodGet, "/api/profile", "", "", "", tc.key+":4000")
+			assertStatus(t, resp, http.StatusOK)
+			if got := resp.Header().Get("X-Canary"); got != tc.canary {
+				t.Fatalf("%s X-Canary = %q, want %q", tc.key, got, tc.canary)
+			}
+			if got := resp.Header().Get("X-Canary-Percent"); got != "50" {
+				t.Fatalf("%s X-Canary-Percent = %q, want 50", tc.key, got)
+			}
+			if got := resp.Header().Get("X-RateLimit-Limit"); got != tc.limit {
+				t.Fatalf("%s X-RateLimit-Limit = %q, want %q", tc.key, got, tc.limit)
+			}
+		}
+		assertStatus(t, executeRequest(handler, http.MethodGet, "/api/profile", "", "", "", tc.key+":4000"), http.StatusTooManyRequests)
+	}
+
+	// Routes without a canary do not report one.
+	resp := executeRequest(handler, http.MethodPost, "/api/orders", "", "", `{}`, in+":4000")
+	if got := resp.Header().Get("X-Canary"); got != "" {
+		t.Fatalf("orders X-Canary = %q, want none", got)
+	}
+
+	resp = adminRequest(handler, http.MethodPatch, "/admin/canary", `{"endpoint":"GET /api/profile","percent":100}`)
+	assertStatus(t, resp, http.StatusOK)
+	var state RoutePolicyState
+	if err := json.Unmarshal(resp.Body.Bytes(), &state); err != nil {
+		t.Fatalf("decode canary state: %v", err)
+	}
+	if state.Canary == nil || state.Canary.Percent != 100 || state.Canary.Requests != 9 || state.Canary.Enforced != 3 {
+		t.Fatalf("canary state = %s", resp.Body.String())
+	}
+	// A key joining the canary starts fresh in the canary policy's limiter.
+	resp = executeRequest(handler, http.MethodGet, "/api/profile", "", "", "", out+":4000")
+	assertStatus(t, resp, http.StatusOK)
+	if got := resp.Header().Get("X-Canary"); got != "true" || resp.Header().Get("X-RateLimit-Limit") != "2" {
+		t.Fatalf("at 100%% X-Canary = %q, X-RateLimit-Limit = %q", got, resp.Header().Get("X-RateLimit-Limit"))
+	}
+
+	assertStatus(t, adminRequest(handler, http.MethodPatch, "/admin/canary", `{"endpoint":"GET /api/profile","percent":101}`), http.StatusBadRequest)
+	assertStatus(t, adminRequest(handler, http.MethodPatch, "/admin/canary", `{"endpoint":"GET /api/profile"}`), http.StatusBadRequest)
+	assertStatus(t, adminRequest(handler, http.MethodPatch, "/admin/canary", `{"endpoint":"POST /api/orders","percent":10}`), http.StatusNotFound)
+
+	resp = adminRequest(handler, http.MethodPost, "/admin/canary/promote", `{"endpoint":"GET /api/profile"}`)
+	assertStatus(t, resp, http.StatusOK)
+	if !strings.Contains(resp.Body.String(), `"promoted":{"limits":{"rate":2}}`) || strings.Contains(resp.Body.String(), `"canary"`) {
+		t.Fatalf("promote body = %s", resp.Body.String())
+	}
+	resp = executeRequest(handler, http.MethodGet, "/api/profile", "", "", "", "203.0.113.1:4000")
+	if got := resp.Header().Get("X-RateLimit-Limit"); got != "2" || resp.Header().Get("X-Canary") != "" {
+		t.Fatalf("after promote X-RateLimit-Limit = %q, X-Canary = %q", got, resp.Header().Get("X-Canary"))
+	}
+
+	assertStatus(t, adminRequest(handler, http.MethodPut, "/admin/canary", `{"endpoint":"POST /api/orders","limits":{"rate":1},"percent":150}`), http.StatusBadRequest)
+	assertStatus(t, adminRequest(handler, http.MethodPut, "/admin/canary", `{"endpoint":"POST /api/orders","limits":{"rate":1},"percent":10}`), http.StatusOK)
+	assertStatus(t, adminRequest(handler, http.MethodDelete, "/admin/canary?endpoint="+url.QueryEscape("POST /api/orders"), ""), http.StatusNoContent)
+	assertStatus(t, adminRequest(handler, http.MethodDelete, "/admin/canary?endpoint="+url.QueryEscape("POST /api/orders"), ""), http.StatusNotFound)
+}
+
+func TestInCanaryGrowsMonotonically(t *testing.T) {
+	in := 0
+	for i := 0; i < 2000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if inCanary("GET /api/profile", key, 0) {
+			t.Fatalf("%s in a 0%% canary", key)
+		}
+		if !inCanary("GET /api/profile", key, 100) {
+			t.Fatalf("%s not in a 100%% canary", key)
+		}
+		if inCanary("GET /api/profile", key, 10) {
+			in++
+			if !inCanary("GET /api/profile", key, 25) {
+				t.Fatalf("%s left the canary when it grew from 10%% to 25%%", key)
+			}
+		}
+	}
+	if in < 100 || in > 300 {
+		t.Fatalf("%d of 2000 keys in a 10%% canary", in)
+	}
+
+	// Another route's canary samples other clients.
+	same := 0
+	for i := 0; i < 2000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if inCanary("GET /api/profile", key, 10) && inCanary("POST /api/orders", key, 10) {
+			same++
+		}
+	}
+	if same > in/2 {
+		t.Fatalf("%d of %d canary keys are also in another route's canary", same, in)
+	}
+}
@@ -24,6 +24,62 @@ type Config struct {
 
 	StorageBackend string
 	Storage        chronostorage.Config
+
+	// ReplayHistoryDir persists replay runs as JSON files; empty keeps them in memory.
+	ReplayHistoryDir string
+	// ReplayWorkers caps how many asynchronous replay jobs run at once; 0 uses
+	// the default of 2.
+	ReplayWorkers int
+
+	// Routes sets which routes are limited and recorded; unlisted routes keep
+	// their DefaultRouteTable scope.
+	Routes []RouteScope
+	// Deny shapes rate-limited responses; routes may override it.
+	Deny DenyResponse
+	// CORS is the default cross-origin policy; nil disables CORS.
+	CORS *CORSPolicy
+
+	// AuthRequired turns on API key authentication with an in-memory key
+	// store; APIKeysFile or AdminAPIKey turn it on as well.
+	AuthRequired bool
+	// APIKeysFile persists API keys (hashed) as JSON; empty keeps them in memory.
+	APIKeysFile string
+	// AdminAPIKey is a bootstrap secret with the admin scope, never stored.
+	AdminAPIKey string
+	// JWT verifies bearer tokens when JWT.JWKSFile is set.
+	JWT JWTConfig
+
+	// IPRules are the allow, deny and exempt lists checked before rate
+	// limiting; IPRulesFile, once it exists, replaces them and is reloaded
+	// when it changes.
+	IPRules     IPRules
+	IPRulesFile string
+	// TrustedProxies are the peers, as CIDRs or addresses, whose
+	// X-Forwarded-For is believed; other clients are then identified by
+	// their connection address. Unset, the first X-Forwarded-For address
+	// is used as before.
+	TrustedProxies []string
+	// IPKeyPrefixes aggregates IP-derived rate-limit keys to network prefixes.
+	IPKeyPrefixes IPKeyPrefixes
+	// Shed drops low-priority requests first when the gateway is overloaded.
+	Shed ShedConfig
+	// Quota sets per-key daily and monthly quotas on limited routes.
+	Quota QuotaConfig
+	// Usage aggregates allowed and denied requests per key, route and hour.
+	Usage UsageConfig
+	// Tenants namespaces keys, recordings and replay history per team.
+	Tenants TenantConfig
+}
+
+// APIKeysEnabled reports whether X-API-Key authentication is on.
+func (c Config) APIKeysEnabled() bool {
+	return c.AuthRequired || strings.TrimSpace(c.APIKeysFile) != "" || c.AdminAPIKey != ""
+}
+
+// AuthEnabled reports whether routes marked authenticated require an API key
+// or bearer token.
+func (c Config) AuthEnabled() bool {
+	return c.APIKeysEnabled() || c.JWT.enabled()
 }
 
 // LoadConfig resolves configuration from Chrono defaults, optional config file,
@@ -55,7 +111,8 @@ func LoadConfig(configPath string) (Config, error) {
 			}
 			return chronoCfg.Storage.Backend
 		}(),
-		Storage: toStorageConfig(chronoCfg),
+		Storage:       toStorageConfig(chronoCfg),
+		ReplayWorkers: defaultReplayWorkers,
 	}
 
 	if raw := strings.TrimSpace(os.Getenv("ADDR")); raw != "" {
@@ -81,12 +138,168 @@ func LoadConfig(configPath string) (Config, error) {
 	if err != nil {
 		return Config{}, err
 	}
+	cfg.ReplayWorkers, err = parseNonNegativeIntEnv("REPLAY_WORKERS", cfg.ReplayWorkers)
+	if err != nil {
+		return Config{}, err
+	}
 
 	if raw := strings.TrimSpace(os.Getenv("STORAGE_BACKEND")); raw != "" {
 		cfg.StorageBackend = raw
 		cfg.Storage.Backend = raw
 	}
 
+	if raw := strings.TrimSpace(os.Getenv("REPLAY_HISTORY_DIR")); raw != "" {
+		cfg.ReplayHistoryDir = raw
+	}
+
+	if raw := strings.TrimSpace(os.Getenv("DENY_FORMAT")); raw != "" {
+		cfg.Deny.Format = raw
+	}
+	cfg.Deny.Status, err = parsePositiveIntEnv("DENY_STATUS", cfg.Deny.Status)
+	if err != nil {
+		return Config{}, err
+	}
+	if raw := strings.TrimSpace(os.Getenv("DENY_INCLUDE_KEY")); raw != "" {
+		cfg.Deny.IncludeKey, err = strconv.ParseBool(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid DENY_INCLUDE_KEY %q: %w", raw, err)
+		}
+	}
+	if raw := strings.TrimSpace(os.Getenv("DENY_DOC_URL")); raw != "" {
+		cfg.Deny.DocURL = raw
+	}
+	if raw := os.Getenv("DENY_MESSAGE"); strings.TrimSpace(raw) != "" {
+		cfg.Deny.Message = raw
+	}
+
+	cfg.CORS, err = loadCORSEnv()
+	if err != nil {
+		return Config{}, err
+	}
+
+	if raw := strings.TrimSpace(os.Getenv("AUTH_REQUIRED")); raw != "" {
+		cfg.AuthRequired, err = strconv.ParseBool(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid AUTH_REQUIRED %q: %w", raw, err)
+		}
+	}
+	cfg.APIKeysFile = strings.TrimSpace(os.Getenv("API_KEYS_FILE"))
+	cfg.AdminAPIKey = strings.TrimSpace(os.Getenv("ADMIN_API_KEY"))
+
+	cfg.JWT = JWTConfig{
+		JWKSFile:       strings.TrimSpace(os.Getenv("JWT_JWKS_FILE")),
+		KeyClaim:       strings.TrimSpace(os.Getenv("JWT_KEY_CLAIM")),
+		PlanClaim:      strings.TrimSpace(os.Getenv("JWT_PLAN_CLAIM")),
+		RateLimitClaim: strings.TrimSpace(os.Getenv("JWT_RATE_LIMIT_CLAIM")),
+		Issuer:         strings.TrimSpace(os.Getenv("JWT_ISSUER")),
+		Audience:       strings.TrimSpace(os.Getenv("JWT_AUDIENCE")),
+	}
+	if raw := strings.TrimSpace(os.Getenv("JWT_PLANS_FILE")); raw != "" {
+		cfg.JWT.Plans, err = LoadPlans(raw)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	cfg.IPRules = IPRules{
+		Allow:  splitCommaList(os.Getenv("IP_ALLOWLIST")),
+		Deny:   splitCommaList(os.Getenv("IP_DENYLIST")),
+		Exempt: splitCommaList(os.Getenv("IP_EXEMPTLIST")),
+	}
+	cfg.IPRulesFile = strings.TrimSpace(os.Getenv("IP_RULES_FILE"))
+	cfg.TrustedProxies = splitCommaList(os.Getenv("TRUSTED_PROXIES"))
+	if cfg.IPRulesFile != "" {
+		if _, err := os.Stat(cfg.IPRulesFile); err == nil {
+			if _, err := LoadIPRules(cfg.IPRulesFile); err != nil {
+				return Config{}, fmt.Errorf("invalid IP_RULES_FILE: %w", err)
+			}
+		}
+	}
+
+	cfg.IPKeyPrefixes.IPv4, err = parsePositiveIntEnv("IPV4_KEY_PREFIX", 0)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.IPKeyPrefixes.IPv6, err = parsePositiveIntEnv("IPV6_KEY_PREFIX", 0)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.Shed.MaxInFlight, err = parsePositiveIntEnv("SHED_MAX_INFLIGHT", 0)
+	if err != nil {
+		return Config{}, err
+	}
+	for _, d := range []struct {
+		name string
+		dst  *time.Duration
+	}{{"SHED_TARGET_LATENCY", &cfg.Shed.TargetLatency}, {"SHED_RETRY_AFTER", &cfg.Shed.RetryAfter}} {
+		if raw := strings.TrimSpace(os.Getenv(d.name)); raw != "" {
+			*d.dst, err = time.ParseDuration(raw)
+			if err != nil {
+				return Config{}, fmt.Errorf("invalid %s %q: %w", d.name, raw, err)
+			}
+		}
+	}
+	cfg.Shed.PriorityHeader = strings.TrimSpace(os.Getenv("SHED_PRIORITY_HEADER"))
+	cfg.Shed.TierPriorities, err = parseTierPriorities(os.Getenv("SHED_TIER_PRIORITIES"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid SHED_TIER_PRIORITIES: %w", err)
+	}
+
+	for _, q := range []struct {
+		name string
+		dst  *int64
+	}{{"QUOTA_DAILY", &cfg.Quota.Daily}, {"QUOTA_MONTHLY", &cfg.Quota.Monthly}} {
+		if raw := strings.TrimSpace(os.Getenv(q.name)); raw != "" {
+			*q.dst, err = strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return Config{}, fmt.Errorf("invalid %s %q: %w", q.name, raw, err)
+			}
+		}
+	}
+	cfg.Quota.Tiers, err = parseQuotaTiers(os.Getenv("QUOTA_TIERS"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid QUOTA_TIERS: %w", err)
+	}
+	if raw := strings.TrimSpace(os.Getenv("QUOTA_TIMEZONE")); raw != "" {
+		cfg.Quota.Location, err = time.LoadLocation(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid QUOTA_TIMEZONE %q: %w", raw, err)
+		}
+	}
+	cfg.Quota.File = strings.TrimSpace(os.Getenv("QUOTA_FILE"))
+
+	if raw := strings.TrimSpace(os.Getenv("USAGE_ACCOUNTING")); raw != "" {
+		cfg.Usage.Enabled, err = strconv.ParseBool(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid USAGE_ACCOUNTING %q: %w", raw, err)
+		}
+	}
+	cfg.Usage.File = strings.TrimSpace(os.Getenv("USAGE_FILE"))
+	if raw := strings.TrimSpace(os.Getenv("USAGE_RETENTION")); raw != "" {
+		cfg.Usage.Retention, err = time.ParseDuration(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid USAGE_RETENTION %q: %w", raw, err)
+		}
+	}
+
+	if raw := strings.TrimSpace(os.Getenv("TENANTS_FILE")); raw != "" {
+		cfg.Tenants, err = LoadTenants(raw)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+	if raw := strings.TrimSpace(os.Getenv("TENANT_HEADER")); raw != "" {
+		cfg.Tenants.Header = raw
+	}
+
+	if raw := strings.TrimSpace(os.Getenv("ROUTES_FILE")); raw != "" {
+		cfg.Routes, err = LoadRouteTable(raw)
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
 	if err := cfg.Validate(); err != nil {
 		return Config{}, err
 	}
@@ -163,6 +376,56 @@ func (c Config) Validate() error {
 	if strings.TrimSpace(c.Addr) == "" {
 		return fmt.Errorf("ADDR must not be empty")
 	}
+	if c.AuthRequired && c.AdminAPIKey == "" && strings.TrimSpace(c.APIKeysFile) == "" {
+		return fmt.Errorf("AUTH_REQUIRED needs ADMIN_API_KEY or API_KEYS_FILE, or no key could ever be created")
+	}
+	if c.ReplayWorkers < 0 {
+		return fmt.Errorf("REPLAY_WORKERS must be >= 0, got %d", c.ReplayWorkers)
+	}
+	if err := c.Deny.Validate(); err != nil {
+		return fmt.Errorf("invalid DENY_* setting: %w", err)
+	}
+	if err := c.CORS.Validate(); err != nil {
+		return fmt.Errorf("invalid CORS_* setting: %w", err)
+	}
+	if err := c.IPRules.Validate(); err != nil {
+		return fmt.Errorf("invalid IP_* list: %w", err)
+	}
+	if _, err := parseIPPrefixes("trusted proxy", c.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	if err := c.IPKeyPrefixes.Validate(); err != nil {
+		return fmt.Errorf("invalid IPV*_KEY_PREFIX: %w", err)
+	}
+	if err := c.Quota.Validate(); err != nil {
+		return fmt.Errorf("invalid QUOTA_* setting: %w", err)
+	}
+	if c.StorageBackend == chronostorage.BackendRedis && strings.TrimSpace(c.Quota.File) != "" {
+		return fmt.Errorf("QUOTA_FILE cannot be used with the redis backend, which keeps quotas in Redis")
+	}
+	if err := c.Usage.Validate(); err != nil {
+		return fmt.Errorf("invalid USAGE_* setting: %w", err)
+	}
+	if err := c.JWT.Validate(); err != nil {
+		return fmt.Errorf("invalid JWT_* setting: %w", err)
+	}
+	if err := c.Tenants.Validate(); err != nil {
+		return fmt.Errorf("invalid TENANTS_FILE: %w", err)
+	}
+	if err := c.Shed.Validate(); err != nil {
+		return fmt.Errorf("invalid SHED_* setting: %w", err)
+	}
+	if err := validateRouteTable(c.Routes, c.Deny); err != nil {
+		return fmt.Errorf("invalid ROUTES_FILE: %w", err)
+	}
+	if !c.AuthEnabled() {
+		// Shadow counts are only served by /admin/shadow, which needs auth.
+		for _, route := range c.Routes {
+			if route.Shadow != nil {
+				return fmt.Errorf("invalid ROUTES_FILE: route %q: shadow policies need auth to read them at /admin/shadow", route.Endpoint)
+			}
+		}
+	}
 
 	switch c.StorageBackend {
 	case chronostorage.BackendMemory, chronostorage.BackendRedis, chronostorage.BackendCRDT:
@@ -179,6 +442,52 @@ func (c Config) Validate() error {
 	return nil
 }
 
+// loadCORSEnv builds the default CORS policy; it is nil unless
+// CORS_ALLOWED_ORIGINS is set.
+func loadCORSEnv() (*CORSPolicy, error) {
+	origins := splitCommaList(os.Getenv("CORS_ALLOWED_ORIGINS"))
+	if len(origins) == 0 {
+		return nil, nil
+	}
+	policy := &CORSPolicy{
+		AllowedOrigins: origins,
+		AllowedMethods: splitCommaList(os.Getenv("CORS_ALLOWED_METHODS")),
+		AllowedHeaders: splitCommaList(os.Getenv("CORS_ALLOWED_HEADERS")),
+	}
+	if raw := strings.TrimSpace(os.Getenv("CORS_ALLOW_CREDENTIALS")); raw != "" {
+		allow, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CORS_ALLOW_CREDENTIALS %q: %w", raw, err)
+		}
+		policy.AllowCredentials = allow
+	}
+	maxAge, err := parsePositiveIntEnv("CORS_MAX_AGE", 0)
+	if err != nil {
+		return nil, err
+	}
+	policy.MaxAge = maxAge
+	return policy, nil
+}
+
+// parseNonNegativeIntEnv is parsePositiveIntEnv for settings where 0 means
+// "use the default".
+func parseNonNegativeIntEnv(name string, defaultValue int) (int, error) {
+	raw := strings.TrimSpace(os.Getenv(name))
+	if raw == "" {
+		return defaultValue, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", name, raw, err)
+	}
+	if value < 0 {
+		return 0, fmt.Errorf("%s must be >= 0, got %d", name, value)
+	}
+
+	return value, nil
+}
+
 func parsePositiveIntEnv(name string, defaultValue int) (int, error) {
 	raw := strings.TrimSpace(os.Getenv(name))
 	if raw == "" {
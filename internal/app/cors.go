@@ -0,0 +1,176 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// corsExposedHeaders are readable by browser clients on every CORS response.
+var corsExposedHeaders = []string{
+	"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "Retry-After",
+	"X-Quota-Daily-Limit", "X-Quota-Daily-Remaining", "X-Quota-Daily-Reset",
+	"X-Quota-Monthly-Limit", "X-Quota-Monthly-Remaining", "X-Quota-Monthly-Reset",
+	"X-Canary", "X-Canary-Percent",
+}
+
+// CORSPolicy configures cross-origin access to a route.
+type CORSPolicy struct {
+	// AllowedOrigins are exact origins or path.Match wildcards such as
+	// "https://*.example.com"; "*" allows any origin. Empty disables CORS.
+	AllowedOrigins []string `json:"allowed_origins"`
+	// AllowedMethods defaults to every method the route accepts.
+	AllowedMethods []string `json:"allowed_methods,omitempty"`
+	// AllowedHeaders are request headers a preflight may ask for; "*" allows
+	// any. Defaults to Content-Type, Authorization and X-API-Key.
+	AllowedHeaders   []string `json:"allowed_headers,omitempty"`
+	AllowCredentials bool     `json:"allow_credentials,omitempty"`
+	// MaxAge is how long, in seconds, browsers may cache a preflight; 0 omits it.
+	MaxAge int `json:"max_age,omitempty"`
+}
+
+var defaultCORSHeaders = []string{"Content-Type", "Authorization", "X-API-Key"}
+
+// Validate checks origin patterns, methods and max-age. Credentials cannot
+// be allowed for any origin ("*"): every site could then make authenticated
+// requests on a user's behalf.
+func (p *CORSPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	for _, origin := range p.AllowedOrigins {
+		if strings.TrimSpace(origin) == "" {
+			return fmt.Errorf("cors: empty allowed origin")
+		}
+		if origin == "*" && p.AllowCredentials {
+			return fmt.Errorf("cors: allow_credentials cannot be combined with the \"*\" origin")
+		}
+		if _, err := path.Match(origin, ""); err != nil {
+			return fmt.Errorf("cors: invalid origin pattern %q: %w", origin, err)
+		}
+	}
+	for _, method := range p.AllowedMethods {
+		if method == "" || method != strings.ToUpper(method) || strings.ContainsAny(method, " ,") {
+			return fmt.Errorf("cors: invalid method %q", method)
+		}
+	}
+	if p.MaxAge < 0 {
+		return fmt.Errorf("cors: max_age must be >= 0, got %d", p.MaxAge)
+	}
+	return nil
+}
+
+func (p *CORSPolicy) allowsOrigin(origin string) bool {
+	for _, pattern := range p.AllowedOrigins {
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+		if ok, _ := path.Match(pattern, origin); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// setOrigin writes the origin headers shared by preflight and actual
+// responses. It reports false when the origin is not allowed.
+func (p *CORSPolicy) setOrigin(w http.ResponseWriter, origin string) bool {
+	w.Header().Add("Vary", "Origin")
+	if !p.allowsOrigin(origin) {
+		return false
+	}
+	if p.AllowCredentials || !containsString(p.AllowedOrigins, "*") {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	}
+	if p.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+	return true
+}
+
+// apply sets the headers of an actual (non-preflight) cross-origin response.
+func (p *CORSPolicy) apply(w http.ResponseWriter, origin string) {
+	if p.setOrigin(w, origin) {
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+	}
+}
+
+// preflight answers a preflight request with 204. Disallowed origins, methods
+// or headers get no CORS headers, which the browser reports as a failure.
+func (p *CORSPolicy) preflight(w http.ResponseWriter, r *http.Request, routeMethods []string) {
+	defer w.WriteHeader(http.StatusNoContent)
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+	methods := p.AllowedMethods
+	if len(methods) == 0 {
+		methods = routeMethods
+	}
+	if !containsString(methods, r.Header.Get("Access-Control-Request-Method")) {
+		w.Header().Add("Vary", "Origin")
+		return
+	}
+
+	headers := p.AllowedHeaders
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+	requested := splitCommaList(r.Header.Get("Access-Control-Request-Headers"))
+	if !containsString(headers, "*") {
+		for _, h := range requested {
+			if !containsFold(headers, h) {
+				w.Header().Add("Vary", "Origin")
+				return
+			}
+		}
+	} else {
+		headers = requested
+	}
+
+	if !p.setOrigin(w, r.Header.Get("Origin")) {
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if len(headers) > 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	if p.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(p.MaxAge))
+	}
+}
+
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Origin") != "" && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitCommaList(raw string) []string {
+	var out []string
+	for _, part := range strings.Split(raw, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
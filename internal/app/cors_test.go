@@ -0,0 +1,146 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+)
+
+func newCORSTestHandler(t *testing.T, cfg Config) http.Handler {
+	t.Helper()
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 2, 8, 18, 0, 0, 0, time.UTC))
+	mainLimiter, mainStorage, err := NewStorageBackedLimiter(cfg, vc)
+	if err != nil {
+		t.Fatalf("NewStorageBackedLimiter() error = %v", err)
+	}
+	t.Cleanup(func() { _ = mainStorage.Close() })
+	storageSet, cleanup := newMemoryOnlyStorageSet(t, cfg, vc)
+	t.Cleanup(cleanup)
+	return NewHandler(cfg, mainLimiter, vc, chronorecorder.New(nil), storageSet)
+}
+
+func corsRequest(handler http.Handler, method, path, origin string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("X-API-Key", "cors-key")
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestCORSPreflightDoesNotConsumeBudget(t *testing.T) {
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.Rate = 1
+	cfg.CORS = &CORSPolicy{
+		AllowedOrigins:   []string{"https://*.example.com"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	}
+	handler := newCORSTestHandler(t, cfg)
+
+	preflight := map[string]string{
+		"Access-Control-Request-Method":  http.MethodGet,
+		"Access-Control-Request-Headers": "x-api-key",
+	}
+	for i := 0; i < 3; i++ {
+		resp := corsRequest(handler, http.MethodOptions, "/api/profile", "https://app.example.com", preflight)
+		assertStatus(t, resp, http.StatusNoContent)
+		if got := resp.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+			t.Fatalf("preflight Allow-Origin = %q", got)
+		}
+		if got := resp.Header().Get("Access-Control-Allow-Methods"); !strings.Contains(got, http.MethodGet) {
+			t.Fatalf("preflight Allow-Methods = %q", got)
+		}
+		if resp.Header().Get("Access-Control-Max-Age") != "600" || resp.Header().Get("Access-Control-Allow-Credentials") != "true" {
+			t.Fatalf("preflight headers = %v", resp.Header())
+		}
+		if resp.Header().Get("X-RateLimit-Limit") != "" {
+			t.Fatal("preflight went through the rate limiter")
+		}
+	}
+
+	resp := corsRequest(handler, http.MethodGet, "/api/profile", "https://app.example.com", nil)
+	assertStatus(t, resp, http.StatusOK)
+	if got := resp.Header().Get("Access-Control-Expose-Headers"); !strings.Contains(got, "X-RateLimit-Remaining") || !strings.Contains(got, "Retry-After") || !strings.Contains(got, "X-Quota-Daily-Remaining") || !strings.Contains(got, "X-Canary-Percent") {
+		t.Fatalf("Expose-Headers = %q", got)
+	}
+
+	resp = corsRequest(handler, http.MethodGet, "/api/profile", "https://app.example.com", nil)
+	assertStatus(t, resp, http.StatusTooManyRequests)
+	if resp.Header().Get("Access-Control-Allow-Origin") == "" {
+		t.Fatal("denied response lacks CORS headers")
+	}
+
+	resp = corsRequest(handler, http.MethodOptions, "/api/profile", "https://evil.test", preflight)
+	assertStatus(t, resp, http.StatusNoContent)
+	if resp.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatal("disallowed origin got Allow-Origin")
+	}
+
+	resp = corsRequest(handler, http.MethodOptions, "/api/profile", "https://app.example.com", map[string]string{
+		"Access-Control-Request-Method":  http.MethodGet,
+		"Access-Control-Request-Headers": "x-secret",
+	})
+	if resp.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatal("disallowed request header got Allow-Origin")
+	}
+}
+
+func TestCORSPerRoutePolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.json")
+	if err := os.WriteFile(path, []byte(`[
+		{"endpoint": "GET /public", "cors": {"allowed_origins": ["*"], "allowed_methods": ["GET"]}},
+		{"endpoint": "GET /api/profile", "cors": {"allowed_origins": []}}
+	]`), 0o644); err != nil {
+		t.Fatalf("write routes file: %v", err)
+	}
+	routes, err := LoadRouteTable(path)
+	if err != nil {
+		t.Fatalf("LoadRouteTable() error = %v", err)
+	}
+
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.Routes = routes
+	cfg.CORS = &CORSPolicy{AllowedOrigins: []string{"https://app.example.com"}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	handler := newCORSTestHandler(t, cfg)
+
+	resp := corsRequest(handler, http.MethodGet, "/public", "https://anywhere.test", nil)
+	if got := resp.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Fatalf("/public Allow-Origin = %q, want *", got)
+	}
+
+	resp = corsRequest(handler, http.MethodGet, "/api/profile", "https://app.example.com", nil)
+	if got := resp.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("/api/profile Allow-Origin = %q, want CORS disabled", got)
+	}
+
+	resp = corsRequest(handler, http.MethodGet, "/api/orders", "https://app.example.com", nil)
+	if got := resp.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Fatalf("/api/orders Allow-Origin = %q, want default policy", got)
+	}
+
+	bad := &CORSPolicy{AllowedOrigins: []string{"https://[a.example.com"}}
+	if err := bad.Validate(); err == nil {
+		t.Fatal("Validate() accepted a malformed origin pattern")
+	}
+	wildcard := &CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}
+	if err := wildcard.Validate(); err == nil {
+		t.Fatal("Validate() accepted credentials for any origin")
+	}
+}
@@ -0,0 +1,165 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DenyFormatJSON is the default {"error":"rate_limited",...} body, or a
+	// problem document for clients that ask for application/problem+json.
+	DenyFormatJSON = "json"
+	// DenyFormatText is a plain-text body.
+	DenyFormatText = "text"
+	// DenyFormatProblem is an RFC 7807 application/problem+json body.
+	DenyFormatProblem = "problem"
+
+	defaultDenyMessage = "too many requests"
+)
+
+// DenyResponse configures what a rate-limited request gets back. The zero
+// value is a 429 JSON body without the key.
+type DenyResponse struct {
+	Format string `json:"format,omitempty"`
+	// Status is 429 (default) or 503.
+	Status int `json:"status,omitempty"`
+	// IncludeKey echoes the rate-limit key; off by default because keys are
+	// often API keys that end up in client logs.
+	IncludeKey bool `json:"include_key,omitempty"`
+	// DocURL points clients at rate-limit documentation.
+	DocURL string `json:"doc_url,omitempty"`
+	// Message may use {limit}, {retry_after}, {endpoint} and {key}; {key}
+	// expands to "" unless IncludeKey is set.
+	Message string `json:"message,omitempty"`
+}
+
+// DenyOverride is a per-route DenyResponse override; nil fields inherit the
+// global setting.
+type DenyOverride struct {
+	Format     *string `json:"format,omitempty"`
+	Status     *int    `json:"status,omitempty"`
+	IncludeKey *bool   `json:"include_key,omitempty"`
+	DocURL     *string `json:"doc_url,omitempty"`
+	Message    *string `json:"message,omitempty"`
+}
+
+// With returns d with the fields set in o applied.
+func (d DenyResponse) With(o *DenyOverride) DenyResponse {
+	if o == nil {
+		return d
+	}
+	if o.Format != nil {
+		d.Format = *o.Format
+	}
+	if o.Status != nil {
+		d.Status = *o.Status
+	}
+	if o.IncludeKey != nil {
+		d.IncludeKey = *o.IncludeKey
+	}
+	if o.DocURL != nil {
+		d.DocURL = *o.DocURL
+	}
+	if o.Message != nil {
+		d.Message = *o.Message
+	}
+	return d
+}
+
+// Validate checks the format, status and documentation URL.
+func (d DenyResponse) Validate() error {
+	switch d.Format {
+	case "", DenyFormatJSON, DenyFormatText, DenyFormatProblem:
+	default:
+		return fmt.Errorf("invalid deny format %q: use %s|%s|%s", d.Format, DenyFormatJSON, DenyFormatText, DenyFormatProblem)
+	}
+	switch d.Status {
+	case 0, http.StatusTooManyRequests, http.StatusServiceUnavailable:
+	default:
+		return fmt.Errorf("invalid deny status %d: use 429 or 503", d.Status)
+	}
+	if d.DocURL != "" {
+		u, err := url.Parse(d.DocURL)
+		if err != nil || !u.IsAbs() {
+			return fmt.Errorf("invalid deny doc URL %q: must be absolute", d.DocURL)
+		}
+	}
+	return nil
+}
+
+// denyDetails are the values a deny response can report.
+type denyDetails struct {
+	key        string
+	endpoint   string
+	limit      int
+	retryAfter int
+}
+
+// write sends the deny response. Rate-limit headers are set by the caller.
+func (d DenyResponse) write(w http.ResponseWriter, r *http.Request, details denyDetails) {
+	status := d.Status
+	if status == 0 {
+		status = http.StatusTooManyRequests
+	}
+
+	message := d.Message
+	if message == "" {
+		message = defaultDenyMessage
+	}
+	key := ""
+	if d.IncludeKey {
+		key = details.key
+	}
+	message = strings.NewReplacer(
+		"{limit}", strconv.Itoa(details.limit),
+		"{retry_after}", strconv.Itoa(details.retryAfter),
+		"{endpoint}", details.endpoint,
+		"{key}", key,
+	).Replace(message)
+
+	format := d.Format
+	if (format == "" || format == DenyFormatJSON) && wantsProblemJSON(r) {
+		format = DenyFormatProblem
+	}
+
+	switch format {
+	case DenyFormatText:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+		fmt.Fprintln(w, message)
+		if d.DocURL != "" {
+			fmt.Fprintf(w, "See %s\n", d.DocURL)
+		}
+	case DenyFormatProblem:
+		problemType := d.DocURL
+		if problemType == "" {
+			problemType = "about:blank"
+		}
+		extensions := map[string]any{"retry_after": details.retryAfter}
+		if d.IncludeKey {
+			extensions["key"] = details.key
+		}
+		body := problemBody(problemType, APIError{
+			Status:     status,
+			Code:       ErrCodeRateLimited,
+			Detail:     message,
+			Extensions: extensions,
+		}, r.URL.Path)
+		writeJSONContent(w, status, problemContentType, body)
+	default:
+		body := map[string]string{
+			"error":   ErrCodeRateLimited,
+			"message": message,
+		}
+		if d.IncludeKey {
+			body["key"] = details.key
+		}
+		if d.DocURL != "" {
+			body["documentation_url"] = d.DocURL
+		}
+		writeJSON(w, status, body)
+	}
+}
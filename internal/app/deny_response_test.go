@@ -0,0 +1,138 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+)
+
+func newDenyTestHandler(t *testing.T, cfg Config) http.Handler {
+	t.Helper()
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 2, 8, 16, 0, 0, 0, time.UTC))
+
+	mainLimiter, mainStorage, err := NewStorageBackedLimiter(cfg, vc)
+	if err != nil {
+		t.Fatalf("NewStorageBackedLimiter() error = %v", err)
+	}
+	t.Cleanup(func() { _ = mainStorage.Close() })
+
+	storageSet, cleanup := newMemoryOnlyStorageSet(t, cfg, vc)
+	t.Cleanup(cleanup)
+
+	return NewHandler(cfg, mainLimiter, vc, chronorecorder.New(nil), storageSet)
+}
+
+func TestDeniedResponseOmitsKeyByDefault(t *testing.T) {
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.Rate = 1
+	handler := newDenyTestHandler(t, cfg)
+
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/api/profile", "secret-key", "", "", "198.51.100.60:8080"), http.StatusOK)
+	resp := executeRequest(handler, http.MethodGet, "/api/profile", "secret-key", "", "", "198.51.100.60:8080")
+	assertStatus(t, resp, http.StatusTooManyRequests)
+	assertDeniedResponse(t, resp)
+	if strings.Contains(resp.Body.String(), "secret-key") {
+		t.Fatalf("denied body leaks the key: %s", resp.Body.String())
+	}
+}
+
+func TestDeniedResponseFormats(t *testing.T) {
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.Rate = 1
+	cfg.Deny = DenyResponse{
+		Status:     http.StatusServiceUnavailable,
+		IncludeKey: true,
+		DocURL:     "https://docs.example.com/rate-limits",
+		Message:    "limit {limit} reached for {key} on {endpoint}",
+	}
+	problem, text := DenyFormatProblem, DenyFormatText
+	cfg.Routes = DefaultRouteTable()
+	for i := range cfg.Routes {
+		switch cfg.Routes[i].Endpoint {
+		case "POST /api/orders":
+			cfg.Routes[i].Deny = &DenyOverride{Format: &problem}
+		case "GET /api/fixed-window":
+			cfg.Routes[i].Deny = &DenyOverride{Format: &text}
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	handler := newDenyTestHandler(t, cfg)
+
+	deny := func(method, path string) *http.Response {
+		t.Helper()
+		executeRequest(handler, method, path, "fmt-key", "", "{}", "198.51.100.61:8080")
+		resp := executeRequest(handler, method, path, "fmt-key", "", "{}", "198.51.100.61:8080")
+		assertStatus(t, resp, http.StatusServiceUnavailable)
+		if resp.Header().Get("Retry-After") == "" {
+			t.Fatalf("%s %s: missing Retry-After", method, path)
+		}
+		return resp.Result()
+	}
+
+	resp := deny(http.MethodGet, "/api/profile")
+	var body map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode json body: %v", err)
+	}
+	if body["error"] != "rate_limited" || body["key"] != "fmt-key" || body["documentation_url"] != cfg.Deny.DocURL {
+		t.Fatalf("json body = %+v", body)
+	}
+	if body["message"] != "limit 1 reached for fmt-key on GET /api/profile" {
+		t.Fatalf("message = %q", body["message"])
+	}
+
+	resp = deny(http.MethodPost, "/api/orders")
+	if got := resp.Header.Get("Content-Type"); got != "application/problem+json" {
+		t.Fatalf("problem Content-Type = %q", got)
+	}
+	var problemBody map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&problemBody); err != nil {
+		t.Fatalf("decode problem body: %v", err)
+	}
+	if problemBody["type"] != cfg.Deny.DocURL || problemBody["status"] != float64(http.StatusServiceUnavailable) ||
+		problemBody["instance"] != "/api/orders" || problemBody["title"] != "Service Unavailable" {
+		t.Fatalf("problem body = %+v", problemBody)
+	}
+
+	resp = deny(http.MethodGet, "/api/fixed-window")
+	if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, "text/plain") {
+		t.Fatalf("text Content-Type = %q", got)
+	}
+}
+
+func TestDenyResponseValidation(t *testing.T) {
+	cases := []DenyResponse{
+		{Format: "xml"},
+		{Status: http.StatusTeapot},
+		{DocURL: "/relative"},
+	}
+	for _, d := range cases {
+		if err := d.Validate(); err == nil {
+			t.Fatalf("Validate(%+v) error = nil, want error", d)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "routes.json")
+	if err := os.WriteFile(path, []byte(`[{"endpoint": "GET /api/profile", "deny": {"status": 500}}]`), 0o644); err != nil {
+		t.Fatalf("write routes file: %v", err)
+	}
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	routes, err := LoadRouteTable(path)
+	if err != nil {
+		t.Fatalf("LoadRouteTable() error = %v", err)
+	}
+	cfg.Routes = routes
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "GET /api/profile") {
+		t.Fatalf("Validate() error = %v, want route deny error", err)
+	}
+}
@@ -0,0 +1,159 @@
+package app
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// writeFileAtomic writes data to path+".tmp" and renames it over path, so
+// readers and a crash never see a partially written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// appendLog is a JSON-lines file of T records backing an in-memory store.
+// Every change is appended as one record, so the state survives restarts
+// without rewriting the file on each request; the file is compacted to the
+// store's current state when it is opened and whenever the store prunes.
+type appendLog[T any] struct {
+	// name is what the file is called in errors, e.g. "quota".
+	name string
+	path string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// openAppendLog replays the file at path into load, creating it if missing,
+// then compacts it to snapshot.
+func openAppendLog[T any](name, path string, load func(T), snapshot func() []T) (*appendLog[T], error) {
+	l := &appendLog[T]{name: name, path: path}
+	f, err := os.Open(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("open %s file: %w", name, err)
+	default:
+		err := readAppendLog(f, name, load)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := l.Compact(snapshot); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// readAppendLog calls fn for every record of a log. A torn last line from a
+// crash is dropped; anything else that fails to decode is corrupt.
+func readAppendLog[T any](r io.Reader, name string, fn func(T)) error {
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var rec T
+		if err := json.Unmarshal([]byte(text), &rec); err != nil {
+			if !scanner.Scan() {
+				break
+			}
+			return fmt.Errorf("decode %s file line %d: %w", name, line, err)
+		}
+		fn(rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read %s file: %w", name, err)
+	}
+	return nil
+}
+
+// Append writes recs in one write and then runs apply, if set, which updates
+// the in-memory store, both under the log's lock so a compaction never drops
+// a written record.
+func (l *appendLog[T]) Append(apply func(), recs ...T) error {
+	var lines []byte
+	for _, rec := range recs {
+		line, err := json.Marshal(rec)
+		if err != nil {
+			return fmt.Errorf("encode %s entry: %w", l.name, err)
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return fmt.Errorf("%s file is closed", l.name)
+	}
+	if _, err := l.file.Write(lines); err != nil {
+		return fmt.Errorf("append %s file: %w", l.name, err)
+	}
+	if apply != nil {
+		apply()
+	}
+	return nil
+}
+
+// Compact rewrites the file with the records snapshot returns, taken under
+// the log's lock, and reopens it for appending. The old file stays open
+// until the new one is in place, so a failed compaction leaves appends
+// going to the uncompacted file.
+func (l *appendLog[T]) Compact(snapshot func() []T) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var b strings.Builder
+	for _, rec := range snapshot() {
+		line, _ := json.Marshal(rec)
+		b.Write(line)
+		b.WriteByte('\n')
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0o644); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("write %s file: %w", l.name, err)
+	}
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("open %s file: %w", l.name, err)
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		f.Close()
+		_ = os.Remove(tmp)
+		return fmt.Errorf("write %s file: %w", l.name, err)
+	}
+	if l.file != nil {
+		l.file.Close()
+	}
+	l.file = f
+	return nil
+}
+
+// Close closes the file; later appends fail.
+func (l *appendLog[T]) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
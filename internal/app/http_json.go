@@ -7,7 +7,11 @@ import (
 )
 
 func writeJSON(w http.ResponseWriter, status int, payload any) {
-	w.Header().Set("Content-Type", "application/json")
+	writeJSONContent(w, status, "application/json", payload)
+}
+
+func writeJSONContent(w http.ResponseWriter, status int, contentType string, payload any) {
+	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(status)
 	if err := json.NewEncoder(w).Encode(payload); err != nil {
 		log.Printf("encode json response: %v", err)
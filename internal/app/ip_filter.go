@@ -0,0 +1,380 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IPRules are client address lists checked before rate limiting. Entries are
+// CIDR prefixes or single addresses, IPv4 or IPv6.
+type IPRules struct {
+	// Allow, when non-empty, is the only set of addresses served.
+	Allow []string `json:"allow,omitempty"`
+	// Deny is answered with 403; it wins over Allow and Exempt.
+	Deny []string `json:"deny,omitempty"`
+	// Exempt skips rate limiting; requests are still recorded.
+	Exempt []string `json:"exempt,omitempty"`
+}
+
+// Validate checks that every entry is a prefix or address.
+func (r IPRules) Validate() error {
+	_, err := r.compile()
+	return err
+}
+
+type ipPrefixes struct {
+	allow, deny, exempt []netip.Prefix
+}
+
+func (r IPRules) compile() (ipPrefixes, error) {
+	var (
+		out ipPrefixes
+		err error
+	)
+	if out.allow, err = parseIPPrefixes("allow", r.Allow); err != nil {
+		return ipPrefixes{}, err
+	}
+	if out.deny, err = parseIPPrefixes("deny", r.Deny); err != nil {
+		return ipPrefixes{}, err
+	}
+	if out.exempt, err = parseIPPrefixes("exempt", r.Exempt); err != nil {
+		return ipPrefixes{}, err
+	}
+	return out, nil
+}
+
+func parseIPPrefixes(list string, entries []string) ([]netip.Prefix, error) {
+	out := make([]netip.Prefix, 0, len(entries))
+	for _, entry := range entries {
+		prefix, err := parseIPPrefix(strings.TrimSpace(entry))
+		if err != nil {
+			return nil, fmt.Errorf("%s entry %q: %w", list, entry, err)
+		}
+		out = append(out, prefix)
+	}
+	return out, nil
+}
+
+// parseIPPrefix accepts a CIDR or a bare address. IPv4-mapped IPv6 entries
+// (::ffff:10.0.0.0/104) become IPv4 prefixes, matching how addresses are
+// normalized before lookup.
+func parseIPPrefix(entry string) (netip.Prefix, error) {
+	if !strings.Contains(entry, "/") {
+		addr, err := parseClientAddr(entry)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(entry)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	if addr := prefix.Addr(); addr.Is4In6() {
+		if prefix.Bits() < 96 {
+			return netip.Prefix{}, errors.New("IPv4-mapped prefix must be at least /96")
+		}
+		prefix = netip.PrefixFrom(addr.Unmap(), prefix.Bits()-96)
+	}
+	return prefix.Masked(), nil
+}
+
+// parseClientAddr parses an address without zone, unmapping IPv4-mapped
+// IPv6 so ::ffff:192.0.2.1 matches 192.0.2.0/24.
+func parseClientAddr(raw string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(strings.Trim(raw, "[]"))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addr.WithZone("").Unmap(), nil
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, p := range prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadIPRules reads IPRules from a JSON file.
+func LoadIPRules(path string) (IPRules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return IPRules{}, fmt.Errorf("read ip rules file: %w", err)
+	}
+	var rules IPRules
+	if len(strings.TrimSpace(string(data))) > 0 {
+		if err := json.Unmarshal(data, &rules); err != nil {
+			return IPRules{}, fmt.Errorf("decode ip rules file: %w", err)
+		}
+	}
+	if err := rules.Validate(); err != nil {
+		return IPRules{}, fmt.Errorf("ip rules file: %w", err)
+	}
+	return rules, nil
+}
+
+type ipVerdict int
+
+const (
+	ipAllowed ipVerdict = iota
+	ipDenied
+	ipExempt
+)
+
+// IPFilter applies IPRules to the client address of each request. With a
+// rules file it is reloaded when the file changes; SetRules replaces the
+// rules at runtime and writes them back to the file.
+type IPFilter struct {
+	mu       sync.Mutex
+	path     string
+	modTime  time.Time
+	size     int64
+	rules    IPRules
+	prefixes ipPrefixes
+}
+
+// NewIPFilter creates a filter seeded with rules; a non-empty path replaces
+// them with the file's rules whenever it exists and changes.
+func NewIPFilter(rules IPRules, path string) (*IPFilter, error) {
+	prefixes, err := rules.compile()
+	if err != nil {
+		return nil, err
+	}
+	f := &IPFilter{path: strings.TrimSpace(path), rules: rules, prefixes: prefixes}
+	f.current()
+	return f, nil
+}
+
+// load refreshes the rules when the file changed. Callers hold f.mu.
+func (f *IPFilter) load() error {
+	if f.path == "" {
+		return nil
+	}
+	info, err := os.Stat(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("stat ip rules file: %w", err)
+	}
+	if info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return nil
+	}
+	rules, err := LoadIPRules(f.path)
+	if err != nil {
+		return err
+	}
+	prefixes, _ := rules.compile()
+	f.rules, f.prefixes = rules, prefixes
+	f.modTime, f.size = info.ModTime(), info.Size()
+	return nil
+}
+
+// current returns the compiled rules, reloading the file when it changed.
+// A file that fails to load keeps the previous rules in force.
+func (f *IPFilter) current() ipPrefixes {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.load(); err != nil {
+		log.Printf("reload ip rules: %v", err)
+	}
+	return f.prefixes
+}
+
+// Rules returns the rules in force.
+func (f *IPFilter) Rules() IPRules {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.load(); err != nil {
+		log.Printf("reload ip rules: %v", err)
+	}
+	return f.rules
+}
+
+// SetRules validates and applies rules, persisting them to the rules file
+// when there is one.
+func (f *IPFilter) SetRules(rules IPRules) error {
+	prefixes, err := rules.compile()
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.path != "" {
+		if err := f.write(rules); err != nil {
+			return err
+		}
+	}
+	f.rules, f.prefixes = rules, prefixes
+	return nil
+}
+
+func (f *IPFilter) write(rules IPRules) error {
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode ip rules: %w", err)
+	}
+	if err := writeFileAtomic(f.path, data, 0o644); err != nil {
+		return fmt.Errorf("write ip rules file: %w", err)
+	}
+	if info, err := os.Stat(f.path); err == nil {
+		f.modTime, f.size = info.ModTime(), info.Size()
+	}
+	return nil
+}
+
+// verdict decides a client address. Deny wins; a non-empty allow list
+// rejects everything outside it, including addresses that do not parse.
+func (f *IPFilter) verdict(raw string) ipVerdict {
+	p := f.current()
+	if len(p.allow) == 0 && len(p.deny) == 0 && len(p.exempt) == 0 {
+		return ipAllowed
+	}
+	addr, err := parseClientAddr(raw)
+	if err != nil {
+		if len(p.allow) > 0 {
+			return ipDenied
+		}
+		return ipAllowed
+	}
+	if containsAddr(p.deny, addr) {
+		return ipDenied
+	}
+	if len(p.allow) > 0 && !containsAddr(p.allow, addr) {
+		return ipDenied
+	}
+	if containsAddr(p.exempt, addr) {
+		return ipExempt
+	}
+	return ipAllowed
+}
+
+// Middleware rejects denied clients with 403 and marks exempt ones so
+// RateLimitMiddleware lets them through.
+func (f *IPFilter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch f.verdict(clientIPFromRequest(r)) {
+		case ipDenied:
+			writeError(w, r, http.StatusForbidden, ErrCodeIPDenied, "client address is not allowed")
+			return
+		case ipExempt:
+			r = r.WithContext(context.WithValue(r.Context(), ipExemptContextKey{}, true))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type ipExemptContextKey struct{}
+
+// isRateLimitExempt reports whether the IP filter exempted the request.
+func isRateLimitExempt(ctx context.Context) bool {
+	exempt, _ := ctx.Value(ipExemptContextKey{}).(bool)
+	return exempt
+}
+
+type trustedProxiesContextKey struct{}
+
+// trustedProxyMiddleware makes trusted the peers whose X-Forwarded-For
+// clientIPFromRequest believes.
+func trustedProxyMiddleware(trusted []netip.Prefix, next http.Handler) http.Handler {
+	if len(trusted) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), trustedProxiesContextKey{}, trusted)))
+	})
+}
+
+// clientIPFromRequest returns the client address; it is empty when no
+// address is known. Without trusted proxies that is the first
+// X-Forwarded-For address, else the remote address host. With them, only a
+// trusted peer's X-Forwarded-For is read, and the client is its rightmost
+// address that is not itself a trusted proxy, since anything left of that
+// was written by the client.
+func clientIPFromRequest(r *http.Request) string {
+	trusted, _ := r.Context().Value(trustedProxiesContextKey{}).([]netip.Prefix)
+	if len(trusted) == 0 {
+		if forwardedFor := strings.TrimSpace(r.Header.Get("X-Forwarded-For")); forwardedFor != "" {
+			if ip := strings.TrimSpace(strings.Split(forwardedFor, ",")[0]); ip != "" {
+				return ip
+			}
+		}
+		return remoteHost(r)
+	}
+
+	remote := remoteHost(r)
+	if !isTrustedProxy(trusted, remote) {
+		return remote
+	}
+	hops := splitCommaList(strings.Join(r.Header.Values("X-Forwarded-For"), ","))
+	for i := len(hops) - 1; i >= 0; i-- {
+		if !isTrustedProxy(trusted, hops[i]) {
+			return hops[i]
+		}
+	}
+	if len(hops) > 0 {
+		return hops[0]
+	}
+	return remote
+}
+
+// fromTrustedProxy reports whether the request's peer is a trusted proxy.
+func fromTrustedProxy(r *http.Request) bool {
+	trusted, _ := r.Context().Value(trustedProxiesContextKey{}).([]netip.Prefix)
+	return isTrustedProxy(trusted, remoteHost(r))
+}
+
+// remoteHost is the peer address without its port.
+func remoteHost(r *http.Request) string {
+	remote := strings.TrimSpace(r.RemoteAddr)
+	if host, _, err := net.SplitHostPort(remote); err == nil && host != "" {
+		return host
+	}
+	return remote
+}
+
+func isTrustedProxy(trusted []netip.Prefix, raw string) bool {
+	if len(trusted) == 0 {
+		return false
+	}
+	addr, err := parseClientAddr(raw)
+	return err == nil && containsAddr(trusted, addr)
+}
+
+func ipRulesGetHandler(filter *IPFilter) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, filter.Rules())
+	}
+}
+
+func ipRulesPutHandler(filter *IPFilter) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var rules IPRules
+		if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid JSON body")
+			return
+		}
+		if err := rules.Validate(); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+			return
+		}
+		if err := filter.SetRules(rules); err != nil {
+			writeError(w, r, http.StatusInternalServerError, ErrCodeStorageError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, rules)
+	}
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
+)
+
+func TestIPFilterDeniesAndExemptsBeforeRateLimiting(t *testing.T) {
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.Rate = 1
+	cfg.IPRules = IPRules{
+		Deny:   []string{"203.0.113.0/24", "2001:db8:bad::/48"},
+		Exempt: []string{"10.0.0.0/8", "::1"},
+	}
+	handler := newAuthTestHandler(t, cfg)
+
+	resp := executeRequest(handler, http.MethodGet, "/api/profile", "", "", "", "203.0.113.9:4000")
+	assertStatus(t, resp, http.StatusForbidden)
+	var problem map[string]any
+	if err := json.Unmarshal(resp.Body.Bytes(), &problem); err != nil || problem["error"] != ErrCodeIPDenied {
+		t.Fatalf("403 body = %s", resp.Body.String())
+	}
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/health", "", "", "", "[2001:db8:bad::1]:4000"), http.StatusForbidden)
+
+	assertStatus(t, executeRequest(handler, http.MethodPost, "/api/record/start", "", "", "", "192.0.2.1:4000"), http.StatusOK)
+	for _, remote := range []string{"[::1]:5000", "[::ffff:10.1.2.3]:5000"} {
+		for i := 0; i < 3; i++ {
+			resp := executeRequest(handler, http.MethodGet, "/api/profile", "", "", "", remote)
+			assertStatus(t, resp, http.StatusOK)
+			if resp.Header().Get("X-RateLimit-Limit") != "" {
+				t.Fatalf("%s: exempt request went through the limiter", remote)
+			}
+		}
+	}
+	resp = executeRequest(handler, http.MethodPost, "/api/record/stop", "", "", "", "192.0.2.1:4000")
+	var stopped struct {
+		Count int `json:"count"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &stopped); err != nil {
+		t.Fatalf("decode stop: %v", err)
+	}
+	if stopped.Count != 6 {
+		t.Fatalf("recorded %d exempt requests, want 6", stopped.Count)
+	}
+
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/api/profile", "", "", "", "192.0.2.1:4000"), http.StatusOK)
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/api/profile", "", "", "", "192.0.2.1:4000"), http.StatusTooManyRequests)
+}
+
+func TestIPFilterIgnoresForwardedForFromUntrustedPeers(t *testing.T) {
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.Rate = 1
+	cfg.IPRules = IPRules{Deny: []string{"203.0.113.0/24"}, Exempt: []string{"10.0.0.0/8"}}
+	cfg.TrustedProxies = []string{"192.0.2.10"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	handler := newAuthTestHandler(t, cfg)
+
+	// A denied client cannot forge its way in, nor a client into the exempt list.
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/health", "", "198.51.100.1", "", "203.0.113.9:4000"), http.StatusForbidden)
+	resp := executeRequest(handler, http.MethodGet, "/api/profile", "", "10.0.0.1", "", "198.51.100.7:4000")
+	assertStatus(t, resp, http.StatusOK)
+	if resp.Header().Get("X-RateLimit-Limit") == "" {
+		t.Fatal("spoofed exempt address skipped the limiter")
+	}
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/api/profile", "", "10.0.0.2", "", "198.51.100.7:4000"), http.StatusTooManyRequests)
+
+	// Behind the trusted proxy the client is the hop it appended, not
+	// whatever the client put first.
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/health", "", "203.0.113.9", "", "192.0.2.10:4000"), http.StatusForbidden)
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/health", "", "10.0.0.1, 203.0.113.9", "", "192.0.2.10:4000"), http.StatusForbidden)
+	resp = executeRequest(handler, http.MethodGet, "/api/profile", "", "203.0.113.9, 10.0.0.1", "", "192.0.2.10:4000")
+	assertStatus(t, resp, http.StatusOK)
+	if resp.Header().Get("X-RateLimit-Limit") != "" {
+		t.Fatal("exempt client behind the trusted proxy went through the limiter")
+	}
+
+	cfg.TrustedProxies = []string{"not-an-ip"}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("Validate() accepted a malformed trusted proxy")
+	}
+}
+
+func TestIPFilterAllowListAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ip-rules.json")
+	if err := os.WriteFile(path, []byte(`{"allow": ["192.0.2.0/24", "2001:db8::/32"]}`), 0o644); err != nil {
+		t.Fatalf("write rules: %v", err)
+	}
+	filter, err := NewIPFilter(IPRules{Deny: []string{"192.0.2.1"}}, path)
+	if err != nil {
+		t.Fatalf("NewIPFilter() error = %v", err)
+	}
+
+	cases := map[string]ipVerdict{
+		"192.0.2.1":        ipAllowed, // the file replaced the static deny entry
+		"::ffff:192.0.2.7": ipAllowed,
+		"2001:db8::1%eth0": ipAllowed,
+		"198.51.100.1":     ipDenied,
+		"unknown":          ipDenied,
+	}
+	for addr, want := range cases {
+		if got := filter.verdict(addr); got != want {
+			t.Fatalf("verdict(%q) = %d, want %d", addr, got, want)
+		}
+	}
+
+	// Another process edits the file; the next request sees it.
+	if err := os.WriteFile(path, []byte(`{"deny": ["192.0.2.0/25"], "exempt": ["::ffff:198.51.100.0/120"]}`), 0o644); err != nil {
+		t.Fatalf("rewrite rules: %v", err)
+	}
+	if got := filter.verdict("192.0.2.1"); got != ipDenied {
+		t.Fatalf("after reload verdict = %d, want denied", got)
+	}
+	if got := filter.verdict("198.51.100.1"); got != ipExempt {
+		t.Fatalf("after reload mapped exempt verdict = %d, want exempt", got)
+	}
+
+	if err := filter.SetRules(IPRules{Deny: []string{"not-an-ip"}}); err == nil {
+		t.Fatal("SetRules() accepted an invalid entry")
+	}
+	if err := filter.SetRules(IPRules{Exempt: []string{"fd00::/8"}}); err != nil {
+		t.Fatalf("SetRules() error = %v", err)
+	}
+	saved, err := LoadIPRules(path)
+	if err != nil || len(saved.Exempt) != 1 || saved.Exempt[0] != "fd00::/8" {
+		t.Fatalf("persisted rules = %+v, %v", saved, err)
+	}
+}
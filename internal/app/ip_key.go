@@ -0,0 +1,77 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/netip"
+
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+)
+
+// IPKeyPrefixes aggregates IP-derived rate-limit keys to network prefixes, so
+// a host cannot dodge its limit by rotating addresses within its /64. Zero
+// keeps full addresses.
+type IPKeyPrefixes struct {
+	IPv4 int `json:"ipv4,omitempty"`
+	IPv6 int `json:"ipv6,omitempty"`
+}
+
+// Validate checks the prefix lengths.
+func (p IPKeyPrefixes) Validate() error {
+	if p.IPv4 < 0 || p.IPv4 > 32 {
+		return fmt.Errorf("IPv4 key prefix must be between 0 and 32, got %d", p.IPv4)
+	}
+	if p.IPv6 < 0 || p.IPv6 > 128 {
+		return fmt.Errorf("IPv6 key prefix must be between 0 and 128, got %d", p.IPv6)
+	}
+	return nil
+}
+
+// Key returns the key for a client address: the address itself when its
+// family is not aggregated, else its network prefix such as 2001:db8::/64.
+// IPv4-mapped IPv6 addresses aggregate as IPv4; other strings are returned
+// unchanged.
+func (p IPKeyPrefixes) Key(ip string) string {
+	bits := p.IPv6
+	addr, err := parseClientAddr(ip)
+	if err != nil {
+		return ip
+	}
+	if addr.Is4() {
+		bits = p.IPv4
+	}
+	if bits <= 0 || bits >= addr.BitLen() {
+		return ip
+	}
+	return netip.PrefixFrom(addr, bits).Masked().String()
+}
+
+// AggregateIPKeys rewrites IP keys of records to their prefixes, so older
+// recordings can be inspected the way the server now keys them.
+func AggregateIPKeys(records []chronorecorder.TrafficRecord, p IPKeyPrefixes) []chronorecorder.TrafficRecord {
+	out := make([]chronorecorder.TrafficRecord, len(records))
+	for i, rec := range records {
+		rec.Key = p.Key(rec.Key)
+		out[i] = rec
+	}
+	return out
+}
+
+type ipKeyPrefixesContextKey struct{}
+
+// ipKeyMiddleware makes p the aggregation used by clientKeyFromRequest, so
+// limiting, recording and the storage views agree on the key.
+func ipKeyMiddleware(p IPKeyPrefixes, next http.Handler) http.Handler {
+	if p == (IPKeyPrefixes{}) {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ipKeyPrefixesContextKey{}, p)))
+	})
+}
+
+func ipKeyPrefixesFromContext(ctx context.Context) IPKeyPrefixes {
+	p, _ := ctx.Value(ipKeyPrefixesContextKey{}).(IPKeyPrefixes)
+	return p
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+)
+
+func TestIPKeyPrefixesKey(t *testing.T) {
+	p := IPKeyPrefixes{IPv4: 24, IPv6: 64}
+	cases := map[string]string{
+		"2001:db8:1:2:aaaa::1":    "2001:db8:1:2::/64",
+		"2001:db8:1:2:ffff::9":    "2001:db8:1:2::/64",
+		"fe80::1%eth0":            "fe80::/64",
+		"192.0.2.77":              "192.0.2.0/24",
+		"::ffff:192.0.2.1":        "192.0.2.0/24",
+		"unknown":                 "unknown",
+		"not-an-ip, 198.51.100.1": "not-an-ip, 198.51.100.1",
+	}
+	for in, want := range cases {
+		if got := p.Key(in); got != want {
+			t.Fatalf("Key(%q) = %q, want %q", in, got, want)
+		}
+	}
+	if got := (IPKeyPrefixes{}).Key("2001:db8::1"); got != "2001:db8::1" {
+		t.Fatalf("zero prefixes Key() = %q, want the address", got)
+	}
+	if err := (IPKeyPrefixes{IPv6: 129}).Validate(); err == nil {
+		t.Fatal("Validate() accepted /129")
+	}
+}
+
+func TestIPv6HostsInOnePrefixShareABucket(t *testing.T) {
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.Rate = 1
+	cfg.IPKeyPrefixes = IPKeyPrefixes{IPv6: 64}
+	handler := newAuthTestHandler(t, cfg)
+
+	assertStatus(t, executeRequest(handler, http.MethodPost, "/api/record/start", "", "", "", "192.0.2.1:4000"), http.StatusOK)
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/api/profile", "", "", "", "[2001:db8:0:1::1]:4000"), http.StatusOK)
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/api/profile", "", "2001:db8:0:1::beef", "", "[::1]:4000"), http.StatusTooManyRequests)
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/api/profile", "", "", "", "[2001:db8:0:2::1]:4000"), http.StatusOK)
+	// IPv4 is not aggregated.
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/api/profile", "", "", "", "192.0.2.2:4000"), http.StatusOK)
+
+	resp := executeRequest(handler, http.MethodPost, "/api/record/stop", "", "", "", "192.0.2.1:4000")
+	var stopped struct {
+		Records []chronorecorder.TrafficRecord `json:"records"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &stopped); err != nil {
+		t.Fatalf("decode stop: %v", err)
+	}
+	want := []string{"2001:db8:0:1::/64", "2001:db8:0:1::/64", "2001:db8:0:2::/64", "192.0.2.2"}
+	if len(stopped.Records) != len(want) {
+		t.Fatalf("recorded %d records, want %d", len(stopped.Records), len(want))
+	}
+	for i, rec := range stopped.Records {
+		if rec.Key != want[i] {
+			t.Fatalf("record %d key = %q, want %q", i, rec.Key, want[i])
+		}
+	}
+}
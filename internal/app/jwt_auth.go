@@ -0,0 +1,400 @@
+package app
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTConfig configures bearer-token verification. JWKSFile turns it on.
+type JWTConfig struct {
+	JWKSFile string
+	// KeyClaim becomes the rate-limit key; defaults to "sub".
+	KeyClaim string
+	// PlanClaim names a plan in Plans; defaults to "plan".
+	PlanClaim string
+	// RateLimitClaim carries limits directly, as a rate number or a
+	// {"rate","window","burst"} object; defaults to "rate_limit". It wins over PlanClaim.
+	RateLimitClaim string
+	Issuer         string
+	Audience       string
+	// Plans maps plan names to limits; unknown plans use the route's limits.
+	Plans map[string]PlanLimits
+}
+
+func (c JWTConfig) enabled() bool {
+	return strings.TrimSpace(c.JWKSFile) != ""
+}
+
+// Validate checks plan limits and, when enabled, that the JWKS file loads,
+// so a bad file fails at startup rather than on the first request.
+func (c JWTConfig) Validate() error {
+	for name, plan := range c.Plans {
+		if err := plan.Validate(); err != nil {
+			return fmt.Errorf("plan %q: %w", name, err)
+		}
+	}
+	if !c.enabled() {
+		return nil
+	}
+	if _, err := LoadJWKS(c.JWKSFile); err != nil {
+		return fmt.Errorf("jwks file: %w", err)
+	}
+	return nil
+}
+
+// PlanLimits overrides the configured limiter parameters; zero fields keep them.
+type PlanLimits struct {
+	Rate   int
+	Window time.Duration
+	Burst  int
+}
+
+type planLimitsJSON struct {
+	Rate   int    `json:"rate,omitempty"`
+	Window string `json:"window,omitempty"`
+	Burst  int    `json:"burst,omitempty"`
+}
+
+func (p PlanLimits) MarshalJSON() ([]byte, error) {
+	out := planLimitsJSON{Rate: p.Rate, Burst: p.Burst}
+	if p.Window > 0 {
+		out.Window = p.Window.String()
+	}
+	return json.Marshal(out)
+}
+
+func (p *PlanLimits) UnmarshalJSON(data []byte) error {
+	var in planLimitsJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*p = PlanLimits{Rate: in.Rate, Burst: in.Burst}
+	if strings.TrimSpace(in.Window) != "" {
+		d, err := time.ParseDuration(strings.TrimSpace(in.Window))
+		if err != nil {
+			return fmt.Errorf("invalid window %q: %w", in.Window, err)
+		}
+		p.Window = d
+	}
+	return p.Validate()
+}
+
+// Validate rejects negative limits.
+func (p PlanLimits) Validate() error {
+	if p.Rate < 0 || p.Burst < 0 || p.Window < 0 {
+		return fmt.Errorf("plan limits must be >= 0, got rate=%d window=%s burst=%d", p.Rate, p.Window, p.Burst)
+	}
+	return nil
+}
+
+// Apply returns cfg with the non-zero limits applied.
+func (p PlanLimits) Apply(cfg Config) Config {
+	if p.Rate > 0 {
+		cfg.Rate = p.Rate
+	}
+	if p.Window > 0 {
+		cfg.Window = p.Window
+	}
+	if p.Burst > 0 {
+		cfg.Burst = p.Burst
+	}
+	return cfg
+}
+
+// LoadPlans reads a JSON object of plan name to limits.
+func LoadPlans(path string) (map[string]PlanLimits, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read plans file: %w", err)
+	}
+	var plans map[string]PlanLimits
+	if err := json.Unmarshal(data, &plans); err != nil {
+		return nil, fmt.Errorf("decode plans file: %w", err)
+	}
+	return plans, nil
+}
+
+// JWTIdentity is the verified client identity of a bearer token.
+type JWTIdentity struct {
+	Key     string
+	Subject string
+	Plan    string
+	Scopes  []string
+	// Limits are the claim-selected limits, nil when the token selects none.
+	Limits *PlanLimits
+}
+
+// HasScope reports whether the token holds scope; admin holds every scope.
+func (id JWTIdentity) HasScope(scope string) bool {
+	for _, s := range id.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+type jwtIdentityContextKey struct{}
+
+// WithJWTIdentity returns ctx carrying a verified token identity.
+func WithJWTIdentity(ctx context.Context, id JWTIdentity) context.Context {
+	return context.WithValue(ctx, jwtIdentityContextKey{}, id)
+}
+
+// JWTIdentityFromContext returns the token identity verified for the request, if any.
+func JWTIdentityFromContext(ctx context.Context) (JWTIdentity, bool) {
+	id, ok := ctx.Value(jwtIdentityContextKey{}).(JWTIdentity)
+	return id, ok
+}
+
+// jwk is one verification key of a JWKS file.
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+
+	key any
+}
+
+// jwkAlgorithms maps each accepted signing algorithm to the key type it needs,
+// so a token can never pick an algorithm its key was not issued for.
+var jwkAlgorithms = map[string]string{"HS256": "oct", "RS256": "RSA", "ES256": "EC"}
+
+// LoadJWKS reads verification keys from a JWKS file: oct (HS256), RSA
+// (RS256) and EC P-256 (ES256) keys.
+func LoadJWKS(path string) ([]jwk, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read jwks file: %w", err)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("decode jwks file: %w", err)
+	}
+	if len(set.Keys) == 0 {
+		return nil, fmt.Errorf("jwks file %s has no keys", path)
+	}
+	for i := range set.Keys {
+		if err := set.Keys[i].parse(); err != nil {
+			return nil, fmt.Errorf("jwks key %d (kid %q): %w", i, set.Keys[i].Kid, err)
+		}
+	}
+	return set.Keys, nil
+}
+
+func (k *jwk) parse() error {
+	switch k.Kty {
+	case "oct":
+		secret, err := decodeJWKField(k.K)
+		if err != nil || len(secret) == 0 {
+			return fmt.Errorf("invalid oct key")
+		}
+		k.key = secret
+	case "RSA":
+		n, errN := decodeJWKField(k.N)
+		e, errE := decodeJWKField(k.E)
+		if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 {
+			return fmt.Errorf("invalid RSA key")
+		}
+		k.key = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	case "EC":
+		if k.Crv != "P-256" {
+			return fmt.Errorf("unsupported EC curve %q", k.Crv)
+		}
+		x, errX := decodeJWKField(k.X)
+		y, errY := decodeJWKField(k.Y)
+		if errX != nil || errY != nil {
+			return fmt.Errorf("invalid EC key")
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return fmt.Errorf("EC point is not on P-256")
+		}
+		k.key = pub
+	default:
+		return fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+	if k.Alg != "" && jwkAlgorithms[k.Alg] != k.Kty {
+		return fmt.Errorf("alg %q does not match key type %q", k.Alg, k.Kty)
+	}
+	return nil
+}
+
+func decodeJWKField(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// JWTVerifier verifies bearer tokens against a JWKS file, reloading it when
+// it changes on disk.
+type JWTVerifier struct {
+	cfg    JWTConfig
+	parser *jwt.Parser
+
+	mu      sync.Mutex
+	keys    []jwk
+	modTime time.Time
+}
+
+// NewJWTVerifier creates a verifier; token times are checked against clk.
+func NewJWTVerifier(cfg JWTConfig, clk chronoclock.Clock) *JWTVerifier {
+	if cfg.KeyClaim == "" {
+		cfg.KeyClaim = "sub"
+	}
+	if cfg.PlanClaim == "" {
+		cfg.PlanClaim = "plan"
+	}
+	if cfg.RateLimitClaim == "" {
+		cfg.RateLimitClaim = "rate_limit"
+	}
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "RS256", "ES256"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(clk.Now),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	return &JWTVerifier{cfg: cfg, parser: jwt.NewParser(opts...)}
+}
+
+// errJWKSUnavailable marks verification failures caused by the key file, not the token.
+var errJWKSUnavailable = errors.New("jwks unavailable")
+
+func (v *JWTVerifier) currentKeys() ([]jwk, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	info, err := os.Stat(v.cfg.JWKSFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errJWKSUnavailable, err)
+	}
+	if v.keys != nil && info.ModTime().Equal(v.modTime) {
+		return v.keys, nil
+	}
+	keys, err := LoadJWKS(v.cfg.JWKSFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errJWKSUnavailable, err)
+	}
+	v.keys, v.modTime = keys, info.ModTime()
+	return keys, nil
+}
+
+// Verify checks the token signature, expiry, issuer and audience and
+// extracts the client identity.
+func (v *JWTVerifier) Verify(raw string) (JWTIdentity, error) {
+	keys, err := v.currentKeys()
+	if err != nil {
+		return JWTIdentity{}, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = v.parser.ParseWithClaims(raw, claims, func(token *jwt.Token) (any, error) {
+		alg := token.Method.Alg()
+		kid, _ := token.Header["kid"].(string)
+		var match *jwk
+		for i := range keys {
+			k := &keys[i]
+			if k.Kty != jwkAlgorithms[alg] || (k.Alg != "" && k.Alg != alg) || (kid != "" && k.Kid != kid) {
+				continue
+			}
+			if match != nil {
+				return nil, fmt.Errorf("several keys match; set kid")
+			}
+			match = k
+		}
+		if match == nil {
+			return nil, fmt.Errorf("no key for alg %s kid %q", alg, kid)
+		}
+		return match.key, nil
+	})
+	if err != nil {
+		return JWTIdentity{}, err
+	}
+
+	id := JWTIdentity{Scopes: tokenScopes(claims)}
+	id.Subject, _ = claims["sub"].(string)
+	id.Key = claimString(claims[v.cfg.KeyClaim])
+	if id.Key == "" {
+		return JWTIdentity{}, fmt.Errorf("token lacks claim %q", v.cfg.KeyClaim)
+	}
+	id.Plan = claimString(claims[v.cfg.PlanClaim])
+	if limits, ok := v.cfg.Plans[id.Plan]; ok && id.Plan != "" {
+		id.Limits = &limits
+	}
+	if raw, ok := claims[v.cfg.RateLimitClaim]; ok {
+		limits, err := parseRateLimitClaim(raw)
+		if err != nil {
+			return JWTIdentity{}, fmt.Errorf("claim %q: %w", v.cfg.RateLimitClaim, err)
+		}
+		id.Limits = &limits
+	}
+	return id, nil
+}
+
+func parseRateLimitClaim(raw any) (PlanLimits, error) {
+	if rate, ok := raw.(float64); ok {
+		if rate <= 0 || rate != float64(int(rate)) {
+			return PlanLimits{}, fmt.Errorf("rate must be a positive integer")
+		}
+		return PlanLimits{Rate: int(rate)}, nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return PlanLimits{}, err
+	}
+	var limits PlanLimits
+	if err := json.Unmarshal(data, &limits); err != nil {
+		return PlanLimits{}, err
+	}
+	return limits, nil
+}
+
+func claimString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%.0f", v)
+	default:
+		return ""
+	}
+}
+
+// tokenScopes reads the OAuth space-separated "scope" claim or a "scopes" array.
+func tokenScopes(claims jwt.MapClaims) []string {
+	if s, ok := claims["scope"].(string); ok {
+		return strings.Fields(s)
+	}
+	list, _ := claims["scopes"].([]any)
+	out := make([]string, 0, len(list))
+	for _, v := range list {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
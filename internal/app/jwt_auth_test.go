@@ -0,0 +1,207 @@
+package app
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type jwtTestKeys struct {
+	hmac  []byte
+	rsa   *rsa.PrivateKey
+	ecdsa *ecdsa.PrivateKey
+	path  string
+}
+
+func newJWTTestKeys(t *testing.T) jwtTestKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ec key: %v", err)
+	}
+	keys := jwtTestKeys{hmac: []byte("test-hmac-secret-0123456789abcdef"), rsa: rsaKey, ecdsa: ecKey}
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	set := map[string]any{"keys": []map[string]string{
+		{"kid": "hs", "kty": "oct", "alg": "HS256", "k": b64(keys.hmac)},
+		{"kid": "rs", "kty": "RSA", "alg": "RS256", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kid": "es", "kty": "EC", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+	}}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("encode jwks: %v", err)
+	}
+	keys.path = filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(keys.path, data, 0o644); err != nil {
+		t.Fatalf("write jwks: %v", err)
+	}
+	return keys
+}
+
+func (k jwtTestKeys) sign(t *testing.T, method jwt.SigningMethod, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	var key any
+	switch kid {
+	case "hs":
+		key = k.hmac
+	case "rs":
+		key = k.rsa
+	case "es":
+		key = k.ecdsa
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return signed
+}
+
+func bearerRequest(handler http.Handler, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestJWTClaimsSelectKeyAndLimits(t *testing.T) {
+	now := time.Date(2026, 2, 8, 20, 0, 0, 0, time.UTC)
+	keys := newJWTTestKeys(t)
+
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.Rate = 1
+	cfg.JWT = JWTConfig{JWKSFile: keys.path, Plans: map[string]PlanLimits{"pro": {Rate: 3}}}
+
+	handler := newAuthTestHandlerAt(t, cfg, now)
+
+	exp := now.Add(time.Hour).Unix()
+
+	// The plan claim raises the limit for this subject to 3.
+	pro := keys.sign(t, jwt.SigningMethodHS256, "hs", jwt.MapClaims{"sub": "user-1", "plan": "pro", "exp": exp})
+	for i := 0; i < 3; i++ {
+		resp := bearerRequest(handler, "/api/profile", pro)
+		assertStatus(t, resp, http.StatusOK)
+		if got := resp.Header().Get("X-RateLimit-Limit"); got != "3" {
+			t.Fatalf("pro X-RateLimit-Limit = %q, want 3", got)
+		}
+	}
+	assertStatus(t, bearerRequest(handler, "/api/profile", pro), http.StatusTooManyRequests)
+
+	// A different token for the same subject and plan shares its budget.
+	again := keys.sign(t, jwt.SigningMethodES256, "es", jwt.MapClaims{"sub": "user-1", "plan": "pro", "exp": exp})
+	assertStatus(t, bearerRequest(handler, "/api/profile", again), http.StatusTooManyRequests)
+
+	// No plan falls back to the configured limit of 1.
+	free := keys.sign(t, jwt.SigningMethodRS256, "rs", jwt.MapClaims{"sub": "user-2", "exp": exp})
+	resp := bearerRequest(handler, "/api/profile", free)
+	assertStatus(t, resp, http.StatusOK)
+	if got := resp.Header().Get("X-RateLimit-Limit"); got != "1" {
+		t.Fatalf("free X-RateLimit-Limit = %q, want 1", got)
+	}
+
+	// A rate_limit claim wins over the plan.
+	direct := keys.sign(t, jwt.SigningMethodRS256, "rs", jwt.MapClaims{"sub": "user-3", "plan": "pro", "rate_limit": map[string]any{"rate": 7}, "exp": exp})
+	resp = bearerRequest(handler, "/api/profile", direct)
+	if got := resp.Header().Get("X-RateLimit-Limit"); got != "7" {
+		t.Fatalf("rate_limit X-RateLimit-Limit = %q, want 7", got)
+	}
+}
+
+func TestJWTRejectsInvalidTokensBeforeLimiter(t *testing.T) {
+	now := time.Date(2026, 2, 8, 20, 0, 0, 0, time.UTC)
+	keys := newJWTTestKeys(t)
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.JWT = JWTConfig{JWKSFile: keys.path, Issuer: "https://issuer.test"}
+	handler := newAuthTestHandlerAt(t, cfg, now)
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	forged := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "x", "iss": "https://issuer.test", "exp": now.Add(time.Hour).Unix()})
+	forged.Header["kid"] = "rs"
+	forgedToken, err := forged.SignedString(otherKey)
+	if err != nil {
+		t.Fatalf("sign forged token: %v", err)
+	}
+
+	cases := map[string]string{
+		"expired":     keys.sign(t, jwt.SigningMethodHS256, "hs", jwt.MapClaims{"sub": "x", "iss": "https://issuer.test", "exp": now.Add(-time.Minute).Unix()}),
+		"no exp":      keys.sign(t, jwt.SigningMethodHS256, "hs", jwt.MapClaims{"sub": "x", "iss": "https://issuer.test"}),
+		"bad issuer":  keys.sign(t, jwt.SigningMethodHS256, "hs", jwt.MapClaims{"sub": "x", "iss": "https://evil.test", "exp": now.Add(time.Hour).Unix()}),
+		"no subject":  keys.sign(t, jwt.SigningMethodHS256, "hs", jwt.MapClaims{"iss": "https://issuer.test", "exp": now.Add(time.Hour).Unix()}),
+		"bad sig":     forgedToken,
+		"not a token": "abc.def.ghi",
+	}
+	for name, token := range cases {
+		resp := bearerRequest(handler, "/api/profile", token)
+		if resp.Code != http.StatusUnauthorized {
+			t.Fatalf("%s: status = %d, want 401", name, resp.Code)
+		}
+		if resp.Header().Get("X-RateLimit-Limit") != "" {
+			t.Fatalf("%s: invalid token reached the rate limiter", name)
+		}
+	}
+
+	valid := keys.sign(t, jwt.SigningMethodHS256, "hs", jwt.MapClaims{"sub": "x", "iss": "https://issuer.test", "exp": now.Add(time.Hour).Unix()})
+	assertStatus(t, bearerRequest(handler, "/api/profile", valid), http.StatusOK)
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/api/profile", "some-api-key", "", "", "198.51.100.80:8080"), http.StatusUnauthorized)
+}
+
+func TestJWTKeysAndClaimLimitersStayApartFromAPIKeys(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/profile", nil)
+	req = req.WithContext(WithJWTIdentity(req.Context(), JWTIdentity{Key: "key_1"}))
+	if got := clientKeyFromRequest(req); got != jwtKeyPrefix+"key_1" {
+		t.Fatalf("token rate-limit key = %q, want %q", got, jwtKeyPrefix+"key_1")
+	}
+	if err := validateAPIKey(APIKey{ID: jwtKeyPrefix + "x", Hash: HashAPIKey("s")}); err == nil {
+		t.Fatal("validateAPIKey() accepted an id in the token keyspace")
+	}
+
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 2, 8, 20, 0, 0, 0, time.UTC))
+	stores, cleanup := newMemoryOnlyStorageSet(t, cfg, vc)
+	defer cleanup()
+	limiters := newRouteLimiters(cfg, vc, nil, stores)
+	if _, ok := limiters.claim("", &PlanLimits{Rate: 3}).(*keyPrefixLimiter); !ok {
+		t.Fatal("claim limiter is not built on the storage backend")
+	}
+	if _, ok := limiters.get("", &PlanLimits{Rate: 3}).(*keyPrefixLimiter); ok {
+		t.Fatal("route limiter built on the storage backend")
+	}
+	if _, ok := limiters.get(limiter.AlgorithmTokenBucket, nil).(*keyPrefixLimiter); ok {
+		t.Fatal("token bucket limiter built on a fixed-window memory backend")
+	}
+}
+
+func TestJWTConfigValidateLoadsJWKS(t *testing.T) {
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.JWT = JWTConfig{JWKSFile: filepath.Join(t.TempDir(), "missing.json")}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("Validate() accepted a missing JWKS file")
+	}
+	cfg.JWT = JWTConfig{Plans: map[string]PlanLimits{"bad": {Rate: -1}}}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("Validate() accepted negative plan limits")
+	}
+}
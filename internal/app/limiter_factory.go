@@ -1,7 +1,9 @@
 package app
 
 import (
+	"context"
 	"fmt"
+	"sync"
 
 	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
 	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
@@ -42,6 +44,131 @@ func NewStorageBackedLimiter(cfg Config, clk chronoclock.Clock) (limiter.Limiter
 	return lim, backend, nil
 }
 
+// maxRouteLimiters bounds how many distinct limit overrides get their own
+// limiter, since token claims choose them.
+const maxRouteLimiters = 256
+
+// routeLimiters lazily builds one limiter per algorithm and limit override.
+// The spec with no algorithm and no override is the main limiter. Route
+// algorithms and overrides use in-process limiters. Token claim and tenant
+// limits share the configured storage backend, under a key prefix per spec,
+// so replicas enforce them together; algorithms the backend cannot run
+// (Redis and CRDT are sliding-window only, and a memory backend has one
+// algorithm and burst) stay in process.
+type routeLimiters struct {
+	mu       sync.Mutex
+	cfg      Config
+	clk      chronoclock.Clock
+	stores   *StorageLimiterSet
+	limiters map[limiterSpec]limiter.Limiter
+}
+
+type limiterSpec struct {
+	algorithm limiter.Algorithm
+	limits    PlanLimits
+	// shared limiters run on the storage backend when it can enforce them.
+	shared bool
+}
+
+func newRouteLimiters(cfg Config, clk chronoclock.Clock, main limiter.Limiter, stores *StorageLimiterSet) *routeLimiters {
+	return &routeLimiters{
+		cfg:      cfg,
+		clk:      clk,
+		stores:   stores,
+		limiters: map[limiterSpec]limiter.Limiter{{}: main},
+	}
+}
+
+// get returns the in-process limiter for algo (empty = configured) and
+// limits (nil = configured), or nil when it cannot be built.
+func (l *routeLimiters) get(algo limiter.Algorithm, limits *PlanLimits) limiter.Limiter {
+	return l.lookup(algo, limits, false)
+}
+
+// claim is get for token claim and tenant limits, which run on the storage
+// backend when it can enforce them.
+func (l *routeLimiters) claim(algo limiter.Algorithm, limits *PlanLimits) limiter.Limiter {
+	return l.lookup(algo, limits, true)
+}
+
+func (l *routeLimiters) lookup(algo limiter.Algorithm, limits *PlanLimits, shared bool) limiter.Limiter {
+	spec := limiterSpec{algorithm: algo, shared: shared}
+	if limits != nil {
+		spec.limits = *limits
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if lim, ok := l.limiters[spec]; ok {
+		return lim
+	}
+	if len(l.limiters) >= maxRouteLimiters {
+		return nil
+	}
+
+	specCfg := spec.limits.Apply(l.cfg)
+	if algo != "" {
+		specCfg.Algorithm = algo
+	}
+	var lim limiter.Limiter
+	var err error
+	if shared {
+		lim, err = l.newLimiter(specCfg)
+	} else {
+		lim, err = NewLimiter(specCfg, l.clk)
+	}
+	if err != nil {
+		lim = nil
+	}
+	l.limiters[spec] = lim
+	return lim
+}
+
+// newLimiter builds a limiter for specCfg on the storage backend, under a
+// key prefix per spec, or in process when the backend cannot enforce it.
+func (l *routeLimiters) newLimiter(specCfg Config) (limiter.Limiter, error) {
+	if !l.backendRuns(specCfg) {
+		return NewLimiter(specCfg, l.clk)
+	}
+	store, err := l.stores.store(l.cfg.StorageBackend)
+	if err != nil {
+		return nil, err
+	}
+	lim, err := limiter.NewStorageLimiter(store, specCfg.Rate, specCfg.Window, l.clk)
+	if err != nil {
+		return nil, err
+	}
+	return &keyPrefixLimiter{
+		next:   lim,
+		prefix: fmt.Sprintf("limits:%s:%d:%s:%d:", specCfg.Algorithm, specCfg.Rate, specCfg.Window, specCfg.Burst),
+	}, nil
+}
+
+// backendRuns reports whether the configured storage backend can enforce
+// specCfg's algorithm and burst.
+func (l *routeLimiters) backendRuns(specCfg Config) bool {
+	if l.stores == nil {
+		return false
+	}
+	switch l.cfg.StorageBackend {
+	case chronostorage.BackendMemory:
+		return specCfg.Algorithm == l.cfg.Algorithm && specCfg.Burst == l.cfg.Burst
+	case chronostorage.BackendRedis, chronostorage.BackendCRDT:
+		return specCfg.Algorithm == limiter.AlgorithmSlidingWindow
+	}
+	return false
+}
+
+// keyPrefixLimiter namespaces every key before delegating to the wrapped limiter.
+type keyPrefixLimiter struct {
+	next   limiter.Limiter
+	prefix string
+}
+
+func (l *keyPrefixLimiter) Allow(ctx context.Context, key string) limiter.Decision {
+	return l.next.Allow(ctx, l.prefix+key)
+}
+
 func injectClockIntoStorageConfig(cfg *chronostorage.Config, clk chronoclock.Clock) {
 	if cfg == nil {
 		return
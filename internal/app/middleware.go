@@ -3,7 +3,6 @@ package app
 import (
 	"log"
 	"math"
-	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -19,14 +18,16 @@ func RecordingMiddleware(state *RecordingState, clk chronoclock.Clock) func(http
 	if state == nil {
 		state = NewRecordingState(nil, true)
 	}
+	return recordingMiddleware(func(*http.Request) *RecordingState { return state }, clk)
+}
 
+// recordingMiddleware is RecordingMiddleware with the recording chosen per
+// request. Keys are recorded without their tenant namespace, since each
+// tenant has its own recording.
+func recordingMiddleware(stateFor func(*http.Request) *RecordingState, clk chronoclock.Clock) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if err := state.Record(chronorecorder.TrafficRecord{
-				Timestamp: clk.Now(),
-				Key:       clientKeyFromRequest(r),
-				Endpoint:  r.Method + " " + r.URL.Path,
-			}); err != nil {
+			if err := stateFor(r).Record(newTrafficRecord(r, clk)); err != nil {
 				log.Printf("record traffic: %v", err)
 			}
 
@@ -35,12 +36,41 @@ func RecordingMiddleware(state *RecordingState, clk chronoclock.Clock) func(http
 	}
 }
 
-// RateLimitMiddleware enforces rate limiting for protected endpoints.
-func RateLimitMiddleware(lim limiter.Limiter, clk chronoclock.Clock) func(http.Handler) http.Handler {
+// newTrafficRecord captures r as the recording and usage middleware see it.
+func newTrafficRecord(r *http.Request, clk chronoclock.Clock) chronorecorder.TrafficRecord {
+	return chronorecorder.TrafficRecord{
+		Timestamp: clk.Now(),
+		Key:       clientKeyFromRequest(r),
+		Endpoint:  r.Method + " " + r.URL.Path,
+	}
+}
+
+// RateLimitMiddleware enforces rate limiting for protected endpoints and
+// answers denied requests as configured by deny. Requests exempted by the IP
+// filter pass through without spending budget.
+func RateLimitMiddleware(lim limiter.Limiter, clk chronoclock.Clock, deny DenyResponse) func(http.Handler) http.Handler {
+	return rateLimitMiddleware(func(*http.Request) limiter.Limiter { return lim }, nil, clk, deny)
+}
+
+// rateLimitMiddleware is RateLimitMiddleware with the limiter chosen per
+// request. When shadowFor returns a route policy, its shadow policy is
+// evaluated for the same key; its verdict is only counted.
+func rateLimitMiddleware(limiterFor func(*http.Request) limiter.Limiter, shadowFor func(*http.Request) *routePolicy, clk chronoclock.Clock, deny DenyResponse) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isRateLimitExempt(r.Context()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			key := clientKeyFromRequest(r)
-			decision := lim.Allow(r.Context(), key)
+			limitKey := tenantKey(r.Context(), key)
+			decision := limiterFor(r).Allow(r.Context(), limitKey)
+			if shadowFor != nil {
+				if policy := shadowFor(r); policy != nil {
+					policy.evaluateShadow(r.Context(), limitKey, decision.Allowed)
+				}
+			}
 
 			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(decision.Limit))
 			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
@@ -52,39 +82,41 @@ func RateLimitMiddleware(lim limiter.Limiter, clk chronoclock.Clock) func(http.H
 				return
 			}
 
+			markUsageDenied(r.Context())
 			retryAfter := retryAfterSeconds(decision.RetryAt, clk.Now())
 			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
-			writeJSON(w, http.StatusTooManyRequests, map[string]string{
-				"error":   "rate_limited",
-				"message": "too many requests",
-				"key":     key,
+			deny.write(w, r, denyDetails{
+				key:        key,
+				endpoint:   r.Method + " " + r.URL.Path,
+				limit:      decision.Limit,
+				retryAfter: retryAfter,
 			})
 		})
 	}
 }
 
+// jwtKeyPrefix keeps token keys apart from API key IDs, which may never
+// start with it, so a token subject cannot spend an API key's budget.
+const jwtKeyPrefix = "jwt:"
+
+// clientKeyFromRequest resolves the rate-limit key: the verified token's key
+// claim (under jwtKeyPrefix) or the authenticated key ID, then X-API-Key,
+// X-Forwarded-For and the remote address; addresses are aggregated by the
+// configured IPKeyPrefixes. With API keys on, X-API-Key is a secret and is
+// never used itself: a valid key is keyed by its ID, anything else by address.
 func clientKeyFromRequest(r *http.Request) string {
-	if apiKey := strings.TrimSpace(r.Header.Get("X-API-Key")); apiKey != "" {
-		return apiKey
+	if id, ok := JWTIdentityFromContext(r.Context()); ok {
+		return jwtKeyPrefix + id.Key
 	}
-
-	if forwardedFor := strings.TrimSpace(r.Header.Get("X-Forwarded-For")); forwardedFor != "" {
-		parts := strings.Split(forwardedFor, ",")
-		if len(parts) > 0 {
-			ip := strings.TrimSpace(parts[0])
-			if ip != "" {
-				return ip
-			}
-		}
+	if key, ok := APIKeyFromContext(r.Context()); ok {
+		return key.ID
 	}
-
-	host, _, err := net.SplitHostPort(strings.TrimSpace(r.RemoteAddr))
-	if err == nil && host != "" {
-		return host
+	if apiKey := strings.TrimSpace(r.Header.Get("X-API-Key")); apiKey != "" && !apiKeysEnabled(r.Context()) {
+		return apiKey
 	}
 
-	if remoteAddr := strings.TrimSpace(r.RemoteAddr); remoteAddr != "" {
-		return remoteAddr
+	if ip := clientIPFromRequest(r); ip != "" {
+		return ipKeyPrefixesFromContext(r.Context()).Key(ip)
 	}
 
 	return "unknown"
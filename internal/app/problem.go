@@ -0,0 +1,123 @@
+package app
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Stable machine-readable error codes. They are the "code" member of a
+// problem document, the suffix of its type URI, and the "error" member of the
+// legacy body.
+const (
+	ErrCodeMethodNotAllowed     = "method_not_allowed"
+	ErrCodeNotFound             = "not_found"
+	ErrCodeInvalidRequest       = "invalid_request"
+	ErrCodeRateLimited          = "rate_limited"
+	ErrCodeLimiterUnavailable   = "limiter_unavailable"
+	ErrCodeBackendUnavailable   = "backend_unavailable"
+	ErrCodeStorageError         = "storage_error"
+	ErrCodeInvalidScope         = "invalid_scope"
+	ErrCodeExportFailed         = "export_failed"
+	ErrCodeInvalidReplayRequest = "invalid_replay_request"
+	ErrCodeReplayFailed         = "replay_failed"
+	ErrCodeReplayQueueFull      = "replay_queue_full"
+	ErrCodeReplayHistoryFailed  = "replay_history_failed"
+	ErrCodeUnauthorized         = "unauthorized"
+	ErrCodeInsufficientScope    = "insufficient_scope"
+	ErrCodeAuthUnavailable      = "auth_unavailable"
+	ErrCodeIPDenied             = "ip_denied"
+	ErrCodeOverloaded           = "overloaded"
+	ErrCodeQuotaExceeded        = "quota_exceeded"
+	ErrCodeQuotaUnavailable     = "quota_unavailable"
+	ErrCodeUsageUnavailable     = "usage_unavailable"
+	ErrCodeUnknownTenant        = "unknown_tenant"
+	ErrCodeTenantConflict       = "tenant_conflict"
+)
+
+const (
+	problemContentType = "application/problem+json"
+	// ProblemTypePrefix prefixes the error code in problem type URIs.
+	ProblemTypePrefix = "urn:chronogate:error:"
+)
+
+// APIError is the error model of every API endpoint. It is written as the
+// legacy {"error","message"} body, or as an RFC 7807 problem document for
+// clients that ask for application/problem+json.
+type APIError struct {
+	Status int
+	Code   string
+	Detail string
+	// Extensions are extra members, such as replay validation issues.
+	Extensions map[string]any
+}
+
+func (e APIError) Error() string {
+	return e.Code + ": " + e.Detail
+}
+
+// writeError writes an APIError without extensions.
+func writeError(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	writeAPIError(w, r, APIError{Status: status, Code: code, Detail: detail})
+}
+
+func writeAPIError(w http.ResponseWriter, r *http.Request, e APIError) {
+	if !wantsProblemJSON(r) {
+		writeJSON(w, e.Status, legacyErrorBody(e))
+		return
+	}
+	writeJSONContent(w, e.Status, problemContentType, problemBody(ProblemTypePrefix+e.Code, e, r.URL.Path))
+}
+
+// legacyErrorBody builds the {"error","message"} body; extensions never
+// replace those two members.
+func legacyErrorBody(e APIError) map[string]any {
+	body := make(map[string]any, len(e.Extensions)+2)
+	for k, v := range e.Extensions {
+		body[k] = v
+	}
+	body["error"] = e.Code
+	body["message"] = e.Detail
+	return body
+}
+
+// problemBody builds an RFC 7807 document; extensions never replace the
+// standard members.
+func problemBody(problemType string, e APIError, instance string) map[string]any {
+	body := make(map[string]any, len(e.Extensions)+6)
+	for k, v := range e.Extensions {
+		body[k] = v
+	}
+	body["type"] = problemType
+	body["title"] = http.StatusText(e.Status)
+	body["status"] = e.Status
+	body["detail"] = e.Detail
+	body["instance"] = instance
+	body["code"] = e.Code
+	return body
+}
+
+// wantsProblemJSON reports whether the response should be problem+json: only
+// clients whose Accept header explicitly lists application/problem+json get
+// it. No Accept header, wildcards and application/json keep the legacy body.
+func wantsProblemJSON(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	for _, value := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err != nil {
+				continue
+			}
+			if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q <= 0 {
+				continue
+			}
+			if mediaType == problemContentType {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+)
+
+func TestWantsProblemJSON(t *testing.T) {
+	cases := []struct {
+		accept string
+		want   bool
+	}{
+		{"", false},
+		{"*/*", false},
+		{"application/problem+json", true},
+		{"application/json", false},
+		{"application/json, application/problem+json", true},
+		{"application/json, application/problem+json;q=0", false},
+		{"text/html", false},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		if got := wantsProblemJSON(req); got != tc.want {
+			t.Fatalf("wantsProblemJSON(%q) = %v, want %v", tc.accept, got, tc.want)
+		}
+	}
+}
+
+func TestAPIErrorsNegotiateProblemOrLegacyBody(t *testing.T) {
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 2, 8, 17, 0, 0, 0, time.UTC))
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	mainLimiter, mainStorage, err := NewStorageBackedLimiter(cfg, vc)
+	if err != nil {
+		t.Fatalf("NewStorageBackedLimiter() error = %v", err)
+	}
+	defer mainStorage.Close()
+	storageSet, cleanup := newMemoryOnlyStorageSet(t, cfg, vc)
+	defer cleanup()
+	handler := NewHandler(cfg, mainLimiter, vc, chronorecorder.New(nil), storageSet)
+
+	do := func(method, path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := do(http.MethodDelete, "/api/replay/last", "application/problem+json")
+	assertStatus(t, resp, http.StatusMethodNotAllowed)
+	if ct := resp.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("Content-Type = %q, want application/problem+json", ct)
+	}
+	var problem map[string]any
+	if err := json.Unmarshal(resp.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	if problem["type"] != ProblemTypePrefix+ErrCodeMethodNotAllowed || problem["code"] != ErrCodeMethodNotAllowed ||
+		problem["title"] != "Method Not Allowed" || problem["status"] != float64(http.StatusMethodNotAllowed) ||
+		problem["instance"] != "/api/replay/last" || problem["detail"] == "" {
+		t.Fatalf("problem = %+v", problem)
+	}
+
+	for _, accept := range []string{"", "*/*", "application/json"} {
+		resp = do(http.MethodGet, "/api/replays/missing", accept)
+		assertStatus(t, resp, http.StatusNotFound)
+		if ct := resp.Header().Get("Content-Type"); ct != "application/json" {
+			t.Fatalf("Accept %q: legacy Content-Type = %q, want application/json", accept, ct)
+		}
+		var legacy map[string]string
+		if err := json.Unmarshal(resp.Body.Bytes(), &legacy); err != nil {
+			t.Fatalf("decode legacy body: %v", err)
+		}
+		if legacy["error"] != ErrCodeNotFound || legacy["message"] == "" || len(legacy) != 2 {
+			t.Fatalf("Accept %q: legacy body = %+v", accept, legacy)
+		}
+	}
+}
+
+func TestDefaultDenyBodyIsNegotiated(t *testing.T) {
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.Rate = 1
+	handler := newAuthTestHandler(t, cfg)
+
+	deny := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/profile", nil)
+		req.Header.Set("X-API-Key", "negotiated")
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		return resp
+	}
+	assertStatus(t, deny(""), http.StatusOK)
+
+	resp := deny("*/*")
+	assertStatus(t, resp, http.StatusTooManyRequests)
+	if ct := resp.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("*/* deny Content-Type = %q, want application/json", ct)
+	}
+
+	resp = deny("application/problem+json")
+	assertStatus(t, resp, http.StatusTooManyRequests)
+	if ct := resp.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("problem deny Content-Type = %q, want application/problem+json", ct)
+	}
+	var problem map[string]any
+	if err := json.Unmarshal(resp.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	if problem["code"] != ErrCodeRateLimited || problem["status"] != float64(http.StatusTooManyRequests) {
+		t.Fatalf("problem = %+v", problem)
+	}
+}
+
+func TestAPIErrorExtensionsKeepStandardMembers(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/storage/compare", nil)
+	req.Header.Set("Accept", "application/problem+json")
+	resp := httptest.NewRecorder()
+	writeAPIError(resp, req, APIError{
+		Status:     http.StatusServiceUnavailable,
+		Code:       ErrCodeBackendUnavailable,
+		Detail:     "redis down",
+		Extensions: map[string]any{"backend": "redis", "status": "bogus"},
+	})
+
+	var problem map[string]any
+	if err := json.Unmarshal(resp.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("decode problem: %v", err)
+	}
+	if problem["backend"] != "redis" || problem["status"] != float64(http.StatusServiceUnavailable) {
+		t.Fatalf("problem = %+v", problem)
+	}
+
+	resp = httptest.NewRecorder()
+	writeAPIError(resp, httptest.NewRequest(http.MethodGet, "/", nil), APIError{
+		Status:     http.StatusBadRequest,
+		Code:       ErrCodeInvalidRequest,
+		Detail:     "bad",
+		Extensions: map[string]any{"error": "spoofed", "message": "spoofed"},
+	})
+	var legacy map[string]any
+	if err := json.Unmarshal(resp.Body.Bytes(), &legacy); err != nil {
+		t.Fatalf("decode legacy body: %v", err)
+	}
+	if legacy["error"] != ErrCodeInvalidRequest || legacy["message"] != "bad" {
+		t.Fatalf("legacy body = %+v", legacy)
+	}
+}
@@ -0,0 +1,383 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+)
+
+// Quota period names, used in X-Quota-* headers and /api/quota.
+const (
+	QuotaDaily   = "daily"
+	QuotaMonthly = "monthly"
+)
+
+// QuotaLimits are long-horizon request allowances; zero means unlimited.
+type QuotaLimits struct {
+	Daily   int64 `json:"daily,omitempty"`
+	Monthly int64 `json:"monthly,omitempty"`
+}
+
+// QuotaConfig turns on per-key quotas when any limit is set. Periods reset
+// at calendar boundaries in Location.
+type QuotaConfig struct {
+	QuotaLimits
+	// Tiers overrides the limits per API key tier or JWT plan.
+	Tiers map[string]QuotaLimits
+	// Location aligns daily and monthly resets; nil means UTC.
+	Location *time.Location
+	// File persists counters; empty keeps them in memory.
+	File string
+}
+
+func (c QuotaConfig) enabled() bool {
+	if c.Daily > 0 || c.Monthly > 0 {
+		return true
+	}
+	for _, limits := range c.Tiers {
+		if limits.Daily > 0 || limits.Monthly > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate rejects negative limits.
+func (c QuotaConfig) Validate() error {
+	if c.Daily < 0 || c.Monthly < 0 {
+		return fmt.Errorf("quota limits must be >= 0")
+	}
+	for tier, limits := range c.Tiers {
+		if limits.Daily < 0 || limits.Monthly < 0 {
+			return fmt.Errorf("tier %q: quota limits must be >= 0", tier)
+		}
+	}
+	return nil
+}
+
+// parseQuotaTiers parses "free=1000/20000,pro=0/500000" (daily/monthly).
+func parseQuotaTiers(raw string) (map[string]QuotaLimits, error) {
+	entries := splitCommaList(raw)
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]QuotaLimits, len(entries))
+	for _, entry := range entries {
+		tier, limits, ok := strings.Cut(entry, "=")
+		daily, monthly, ok2 := strings.Cut(limits, "/")
+		if !ok || !ok2 || strings.TrimSpace(tier) == "" {
+			return nil, fmt.Errorf("invalid tier quota %q: want tier=daily/monthly", entry)
+		}
+		d, err := strconv.ParseInt(strings.TrimSpace(daily), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tier quota %q: %w", entry, err)
+		}
+		m, err := strconv.ParseInt(strings.TrimSpace(monthly), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tier quota %q: %w", entry, err)
+		}
+		out[strings.TrimSpace(tier)] = QuotaLimits{Daily: d, Monthly: m}
+	}
+	return out, nil
+}
+
+// QuotaUsage is a key's use of one quota period.
+type QuotaUsage struct {
+	Limit     int64     `json:"limit"`
+	Used      int64     `json:"used"`
+	Remaining int64     `json:"remaining"`
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+// QuotaStatus is a key's use of every limited quota period.
+type QuotaStatus struct {
+	Key      string                `json:"key"`
+	TimeZone string                `json:"time_zone"`
+	Periods  map[string]QuotaUsage `json:"periods"`
+}
+
+// quotaPeriod is one calendar period containing a moment.
+type quotaPeriod struct {
+	name    string
+	id      string
+	limit   int64
+	resetAt time.Time
+}
+
+// Quotas counts allowed requests per key against daily and monthly limits.
+type Quotas struct {
+	cfg   QuotaConfig
+	clk   chronoclock.Clock
+	store QuotaStore
+
+	mu sync.Mutex
+	// prunedFor is the day whose earlier periods were last pruned.
+	prunedFor string
+}
+
+// NewQuotas returns nil when cfg sets no limits. Counters of past periods are
+// pruned from store now and whenever the day rolls over.
+func NewQuotas(cfg QuotaConfig, clk chronoclock.Clock, store QuotaStore) *Quotas {
+	if !cfg.enabled() {
+		return nil
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.UTC
+	}
+	q := &Quotas{cfg: cfg, clk: clk, store: store}
+	q.mu.Lock()
+	q.prune(clk.Now())
+	q.mu.Unlock()
+	return q
+}
+
+// prune drops counters of periods before now's once per day. Callers hold q.mu.
+func (q *Quotas) prune(now time.Time) {
+	day := q.periods(QuotaLimits{Daily: 1}, now)[0].id
+	if day == q.prunedFor {
+		return
+	}
+	q.prunedFor = day
+	current := make(map[string]bool)
+	for _, p := range q.periods(QuotaLimits{Daily: 1, Monthly: 1}, now) {
+		current[p.id] = true
+	}
+	if err := q.store.Prune(func(period string) bool { return current[period] }); err != nil {
+		log.Printf("prune quotas: %v", err)
+	}
+}
+
+// newQuotas builds the configured quotas on store, the storage set's quota
+// store. Without one (as in handlers built on a hand-made set) counters are
+// kept in memory; if it failed to open, every quota check fails with
+// quota_unavailable rather than silently counting in memory.
+func newQuotas(cfg QuotaConfig, clk chronoclock.Clock, store QuotaStore, err error) *Quotas {
+	if !cfg.enabled() {
+		return nil
+	}
+	switch {
+	case err != nil:
+		log.Printf("quota store: %v", err)
+		store = unavailableQuotaStore{err: err}
+	case store == nil:
+		store = NewMemoryQuotaStore()
+	}
+	return NewQuotas(cfg, clk, store)
+}
+
+// limitsFor returns the limits of the request's key tier or token plan, then
+// of its tenant.
+func (q *Quotas) limitsFor(r *http.Request) QuotaLimits {
+	if key, ok := APIKeyFromContext(r.Context()); ok {
+		if limits, ok := q.cfg.Tiers[key.Tier]; ok {
+			return limits
+		}
+	}
+	if id, ok := JWTIdentityFromContext(r.Context()); ok {
+		if limits, ok := q.cfg.Tiers[id.Plan]; ok {
+			return limits
+		}
+	}
+	if t, ok := TenantFromContext(r.Context()); ok && t.Quota != nil {
+		return *t.Quota
+	}
+	return q.cfg.QuotaLimits
+}
+
+// limitsForKey returns the limits of the API key id, found in keys, or else
+// of the request's tenant. Token keys have no stored plan and get the tenant's
+// or default limits.
+func (q *Quotas) limitsForKey(r *http.Request, id string, keys APIKeyStore) QuotaLimits {
+	if keys != nil {
+		if key, ok, err := keys.Get(id); err == nil && ok {
+			if limits, ok := q.cfg.Tiers[key.Tier]; ok {
+				return limits
+			}
+		}
+	}
+	if t, ok := TenantFromContext(r.Context()); ok && t.Quota != nil {
+		return *t.Quota
+	}
+	return q.cfg.QuotaLimits
+}
+
+// periods returns the limited periods containing now. Boundaries are local
+// midnights and month starts, so days are 23 or 25 hours across DST changes.
+func (q *Quotas) periods(limits QuotaLimits, now time.Time) []quotaPeriod {
+	local := now.In(q.cfg.Location)
+	var out []quotaPeriod
+	if limits.Daily > 0 {
+		start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, q.cfg.Location)
+		out = append(out, quotaPeriod{name: QuotaDaily, id: "day:" + start.Format("2006-01-02"), limit: limits.Daily, resetAt: start.AddDate(0, 0, 1)})
+	}
+	if limits.Monthly > 0 {
+		start := time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, q.cfg.Location)
+		out = append(out, quotaPeriod{name: QuotaMonthly, id: "month:" + start.Format("2006-01"), limit: limits.Monthly, resetAt: start.AddDate(0, 1, 0)})
+	}
+	return out
+}
+
+// consume counts one request for key unless a period is exhausted, and
+// returns the usage after the decision. Counters are incremented, then
+// rolled back if another gateway sharing the store got there first.
+func (q *Quotas) consume(key string, limits QuotaLimits) (map[string]QuotaUsage, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.clk.Now()
+	q.prune(now)
+	periods := q.periods(limits, now)
+	used := make([]int64, len(periods))
+	allowed := true
+	for i, p := range periods {
+		n, err := q.store.Get(key, p.id)
+		if err != nil {
+			return nil, false, err
+		}
+		used[i] = n
+		if n >= p.limit {
+			allowed = false
+		}
+	}
+	if !allowed {
+		return quotaUsage(periods, used), false, nil
+	}
+	for i, p := range periods {
+		n, err := q.store.Add(key, p.id, 1)
+		if err != nil {
+			q.rollback(key, periods[:i])
+			return nil, false, err
+		}
+		used[i] = n
+		if n > p.limit {
+			allowed = false
+		}
+	}
+	if !allowed {
+		q.rollback(key, periods)
+		for i := range used {
+			used[i]--
+		}
+	}
+	return quotaUsage(periods, used), allowed, nil
+}
+
+// rollback takes back one request counted in periods. Callers hold q.mu.
+func (q *Quotas) rollback(key string, periods []quotaPeriod) {
+	for _, p := range periods {
+		if _, err := q.store.Add(key, p.id, -1); err != nil {
+			log.Printf("roll back quota of %s: %v", key, err)
+		}
+	}
+}
+
+// Status returns key's usage under limits without counting a request.
+func (q *Quotas) Status(key string, limits QuotaLimits) (QuotaStatus, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	periods := q.periods(limits, q.clk.Now())
+	used := make([]int64, len(periods))
+	for i, p := range periods {
+		n, err := q.store.Get(key, p.id)
+		if err != nil {
+			return QuotaStatus{}, err
+		}
+		used[i] = n
+	}
+	return QuotaStatus{Key: key, TimeZone: q.cfg.Location.String(), Periods: quotaUsage(periods, used)}, nil
+}
+
+func quotaUsage(periods []quotaPeriod, used []int64) map[string]QuotaUsage {
+	out := make(map[string]QuotaUsage, len(periods))
+	for i, p := range periods {
+		out[p.name] = QuotaUsage{
+			Limit:     p.limit,
+			Used:      used[i],
+			Remaining: max(0, p.limit-used[i]),
+			ResetAt:   p.resetAt,
+		}
+	}
+	return out
+}
+
+// Middleware counts requests that passed the rate limiter against the key's
+// quotas, sets X-Quota-<Period>-Limit/-Remaining/-Reset, and answers 429
+// quota_exceeded once a period is used up.
+func (q *Quotas) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := tenantKey(r.Context(), clientKeyFromRequest(r))
+		usage, allowed, err := q.consume(key, q.limitsFor(r))
+		if err != nil {
+			writeError(w, r, http.StatusServiceUnavailable, ErrCodeQuotaUnavailable, err.Error())
+			return
+		}
+
+		var retryAt time.Time
+		for name, u := range usage {
+			prefix := "X-Quota-" + strings.ToUpper(name[:1]) + name[1:] + "-"
+			w.Header().Set(prefix+"Limit", strconv.FormatInt(u.Limit, 10))
+			w.Header().Set(prefix+"Remaining", strconv.FormatInt(u.Remaining, 10))
+			w.Header().Set(prefix+"Reset", strconv.FormatInt(u.ResetAt.Unix(), 10))
+			if u.Remaining == 0 && !allowed && u.ResetAt.After(retryAt) {
+				retryAt = u.ResetAt
+			}
+		}
+		if !allowed {
+			markUsageDenied(r.Context())
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(retryAt, q.clk.Now())))
+			writeAPIError(w, r, APIError{
+				Status:     http.StatusTooManyRequests,
+				Code:       ErrCodeQuotaExceeded,
+				Detail:     "quota exhausted until " + retryAt.Format(time.RFC3339),
+				Extensions: map[string]any{"quota": usage},
+			})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// quotaHandler serves GET /api/quota: the caller's own usage, or any key's
+// with ?key= for admins (or anyone when auth is off). Another API key is read
+// under its own tier's limits. Keys are read in the caller's tenant namespace.
+func quotaHandler(q *Quotas, auth *Authenticator) func(http.ResponseWriter, *http.Request) {
+	var keys APIKeyStore
+	if auth != nil {
+		keys = auth.Store()
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := clientKeyFromRequest(r)
+		limits := q.limitsFor(r)
+		if other := strings.TrimSpace(r.URL.Query().Get("key")); other != "" && other != key {
+			if auth != nil && !hasAdminScope(r) {
+				writeError(w, r, http.StatusForbidden, ErrCodeInsufficientScope, "reading another key's quota requires the admin scope")
+				return
+			}
+			key = other
+			limits = q.limitsForKey(r, other, keys)
+		}
+		status, err := q.Status(tenantKey(r.Context(), key), limits)
+		if err != nil {
+			writeError(w, r, http.StatusServiceUnavailable, ErrCodeQuotaUnavailable, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+	}
+}
+
+// hasAdminScope reports whether the request's API key or token holds admin.
+func hasAdminScope(r *http.Request) bool {
+	if key, ok := APIKeyFromContext(r.Context()); ok && key.HasScope(ScopeAdmin) {
+		return true
+	}
+	if id, ok := JWTIdentityFromContext(r.Context()); ok && id.HasScope(ScopeAdmin) {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,219 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	chronostorage "github.com/SmitUplenchwar2687/Chrono/pkg/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+// QuotaStore persists quota counters by key and calendar period.
+type QuotaStore interface {
+	// Get returns the count of key in period; missing counters are zero.
+	Get(key, period string) (int64, error)
+	// Add adds delta to the counter of key in period and returns the new count.
+	Add(key, period string, delta int64) (int64, error)
+	// Prune drops every counter whose period keep rejects.
+	Prune(keep func(period string) bool) error
+	// Close releases the store's file or connection.
+	Close() error
+}
+
+type quotaCounter struct {
+	key, period string
+}
+
+// MemoryQuotaStore keeps quota counters in memory.
+type MemoryQuotaStore struct {
+	mu     sync.Mutex
+	counts map[quotaCounter]int64
+}
+
+// NewMemoryQuotaStore creates an empty in-memory store.
+func NewMemoryQuotaStore() *MemoryQuotaStore {
+	return &MemoryQuotaStore{counts: make(map[quotaCounter]int64)}
+}
+
+func (s *MemoryQuotaStore) Get(key, period string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counts[quotaCounter{key, period}], nil
+}
+
+func (s *MemoryQuotaStore) Add(key, period string, delta int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := quotaCounter{key, period}
+	s.counts[c] += delta
+	return s.counts[c], nil
+}
+
+func (s *MemoryQuotaStore) Prune(keep func(period string) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.counts {
+		if !keep(c.period) {
+			delete(s.counts, c)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryQuotaStore) Close() error { return nil }
+
+// quotaLogEntry is one line of a quota file.
+type quotaLogEntry struct {
+	Key    string `json:"key"`
+	Period string `json:"period"`
+	Count  int64  `json:"n"`
+}
+
+// FileQuotaStore keeps quota counters in memory and in an append log of
+// counter deltas.
+type FileQuotaStore struct {
+	mem *MemoryQuotaStore
+	log *appendLog[quotaLogEntry]
+}
+
+// NewFileQuotaStore opens (or creates) the quota file at path.
+func NewFileQuotaStore(path string) (*FileQuotaStore, error) {
+	s := &FileQuotaStore{mem: NewMemoryQuotaStore()}
+	log, err := openAppendLog("quota", path, func(e quotaLogEntry) {
+		s.mem.counts[quotaCounter{e.Key, e.Period}] += e.Count
+	}, s.entries)
+	if err != nil {
+		return nil, err
+	}
+	s.log = log
+	return s, nil
+}
+
+// entries returns one log entry per counter.
+func (s *FileQuotaStore) entries() []quotaLogEntry {
+	s.mem.mu.Lock()
+	defer s.mem.mu.Unlock()
+	out := make([]quotaLogEntry, 0, len(s.mem.counts))
+	for c, n := range s.mem.counts {
+		out = append(out, quotaLogEntry{Key: c.key, Period: c.period, Count: n})
+	}
+	return out
+}
+
+func (s *FileQuotaStore) Get(key, period string) (int64, error) {
+	return s.mem.Get(key, period)
+}
+
+func (s *FileQuotaStore) Add(key, period string, delta int64) (int64, error) {
+	var n int64
+	err := s.log.Append(func() {
+		n, _ = s.mem.Add(key, period, delta)
+	}, quotaLogEntry{Key: key, Period: period, Count: delta})
+	return n, err
+}
+
+func (s *FileQuotaStore) Prune(keep func(period string) bool) error {
+	return s.log.Compact(func() []quotaLogEntry {
+		_ = s.mem.Prune(keep)
+		return s.entries()
+	})
+}
+
+// Close closes the quota file.
+func (s *FileQuotaStore) Close() error {
+	return s.log.Close()
+}
+
+// quotaCounterTTL outlives the longest quota period, so a Redis counter is
+// only dropped once its period is over.
+const quotaCounterTTL = 32 * 24 * time.Hour
+
+// RedisQuotaStore keeps quota counters in Redis, shared by every gateway
+// using the server. Counters expire on their own, so Prune does nothing.
+type RedisQuotaStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisQuotaStore connects to the Redis server or cluster of cfg.
+func NewRedisQuotaStore(cfg *chronostorage.RedisConfig) (*RedisQuotaStore, error) {
+	client, err := newRedisClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisQuotaStore{client: client}, nil
+}
+
+func redisQuotaKey(key, period string) string {
+	return "chronogate:quota:" + period + ":" + key
+}
+
+func (s *RedisQuotaStore) Get(key, period string) (int64, error) {
+	n, err := s.client.Get(context.Background(), redisQuotaKey(key, period)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read quota counter: %w", err)
+	}
+	return n, nil
+}
+
+func (s *RedisQuotaStore) Add(key, period string, delta int64) (int64, error) {
+	ctx := context.Background()
+	rk := redisQuotaKey(key, period)
+	var incr *redis.IntCmd
+	if _, err := s.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		incr = p.IncrBy(ctx, rk, delta)
+		p.Expire(ctx, rk, quotaCounterTTL)
+		return nil
+	}); err != nil {
+		return 0, fmt.Errorf("update quota counter: %w", err)
+	}
+	return incr.Val(), nil
+}
+
+func (s *RedisQuotaStore) Prune(func(period string) bool) error { return nil }
+
+func (s *RedisQuotaStore) Close() error {
+	return s.client.Close()
+}
+
+// unavailableQuotaStore fails every call with the error that kept the
+// configured store from opening.
+type unavailableQuotaStore struct {
+	err error
+}
+
+func (s unavailableQuotaStore) Get(string, string) (int64, error)        { return 0, s.err }
+func (s unavailableQuotaStore) Add(string, string, int64) (int64, error) { return 0, s.err }
+func (s unavailableQuotaStore) Prune(func(string) bool) error            { return nil }
+func (s unavailableQuotaStore) Close() error                             { return nil }
+
+// openQuotaStore opens the store quota counters live in: Redis with the
+// redis backend, so replicas share quotas, otherwise QUOTA_FILE or memory.
+// It is nil when quotas are off.
+func openQuotaStore(cfg Config) (QuotaStore, error) {
+	if !cfg.Quota.enabled() {
+		return nil, nil
+	}
+	var (
+		store QuotaStore
+		err   error
+	)
+	switch path := strings.TrimSpace(cfg.Quota.File); {
+	case cfg.StorageBackend == chronostorage.BackendRedis:
+		store, err = NewRedisQuotaStore(cfg.Storage.Redis)
+	case path != "":
+		store, err = NewFileQuotaStore(path)
+	default:
+		store = NewMemoryQuotaStore()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
@@ -0,0 +1,237 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+	chronostorage "github.com/SmitUplenchwar2687/Chrono/pkg/storage"
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newQuotaTestHandler builds a handler at clk with the configured quota store
+// open, as NewStorageLimiterSet opens it.
+func newQuotaTestHandler(t *testing.T, cfg Config, clk chronoclock.Clock) http.Handler {
+	t.Helper()
+	mainLimiter, mainStorage, err := NewStorageBackedLimiter(cfg, clk)
+	if err != nil {
+		t.Fatalf("NewStorageBackedLimiter() error = %v", err)
+	}
+	t.Cleanup(func() { _ = mainStorage.Close() })
+	storageSet, cleanup := newMemoryOnlyStorageSet(t, cfg, clk)
+	t.Cleanup(cleanup)
+	storageSet.Quotas, storageSet.QuotaErr = openQuotaStore(cfg)
+	return NewHandler(cfg, mainLimiter, clk, chronorecorder.New(nil), storageSet)
+}
+
+func TestQuotasResetOnCalendarBoundariesAndPersist(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.Rate = 100
+	cfg.Quota = QuotaConfig{
+		QuotaLimits: QuotaLimits{Daily: 2, Monthly: 3},
+		Location:    newYork,
+		File:        filepath.Join(t.TempDir(), "quota.jsonl"),
+	}
+
+	// 23:30 on Feb 28 in New York is already March 1 in UTC.
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 2, 28, 23, 30, 0, 0, newYork))
+	newHandler := func() http.Handler {
+		return newQuotaTestHandler(t, cfg, vc)
+	}
+	handler := newHandler()
+	get := func(h http.Handler) int {
+		return executeRequest(h, http.MethodGet, "/api/profile", "quota-client", "", "", "").Code
+	}
+
+	for i := 0; i < 2; i++ {
+		resp := executeRequest(handler, http.MethodGet, "/api/profile", "quota-client", "", "", "")
+		assertStatus(t, resp, http.StatusOK)
+		if got := resp.Header().Get("X-Quota-Daily-Remaining"); got != strconv.Itoa(1-i) {
+			t.Fatalf("request %d X-Quota-Daily-Remaining = %q", i, got)
+		}
+	}
+	resp := executeRequest(handler, http.MethodGet, "/api/profile", "quota-client", "", "", "")
+	assertStatus(t, resp, http.StatusTooManyRequests)
+	midnight := time.Date(2026, 3, 1, 0, 0, 0, 0, newYork)
+	if got := resp.Header().Get("X-Quota-Daily-Reset"); got != strconv.FormatInt(midnight.Unix(), 10) {
+		t.Fatalf("X-Quota-Daily-Reset = %q, want local midnight", got)
+	}
+	if got := resp.Header().Get("Retry-After"); got != "1800" {
+		t.Fatalf("Retry-After = %q, want 1800", got)
+	}
+	var problem map[string]any
+	if err := json.Unmarshal(resp.Body.Bytes(), &problem); err != nil || problem["error"] != ErrCodeQuotaExceeded {
+		t.Fatalf("429 body = %s", resp.Body.String())
+	}
+
+	// March starts a new day and a new month.
+	vc.Set(midnight)
+	if got := get(handler); got != http.StatusOK {
+		t.Fatalf("first March request = %d, want 200", got)
+	}
+
+	// A restart keeps the counters.
+	handler = newHandler()
+	resp = executeRequest(handler, http.MethodGet, "/api/quota", "quota-client", "", "", "")
+	assertStatus(t, resp, http.StatusOK)
+	var status QuotaStatus
+	if err := json.Unmarshal(resp.Body.Bytes(), &status); err != nil {
+		t.Fatalf("decode quota: %v", err)
+	}
+	if status.TimeZone != "America/New_York" || status.Periods[QuotaDaily].Used != 1 || status.Periods[QuotaMonthly].Used != 1 {
+		t.Fatalf("quota status = %+v", status)
+	}
+	if !status.Periods[QuotaMonthly].ResetAt.Equal(time.Date(2026, 4, 1, 0, 0, 0, 0, newYork)) {
+		t.Fatalf("monthly reset = %s", status.Periods[QuotaMonthly].ResetAt)
+	}
+
+	// Rate-limited requests spend no quota.
+	cfg.Rate = 1
+	handler = newHandler()
+	if got := get(handler); got != http.StatusOK {
+		t.Fatalf("request after restart = %d, want 200", got)
+	}
+	if got := get(handler); got != http.StatusTooManyRequests {
+		t.Fatalf("rate-limited request = %d, want 429", got)
+	}
+	resp = executeRequest(handler, http.MethodGet, "/api/quota", "quota-client", "", "", "")
+	if err := json.Unmarshal(resp.Body.Bytes(), &status); err != nil {
+		t.Fatalf("decode quota: %v", err)
+	}
+	if status.Periods[QuotaDaily].Used != 2 || status.Periods[QuotaMonthly].Used != 2 {
+		t.Fatalf("quota status after rate limiting = %+v", status)
+	}
+}
+
+func TestFileQuotaStoreCompactsAndDropsTornLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quota.jsonl")
+	data := `{"key":"a","period":"day:2026-03-01","n":1}
+{"key":"a","period":"day:2026-03-01","n":1}
+{"key":"a","period":"day:2026-02-28","n":5}
+{"key":"b","per`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("write quota file: %v", err)
+	}
+	store, err := NewFileQuotaStore(path)
+	if err != nil {
+		t.Fatalf("NewFileQuotaStore() error = %v", err)
+	}
+	defer store.Close()
+	if n, _ := store.Get("a", "day:2026-03-01"); n != 2 {
+		t.Fatalf("Get() = %d, want 2", n)
+	}
+	if err := store.Prune(func(period string) bool { return period == "day:2026-03-01" }); err != nil {
+		t.Fatalf("Prune() error = %v", err)
+	}
+	if n, err := store.Add("a", "day:2026-03-01", 1); err != nil || n != 3 {
+		t.Fatalf("Add() = %d, %v; want 3", n, err)
+	}
+
+	reopened, err := NewFileQuotaStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+	if n, _ := reopened.Get("a", "day:2026-03-01"); n != 3 {
+		t.Fatalf("reopened Get() = %d, want 3", n)
+	}
+	if n, _ := reopened.Get("a", "day:2026-02-28"); n != 0 {
+		t.Fatalf("pruned period = %d, want 0", n)
+	}
+}
+
+func TestRedisQuotaStoreSharesCountersAcrossGateways(t *testing.T) {
+	mr := miniredis.RunT(t)
+	port, err := strconv.Atoi(mr.Port())
+	if err != nil {
+		t.Fatalf("parse miniredis port: %v", err)
+	}
+	cfg := mustTestConfig(limiter.AlgorithmSlidingWindow)
+	cfg.StorageBackend = chronostorage.BackendRedis
+	cfg.Storage.Redis = &chronostorage.RedisConfig{Host: mr.Host(), Port: port}
+	cfg.Quota = QuotaConfig{QuotaLimits: QuotaLimits{Daily: 3}}
+
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC))
+	gateway := func() *Quotas {
+		store, err := openQuotaStore(cfg)
+		if err != nil {
+			t.Fatalf("openQuotaStore() error = %v", err)
+		}
+		t.Cleanup(func() { _ = store.Close() })
+		return newQuotas(cfg.Quota, vc, store, nil)
+	}
+	a, b := gateway(), gateway()
+
+	for i, q := range []*Quotas{a, b, a} {
+		if _, allowed, err := q.consume("shared", cfg.Quota.QuotaLimits); err != nil || !allowed {
+			t.Fatalf("request %d = %v, %v; want allowed", i, allowed, err)
+		}
+	}
+	usage, allowed, err := b.consume("shared", cfg.Quota.QuotaLimits)
+	if err != nil || allowed || usage[QuotaDaily].Used != 3 {
+		t.Fatalf("fourth request = %+v, %v, %v; want denied at 3", usage, allowed, err)
+	}
+
+	cfg.Quota.File = "quota.jsonl"
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("Validate() accepted QUOTA_FILE with the redis backend")
+	}
+}
+
+func TestQuotaStoreFailuresAndOtherKeysTier(t *testing.T) {
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.Rate = 100
+	cfg.Quota = QuotaConfig{
+		QuotaLimits: QuotaLimits{Daily: 5},
+		Tiers:       map[string]QuotaLimits{"pro": {Daily: 50}},
+		File:        filepath.Join(t.TempDir(), "missing", "quota.jsonl"),
+	}
+
+	// A quota file that cannot be opened fails quota checks, not open.
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC))
+	set := NewStorageLimiterSet(cfg, vc)
+	_ = set.Close()
+	if set.QuotaErr == nil {
+		t.Fatal("NewStorageLimiterSet() opened a quota file in a missing directory")
+	}
+	resp := executeRequest(newQuotaTestHandler(t, cfg, vc), http.MethodGet, "/api/profile", "client", "", "", "")
+	assertStatus(t, resp, http.StatusServiceUnavailable)
+	var problem map[string]any
+	if err := json.Unmarshal(resp.Body.Bytes(), &problem); err != nil || problem["error"] != ErrCodeQuotaUnavailable {
+		t.Fatalf("503 body = %s", resp.Body.String())
+	}
+
+	// ?key= reads another key under its own tier.
+	cfg.Quota.File = ""
+	cfg.AdminAPIKey = "bootstrap-secret"
+	handler := newAuthTestHandler(t, cfg)
+	resp = adminRequest(handler, http.MethodPost, "/admin/keys", `{"owner":"carol","tier":"pro","scopes":["read"]}`)
+	assertStatus(t, resp, http.StatusCreated)
+	var created struct {
+		Key APIKey `json:"key"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode created key: %v", err)
+	}
+	resp = adminRequest(handler, http.MethodGet, "/api/quota?key="+created.Key.ID, "")
+	assertStatus(t, resp, http.StatusOK)
+	var status QuotaStatus
+	if err := json.Unmarshal(resp.Body.Bytes(), &status); err != nil {
+		t.Fatalf("decode quota: %v", err)
+	}
+	if status.Key != created.Key.ID || status.Periods[QuotaDaily].Limit != 50 {
+		t.Fatalf("quota of pro key = %+v, want daily limit 50", status)
+	}
+}
@@ -42,10 +42,14 @@ func TestRecorderCapturesTrafficAndExportsJSON(t *testing.T) {
 	exportResp := executeRequest(handler, http.MethodGet, "/api/recordings/export", "", "", "", "198.51.100.7:4123")
 	assertStatus(t, exportResp, http.StatusOK)
 
-	var records []chronorecorder.TrafficRecord
-	if err := json.Unmarshal(exportResp.Body.Bytes(), &records); err != nil {
+	var file RecordingFile
+	if err := json.Unmarshal(exportResp.Body.Bytes(), &file); err != nil {
 		t.Fatalf("unmarshal export response: %v", err)
 	}
+	if file.Version != RecordingFormatVersion || file.TimeZone != "UTC" || file.Config == nil || file.Config.Rate != 10 {
+		t.Fatalf("export envelope = %+v, want version %d, UTC and the config snapshot", file, RecordingFormatVersion)
+	}
+	records := file.Records
 
 	if len(records) == 0 {
 		t.Fatal("exported records should not be empty")
@@ -61,4 +65,14 @@ func TestRecorderCapturesTrafficAndExportsJSON(t *testing.T) {
 	if !found {
 		t.Fatal("expected exported records to contain GET /api/profile for key sdk-client")
 	}
+
+	legacyResp := executeRequest(handler, http.MethodGet, "/api/recordings/export?format=array", "", "", "", "198.51.100.7:4123")
+	assertStatus(t, legacyResp, http.StatusOK)
+	var legacy []chronorecorder.TrafficRecord
+	if err := json.Unmarshal(legacyResp.Body.Bytes(), &legacy); err != nil {
+		t.Fatalf("unmarshal legacy export response: %v", err)
+	}
+	if len(legacy) != len(records) {
+		t.Fatalf("legacy export has %d records, want %d", len(legacy), len(records))
+	}
 }
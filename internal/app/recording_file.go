@@ -0,0 +1,490 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+)
+
+// RecordingFormatVersion is the recording envelope version this build writes.
+// Version 0 denotes the legacy bare JSON array of records. Version 1 listed
+// the recorded endpoints in config.endpoints; version 2 replaced them with
+// config.routes.
+const RecordingFormatVersion = 2
+
+const (
+	// RecordingIssueError marks a record replay cannot use as-is.
+	RecordingIssueError = "error"
+	// RecordingIssueWarning marks a suspicious record replay can still process.
+	RecordingIssueWarning = "warning"
+)
+
+// RecordingFile is the versioned recording envelope.
+type RecordingFile struct {
+	Version    int                            `json:"version"`
+	Scope      string                         `json:"scope,omitempty"`
+	CapturedAt time.Time                      `json:"captured_at"`
+	Host       string                         `json:"capture_host,omitempty"`
+	TimeZone   string                         `json:"time_zone,omitempty"`
+	Config     *RecordingConfig               `json:"config,omitempty"`
+	Records    []chronorecorder.TrafficRecord `json:"records"`
+}
+
+// RecordingConfig snapshots the gateway configuration active during capture.
+// It holds the configured limits only: adaptive limits, promoted shadow
+// policies and canary policies change at runtime and are not recorded, so a
+// baseline replay of traffic captured under them uses the configured limits.
+type RecordingConfig struct {
+	Algorithm      string `json:"algorithm"`
+	Rate           int    `json:"rate"`
+	Window         string `json:"window"`
+	Burst          int    `json:"burst"`
+	StorageBackend string `json:"storage_backend,omitempty"`
+	// Quota is the default daily and monthly quota, if any.
+	Quota  *QuotaLimits  `json:"quota,omitempty"`
+	Routes []RoutePolicy `json:"routes,omitempty"`
+	// Endpoints is the version 1 list of recorded endpoints; it is only read.
+	Endpoints []string `json:"endpoints,omitempty"`
+}
+
+// RoutePolicy is the limit a recorded route enforced during capture; the
+// limit fields are empty for unlimited routes.
+type RoutePolicy struct {
+	Endpoint  string `json:"endpoint"`
+	Limited   bool   `json:"limited"`
+	Algorithm string `json:"algorithm,omitempty"`
+	Rate      int    `json:"rate,omitempty"`
+	Window    string `json:"window,omitempty"`
+	Burst     int    `json:"burst,omitempty"`
+}
+
+// NewRecordingFile wraps records captured under cfg in a current-version
+// envelope for the given recording scope.
+func NewRecordingFile(records []chronorecorder.TrafficRecord, cfg Config, scope string, capturedAt time.Time) RecordingFile {
+	host, _ := os.Hostname()
+	return RecordingFile{
+		Version:    RecordingFormatVersion,
+		Scope:      scope,
+		CapturedAt: capturedAt,
+		Host:       host,
+		TimeZone:   timeZoneName(capturedAt),
+		Config:     NewRecordingConfig(cfg),
+		Records:    records,
+	}
+}
+
+// NewRecordingConfig snapshots cfg, its default quota and the policy of every recorded route.
+func NewRecordingConfig(cfg Config) *RecordingConfig {
+	var routes []RoutePolicy
+	for _, route := range cfg.RouteTable() {
+		if !route.Recorded {
+			continue
+		}
+		policy := RoutePolicy{Endpoint: route.Endpoint, Limited: route.Limited}
+		if route.Limited {
+			policy.Algorithm = string(cfg.Algorithm)
+			if route.Algorithm != "" {
+				policy.Algorithm = string(route.Algorithm)
+			}
+			policy.Rate = cfg.Rate
+			policy.Window = cfg.Window.String()
+			policy.Burst = cfg.Burst
+		}
+		routes = append(routes, policy)
+	}
+	rc := &RecordingConfig{
+		Algorithm:      string(cfg.Algorithm),
+		Rate:           cfg.Rate,
+		Window:         cfg.Window.String(),
+		Burst:          cfg.Burst,
+		StorageBackend: cfg.StorageBackend,
+		Routes:         routes,
+	}
+	if quota := cfg.Quota.QuotaLimits; quota != (QuotaLimits{}) {
+		rc.Quota = &quota
+	}
+	return rc
+}
+
+// ReplayOptions returns the limiter parameters, storage backend and route
+// policies captured in c.
+func (c *RecordingConfig) ReplayOptions() (ReplayOptions, error) {
+	algo, err := ParseAlgorithm(c.Algorithm)
+	if err != nil {
+		return ReplayOptions{}, fmt.Errorf("recording config: %w", err)
+	}
+	window, err := time.ParseDuration(c.Window)
+	if err != nil {
+		return ReplayOptions{}, fmt.Errorf("recording config: invalid window %q: %w", c.Window, err)
+	}
+	return ReplayOptions{
+		Algorithm:      algo,
+		Rate:           c.Rate,
+		Window:         window,
+		Burst:          c.Burst,
+		StorageBackend: c.StorageBackend,
+		Routes:         append([]RoutePolicy(nil), c.Routes...),
+	}, nil
+}
+
+// Endpoints returns the endpoints records are expected to hit: the captured
+// routes (or a version 1 endpoint list) when the envelope has them, otherwise
+// the default recorded routes.
+func (f RecordingFile) Endpoints() []string {
+	if f.Config != nil && len(f.Config.Routes) > 0 {
+		out := make([]string, 0, len(f.Config.Routes))
+		for _, route := range f.Config.Routes {
+			out = append(out, route.Endpoint)
+		}
+		return out
+	}
+	if f.Config != nil && len(f.Config.Endpoints) > 0 {
+		return append([]string(nil), f.Config.Endpoints...)
+	}
+	return recordedEndpoints(DefaultRouteTable())
+}
+
+// WriteRecordingFile writes f as indented JSON.
+func WriteRecordingFile(w io.Writer, f RecordingFile) error {
+	if f.Records == nil {
+		f.Records = []chronorecorder.TrafficRecord{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(f)
+}
+
+// RecordingIssue is one validation finding. Line is the 1-based line the
+// record starts on (0 when unknown); Index is its 0-based position.
+type RecordingIssue struct {
+	Line     int    `json:"line,omitempty"`
+	Index    int    `json:"index"`
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+}
+
+func (i RecordingIssue) String() string {
+	where := fmt.Sprintf("record %d", i.Index)
+	if i.Line > 0 {
+		where = fmt.Sprintf("line %d", i.Line)
+	}
+	return fmt.Sprintf("%s: %s %s: %s", where, i.Severity, i.Code, i.Message)
+}
+
+// RecordingValidation is the result of validating a recording's records.
+type RecordingValidation struct {
+	Records  int              `json:"records"`
+	Errors   int              `json:"errors"`
+	Warnings int              `json:"warnings"`
+	Issues   []RecordingIssue `json:"issues"`
+}
+
+// Err returns the issues that should stop a replay: errors always, and
+// warnings too when strict is set.
+func (v RecordingValidation) Err(strict bool) error {
+	var fatal []RecordingIssue
+	for _, issue := range v.Issues {
+		if issue.Severity == RecordingIssueError || strict {
+			fatal = append(fatal, issue)
+		}
+	}
+	if len(fatal) == 0 {
+		return nil
+	}
+	return &RecordingValidationError{Issues: fatal}
+}
+
+// WarningIssues returns the warning-level issues.
+func (v RecordingValidation) WarningIssues() []RecordingIssue {
+	var out []RecordingIssue
+	for _, issue := range v.Issues {
+		if issue.Severity == RecordingIssueWarning {
+			out = append(out, issue)
+		}
+	}
+	return out
+}
+
+// RecordingValidationError reports the issues that failed validation.
+type RecordingValidationError struct {
+	Issues []RecordingIssue
+}
+
+func (e *RecordingValidationError) Error() string {
+	const shown = 5
+	parts := make([]string, 0, shown+1)
+	for i, issue := range e.Issues {
+		if i == shown {
+			parts = append(parts, fmt.Sprintf("and %d more", len(e.Issues)-shown))
+			break
+		}
+		parts = append(parts, issue.String())
+	}
+	return fmt.Sprintf("invalid recording (%d issues): %s", len(e.Issues), strings.Join(parts, "; "))
+}
+
+// LoadRecordingFile reads and validates a recording file. Only unreadable
+// files and malformed JSON are returned as errors; record problems are in the
+// validation result, with undecodable records left out of the file.
+func LoadRecordingFile(path string) (RecordingFile, RecordingValidation, error) {
+	if strings.TrimSpace(path) == "" {
+		return RecordingFile{}, RecordingValidation{}, fmt.Errorf("recording file is required")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return RecordingFile{}, RecordingValidation{}, fmt.Errorf("open recording file: %w", err)
+	}
+	return ReadRecordingFile(data)
+}
+
+// ReadRecordingFile decodes a versioned envelope or a legacy bare array and
+// validates every record.
+func ReadRecordingFile(data []byte) (RecordingFile, RecordingValidation, error) {
+	return ReadRecordingFileWithEndpoints(data, nil)
+}
+
+// ReadRecordingFileWithEndpoints is ReadRecordingFile checking endpoints
+// against known instead of the file's own list (nil keeps the default).
+func ReadRecordingFileWithEndpoints(data []byte, known []string) (RecordingFile, RecordingValidation, error) {
+	header, entries, err := scanRecordingDocument(data, "records")
+	if err != nil {
+		return RecordingFile{}, RecordingValidation{}, err
+	}
+
+	var file RecordingFile
+	if header != nil {
+		if err := decodeRecordingHeader(header, &file); err != nil {
+			return RecordingFile{}, RecordingValidation{}, err
+		}
+		if file.Version == 0 {
+			return RecordingFile{}, RecordingValidation{}, fmt.Errorf("recording envelope is missing version")
+		}
+	}
+
+	if known == nil {
+		known = file.Endpoints()
+	}
+	records, validation := validateRecords(entries, known)
+	file.Records = records
+	return file, validation, nil
+}
+
+func decodeRecordingHeader(header map[string]json.RawMessage, file *RecordingFile) error {
+	if err := decodeJSONHeader(header, file); err != nil {
+		return fmt.Errorf("decode recording envelope: %w", err)
+	}
+	if file.Version > RecordingFormatVersion {
+		return fmt.Errorf("unsupported recording format version %d (this build reads up to %d)", file.Version, RecordingFormatVersion)
+	}
+	if file.Version < 0 {
+		return fmt.Errorf("invalid recording format version %d", file.Version)
+	}
+	return nil
+}
+
+// decodeJSONHeader decodes the non-record members returned by
+// scanRecordingDocument into v.
+func decodeJSONHeader(header map[string]json.RawMessage, v any) error {
+	data, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+type scannedRecord struct {
+	line int
+	rec  chronorecorder.TrafficRecord
+	err  error
+}
+
+// scanRecordingDocument decodes either a bare array of records or an object
+// whose `field` member holds them, noting the line each record starts on.
+// For objects, the other members are returned undecoded in header; header is
+// nil for bare arrays.
+func scanRecordingDocument(data []byte, field string) (map[string]json.RawMessage, []scannedRecord, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	lines := newLineCounter(data)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, nil, jsonPositionError(lines, err)
+	}
+	switch tok {
+	case json.Delim('['):
+		entries, err := scanRecordArray(dec, lines)
+		return nil, entries, err
+	case json.Delim('{'):
+	default:
+		return nil, nil, fmt.Errorf("recording must be a JSON array or object")
+	}
+
+	header := make(map[string]json.RawMessage)
+	var entries []scannedRecord
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, jsonPositionError(lines, err)
+		}
+		key, _ := tok.(string)
+		if key != field {
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return nil, nil, jsonPositionError(lines, err)
+			}
+			header[key] = raw
+			continue
+		}
+
+		tok, err = dec.Token()
+		if err != nil {
+			return nil, nil, jsonPositionError(lines, err)
+		}
+		if tok != json.Delim('[') {
+			return nil, nil, fmt.Errorf("line %d: %q must be an array", lines.at(int(dec.InputOffset())), field)
+		}
+		if entries, err = scanRecordArray(dec, lines); err != nil {
+			return nil, nil, err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, jsonPositionError(lines, err)
+	}
+	return header, entries, nil
+}
+
+func scanRecordArray(dec *json.Decoder, lines *lineCounter) ([]scannedRecord, error) {
+	entries := []scannedRecord{}
+	for dec.More() {
+		// InputOffset sits just after the previous token, before any comma.
+		start := lines.skipSeparators(int(dec.InputOffset()))
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, jsonPositionError(lines, err)
+		}
+		entry := scannedRecord{line: lines.at(start)}
+		if err := json.Unmarshal(raw, &entry.rec); err != nil {
+			entry.err = err
+		}
+		entries = append(entries, entry)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, jsonPositionError(lines, err)
+	}
+	return entries, nil
+}
+
+func validateRecords(entries []scannedRecord, endpoints []string) ([]chronorecorder.TrafficRecord, RecordingValidation) {
+	known := make(map[string]bool, len(endpoints))
+	for _, endpoint := range endpoints {
+		known[endpoint] = true
+	}
+
+	validation := RecordingValidation{Records: len(entries), Issues: []RecordingIssue{}}
+	add := func(i int, entry scannedRecord, severity, code, msg string) {
+		validation.Issues = append(validation.Issues, RecordingIssue{
+			Line: entry.line, Index: i, Severity: severity, Code: code, Message: msg,
+		})
+		if severity == RecordingIssueError {
+			validation.Errors++
+		} else {
+			validation.Warnings++
+		}
+	}
+
+	records := make([]chronorecorder.TrafficRecord, 0, len(entries))
+	var prev time.Time
+	for i, entry := range entries {
+		if entry.err != nil {
+			add(i, entry, RecordingIssueError, "invalid_record", entry.err.Error())
+			continue
+		}
+		rec := entry.rec
+		records = append(records, rec)
+
+		if rec.Timestamp.IsZero() {
+			add(i, entry, RecordingIssueError, "zero_timestamp", "timestamp is missing or zero")
+		} else {
+			if !prev.IsZero() && rec.Timestamp.Before(prev) {
+				add(i, entry, RecordingIssueWarning, "out_of_order",
+					fmt.Sprintf("timestamp %s is before the previous record's %s", rec.Timestamp.Format(time.RFC3339Nano), prev.Format(time.RFC3339Nano)))
+			}
+			prev = rec.Timestamp
+		}
+		if strings.TrimSpace(rec.Key) == "" {
+			add(i, entry, RecordingIssueError, "missing_key", "key is empty")
+		}
+		if _, _, err := splitEndpoint(rec.Endpoint); err != nil {
+			add(i, entry, RecordingIssueError, "malformed_endpoint", err.Error())
+		} else if len(known) > 0 && !known[rec.Endpoint] {
+			add(i, entry, RecordingIssueWarning, "unknown_endpoint", fmt.Sprintf("endpoint %q is not a recorded route", rec.Endpoint))
+		}
+	}
+	return records, validation
+}
+
+// lineCounter maps byte offsets to 1-based line numbers. Offsets must be
+// queried in non-decreasing order.
+type lineCounter struct {
+	data []byte
+	off  int
+	line int
+}
+
+func newLineCounter(data []byte) *lineCounter {
+	return &lineCounter{data: data, line: 1}
+}
+
+func (c *lineCounter) at(offset int) int {
+	if offset > len(c.data) {
+		offset = len(c.data)
+	}
+	if offset < c.off {
+		return 1 + bytes.Count(c.data[:offset], []byte("\n"))
+	}
+	c.line += bytes.Count(c.data[c.off:offset], []byte("\n"))
+	c.off = offset
+	return c.line
+}
+
+func (c *lineCounter) skipSeparators(offset int) int {
+	for offset < len(c.data) {
+		switch c.data[offset] {
+		case ' ', '\t', '\r', '\n', ',':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+func jsonPositionError(lines *lineCounter, err error) error {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return fmt.Errorf("line %d: invalid JSON: %w", lines.at(int(syntaxErr.Offset)), err)
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("invalid JSON: unexpected end of input")
+	}
+	return fmt.Errorf("invalid JSON: %w", err)
+}
+
+func timeZoneName(t time.Time) string {
+	if name := t.Location().String(); name != "Local" {
+		return name
+	}
+	if tz := strings.TrimSpace(os.Getenv("TZ")); tz != "" {
+		return tz
+	}
+	name, _ := t.Zone()
+	return name
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+)
+
+func TestReadRecordingFileReportsIssuesWithLineNumbers(t *testing.T) {
+	data := []byte(`{
+  "version": 1,
+  "captured_at": "2026-02-08T12:00:00Z",
+  "time_zone": "UTC",
+  "records": [
+    {"timestamp": "2026-02-08T12:00:02Z", "key": "k1", "endpoint": "GET /api/profile"},
+    {"timestamp": "2026-02-08T12:00:01Z", "key": "k1", "endpoint": "GET /api/profile"},
+    {"key": "k2", "endpoint": "GET /api/profile"},
+    {"timestamp": "2026-02-08T12:00:03Z", "key": "k2",
+     "endpoint": "/api/profile"},
+    {"timestamp": "2026-02-08T12:00:04Z", "key": "k2", "endpoint": "GET /api/unknown"},
+    {"timestamp": 42, "key": "k3", "endpoint": "GET /api/profile"}
+  ]
+}`)
+
+	file, validation, err := ReadRecordingFile(data)
+	if err != nil {
+		t.Fatalf("ReadRecordingFile() error = %v", err)
+	}
+	if file.Version != 1 || file.TimeZone != "UTC" || len(file.Records) != 5 {
+		t.Fatalf("file = version %d, zone %q, %d records; want 1, UTC, 5 decodable records", file.Version, file.TimeZone, len(file.Records))
+	}
+
+	want := []struct {
+		line int
+		code string
+	}{
+		{7, "out_of_order"},
+		{8, "zero_timestamp"},
+		{9, "malformed_endpoint"},
+		{11, "unknown_endpoint"},
+		{12, "invalid_record"},
+	}
+	if len(validation.Issues) != len(want) {
+		t.Fatalf("issues = %v, want %d", validation.Issues, len(want))
+	}
+	for i, w := range want {
+		if got := validation.Issues[i]; got.Line != w.line || got.Code != w.code {
+			t.Fatalf("issue %d = line %d %s, want line %d %s", i, got.Line, got.Code, w.line, w.code)
+		}
+	}
+	if validation.Errors != 3 || validation.Warnings != 2 {
+		t.Fatalf("errors/warnings = %d/%d, want 3/2", validation.Errors, validation.Warnings)
+	}
+
+	var invalid *RecordingValidationError
+	if err := validation.Err(false); !errors.As(err, &invalid) || len(invalid.Issues) != 3 {
+		t.Fatalf("Err(false) = %v, want the 3 errors", err)
+	}
+	if err := validation.Err(true); !errors.As(err, &invalid) || len(invalid.Issues) != 5 {
+		t.Fatalf("Err(true) = %v, want all 5 issues", err)
+	}
+}
+
+func TestReadRecordingFileFormats(t *testing.T) {
+	legacy := []byte(`[{"timestamp":"2026-02-08T12:00:00Z","key":"k1","endpoint":"GET /api/profile"}]`)
+	file, validation, err := ReadRecordingFile(legacy)
+	if err != nil || file.Version != 0 || len(file.Records) != 1 || len(validation.Issues) != 0 {
+		t.Fatalf("legacy array = %+v, %+v, %v", file, validation, err)
+	}
+
+	for name, data := range map[string]string{
+		"future version":  `{"version": 99, "records": []}`,
+		"missing version": `{"records": []}`,
+		"records object":  `{"version": 1, "records": {}}`,
+		"syntax error":    "[\n{\"key\": \"k1\",}\n]",
+	} {
+		if _, _, err := ReadRecordingFile([]byte(data)); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+	if _, _, err := ReadRecordingFile([]byte("[\n{\"key\": \"k1\",}\n]")); !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("syntax error = %v, want line 2", err)
+	}
+
+	var buf strings.Builder
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	at := time.Date(2026, 2, 8, 12, 0, 0, 0, time.UTC)
+	if err := WriteRecordingFile(&buf, NewRecordingFile(
+		[]chronorecorder.TrafficRecord{{Timestamp: at, Key: "k1", Endpoint: "POST /api/orders"}}, cfg, RecordingScopeLimited, at)); err != nil {
+		t.Fatalf("WriteRecordingFile() error = %v", err)
+	}
+	roundTrip, validation, err := ReadRecordingFile([]byte(buf.String()))
+	if err != nil || len(validation.Issues) != 0 {
+		t.Fatalf("round trip: %+v, %v", validation, err)
+	}
+	if roundTrip.Version != RecordingFormatVersion || roundTrip.Config.Algorithm != string(cfg.Algorithm) || len(roundTrip.Records) != 1 {
+		t.Fatalf("round trip = %+v", roundTrip)
+	}
+}
+
+func TestReplayEndpointRejectsInvalidTraffic(t *testing.T) {
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 2, 8, 14, 0, 0, 0, time.UTC))
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+
+	mainLimiter, mainStorage, err := NewStorageBackedLimiter(cfg, vc)
+	if err != nil {
+		t.Fatalf("NewStorageBackedLimiter() error = %v", err)
+	}
+	defer mainStorage.Close()
+
+	storageSet, cleanup := newMemoryOnlyStorageSet(t, cfg, vc)
+	defer cleanup()
+
+	handler := NewHandler(cfg, mainLimiter, vc, chronorecorder.New(nil), storageSet)
+
+	invalid := `{"traffic":[
+		{"timestamp":"2026-02-08T14:00:00Z","key":"k1","endpoint":"GET /api/profile"},
+		{"key":"k1","endpoint":"GET /api/profile"}
+	]}`
+	resp := executeRequest(handler, http.MethodPost, "/api/replay", "", "", invalid, "198.51.100.44:8080")
+	assertStatus(t, resp, http.StatusBadRequest)
+	var rejected struct {
+		Issues []RecordingIssue `json:"issues"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &rejected); err != nil {
+		t.Fatalf("decode error response: %v", err)
+	}
+	if len(rejected.Issues) != 1 || rejected.Issues[0].Line != 3 || rejected.Issues[0].Code != "zero_timestamp" {
+		t.Fatalf("issues = %+v, want zero_timestamp on line 3", rejected.Issues)
+	}
+
+	warned := `{"traffic":[{"timestamp":"2026-02-08T14:00:00Z","key":"k1","endpoint":"GET /health"}]}`
+	resp = executeRequest(handler, http.MethodPost, "/api/replay", "", "", warned, "198.51.100.44:8080")
+	assertStatus(t, resp, http.StatusOK)
+	var accepted struct {
+		Warnings []RecordingIssue `json:"warnings"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &accepted); err != nil {
+		t.Fatalf("decode replay response: %v", err)
+	}
+	if len(accepted.Warnings) != 1 || accepted.Warnings[0].Code != "unknown_endpoint" {
+		t.Fatalf("warnings = %+v, want unknown_endpoint", accepted.Warnings)
+	}
+
+	strict := `{"strict":true,"traffic":[{"timestamp":"2026-02-08T14:00:00Z","key":"k1","endpoint":"GET /health"}]}`
+	resp = executeRequest(handler, http.MethodPost, "/api/replay", "", "", strict, "198.51.100.44:8080")
+	assertStatus(t, resp, http.StatusBadRequest)
+}
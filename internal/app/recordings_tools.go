@@ -0,0 +1,241 @@
+package app
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+	chronoreplay "github.com/SmitUplenchwar2687/Chrono/pkg/replay"
+)
+
+const (
+	// AnonymizeHash replaces keys with a salted HMAC-SHA256 digest.
+	AnonymizeHash = "hash"
+	// AnonymizePseudonym replaces keys with sequential names in first-seen order.
+	AnonymizePseudonym = "pseudonym"
+)
+
+// MergeRecordings combines several recordings into one timestamp-ordered list.
+// Records with equal timestamps keep their input order.
+func MergeRecordings(sets ...[]chronorecorder.TrafficRecord) []chronorecorder.TrafficRecord {
+	var out []chronorecorder.TrafficRecord
+	for _, set := range sets {
+		out = append(out, set...)
+	}
+	sortRecords(out)
+	return out
+}
+
+// RecordSlice selects records by time range, key and endpoint.
+type RecordSlice struct {
+	From      time.Time // inclusive; zero = no lower bound
+	To        time.Time // exclusive; zero = no upper bound
+	Keys      []string
+	Endpoints []string // matched like replay's endpoint filter
+}
+
+// SliceRecords returns the records matching s, in their original order.
+func SliceRecords(records []chronorecorder.TrafficRecord, s RecordSlice) []chronorecorder.TrafficRecord {
+	filter := &chronoreplay.Filter{Keys: s.Keys, Endpoints: s.Endpoints}
+	out := make([]chronorecorder.TrafficRecord, 0, len(records))
+	for _, rec := range records {
+		if !s.From.IsZero() && rec.Timestamp.Before(s.From) {
+			continue
+		}
+		if !s.To.IsZero() && !rec.Timestamp.Before(s.To) {
+			continue
+		}
+		if filter.Match(rec) {
+			out = append(out, rec)
+		}
+	}
+	return out
+}
+
+// AnonymizeOptions controls key anonymization.
+type AnonymizeOptions struct {
+	Mode string
+	// Salt keys the hash mode; the same salt maps a key to the same digest
+	// across files so anonymized captures can still be merged.
+	Salt string
+	// Prefix is prepended to pseudonyms (default "key-").
+	Prefix string
+	// KeepMetadata retains record metadata, which may itself hold identifiers.
+	KeepMetadata bool
+}
+
+// AnonymizeRecords replaces every key and returns the original-to-new mapping.
+func AnonymizeRecords(records []chronorecorder.TrafficRecord, opts AnonymizeOptions) ([]chronorecorder.TrafficRecord, map[string]string, error) {
+	prefix := opts.Prefix
+	if prefix == "" {
+		prefix = "key-"
+	}
+
+	mapping := make(map[string]string)
+	var rename func(string) string
+	switch opts.Mode {
+	case AnonymizeHash:
+		if opts.Salt == "" {
+			return nil, nil, fmt.Errorf("hash anonymization requires a salt")
+		}
+		rename = func(key string) string {
+			mac := hmac.New(sha256.New, []byte(opts.Salt))
+			mac.Write([]byte(key))
+			return "anon-" + hex.EncodeToString(mac.Sum(nil))[:16]
+		}
+	case AnonymizePseudonym:
+		rename = func(string) string {
+			return prefix + strconv.Itoa(len(mapping)+1)
+		}
+	default:
+		return nil, nil, fmt.Errorf("invalid anonymize mode %q: use %s|%s", opts.Mode, AnonymizeHash, AnonymizePseudonym)
+	}
+
+	out := make([]chronorecorder.TrafficRecord, len(records))
+	for i, rec := range records {
+		alias, ok := mapping[rec.Key]
+		if !ok {
+			alias = rename(rec.Key)
+			mapping[rec.Key] = alias
+		}
+		rec.Key = alias
+		if !opts.KeepMetadata {
+			rec.Metadata = nil
+		}
+		out[i] = rec
+	}
+	return out, mapping, nil
+}
+
+// TimeTransform rewrites timestamps relative to the earliest record: offsets
+// from it are multiplied by Scale, then the whole recording moves to Start (if
+// set) and by Shift.
+type TimeTransform struct {
+	Shift time.Duration
+	Scale float64 // 0 or 1 keeps spacing; 0.5 compresses traffic into half the time
+	Start time.Time
+}
+
+// TransformTimestamps applies t to records and returns them in timestamp order.
+func TransformTimestamps(records []chronorecorder.TrafficRecord, t TimeTransform) ([]chronorecorder.TrafficRecord, error) {
+	if t.Scale < 0 {
+		return nil, fmt.Errorf("scale must be >= 0, got %g", t.Scale)
+	}
+	scale := t.Scale
+	if scale == 0 {
+		scale = 1
+	}
+
+	out := append([]chronorecorder.TrafficRecord(nil), records...)
+	sortRecords(out)
+	if len(out) == 0 {
+		return out, nil
+	}
+
+	origin := out[0].Timestamp
+	base := origin
+	if !t.Start.IsZero() {
+		base = t.Start
+	}
+	for i := range out {
+		offset := time.Duration(float64(out[i].Timestamp.Sub(origin)) * scale)
+		out[i].Timestamp = base.Add(offset + t.Shift)
+	}
+	return out, nil
+}
+
+// RecordingStats summarizes a recording.
+type RecordingStats struct {
+	Records     int            `json:"records"`
+	First       time.Time      `json:"first"`
+	Last        time.Time      `json:"last"`
+	Duration    string         `json:"duration"`
+	PerKey      map[string]int `json:"per_key"`
+	PerEndpoint map[string]int `json:"per_endpoint"`
+	// PeakRPS is the most records in any one-second window, starting at PeakAt.
+	PeakRPS int       `json:"peak_rps"`
+	PeakAt  time.Time `json:"peak_at"`
+}
+
+// ComputeRecordingStats counts records per key and endpoint and finds peak RPS.
+func ComputeRecordingStats(records []chronorecorder.TrafficRecord) RecordingStats {
+	stats := RecordingStats{
+		Records:     len(records),
+		PerKey:      make(map[string]int),
+		PerEndpoint: make(map[string]int),
+	}
+	if len(records) == 0 {
+		stats.Duration = time.Duration(0).String()
+		return stats
+	}
+
+	sorted := append([]chronorecorder.TrafficRecord(nil), records...)
+	sortRecords(sorted)
+	stats.First = sorted[0].Timestamp
+	stats.Last = sorted[len(sorted)-1].Timestamp
+	stats.Duration = stats.Last.Sub(stats.First).String()
+
+	lo := 0
+	for hi, rec := range sorted {
+		stats.PerKey[rec.Key]++
+		stats.PerEndpoint[rec.Endpoint]++
+
+		for rec.Timestamp.Sub(sorted[lo].Timestamp) >= time.Second {
+			lo++
+		}
+		if n := hi - lo + 1; n > stats.PeakRPS {
+			stats.PeakRPS = n
+			stats.PeakAt = sorted[lo].Timestamp
+		}
+	}
+	return stats
+}
+
+// PrintRecordingStats writes stats as a human-readable report.
+func PrintRecordingStats(out io.Writer, stats RecordingStats) {
+	fmt.Fprintf(out, "Records: %d\n", stats.Records)
+	if stats.Records == 0 {
+		return
+	}
+	fmt.Fprintf(out, "Span: %s .. %s (%s)\n", stats.First.Format(time.RFC3339Nano), stats.Last.Format(time.RFC3339Nano), stats.Duration)
+	fmt.Fprintf(out, "Peak RPS: %d (at %s)\n", stats.PeakRPS, stats.PeakAt.Format(time.RFC3339Nano))
+
+	for _, section := range []struct {
+		title  string
+		counts map[string]int
+	}{
+		{title: "Per-key:", counts: stats.PerKey},
+		{title: "Per-endpoint:", counts: stats.PerEndpoint},
+	} {
+		fmt.Fprintln(out, section.title)
+		names := make([]string, 0, len(section.counts))
+		for name := range section.counts {
+			names = append(names, name)
+		}
+		// Busiest first; ties alphabetical.
+		sort.Slice(names, func(i, j int) bool {
+			if section.counts[names[i]] != section.counts[names[j]] {
+				return section.counts[names[i]] > section.counts[names[j]]
+			}
+			return names[i] < names[j]
+		})
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		for _, name := range names {
+			fmt.Fprintf(tw, "  %s\t%d\n", name, section.counts[name])
+		}
+		_ = tw.Flush()
+	}
+}
+
+func sortRecords(records []chronorecorder.TrafficRecord) {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+}
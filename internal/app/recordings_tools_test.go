@@ -0,0 +1,127 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+)
+
+func TestMergeAndSliceRecordings(t *testing.T) {
+	base := time.Date(2026, 2, 8, 12, 0, 0, 0, time.UTC)
+	a := []chronorecorder.TrafficRecord{
+		{Timestamp: base, Key: "client-a", Endpoint: "GET /api/profile"},
+		{Timestamp: base.Add(2 * time.Second), Key: "client-a", Endpoint: "POST /api/orders"},
+	}
+	b := []chronorecorder.TrafficRecord{
+		{Timestamp: base.Add(time.Second), Key: "::1", Endpoint: "GET /health"},
+		{Timestamp: base.Add(3 * time.Second), Key: "::1", Endpoint: "GET /api/profile"},
+	}
+
+	merged := MergeRecordings(a, b)
+	if len(merged) != 4 {
+		t.Fatalf("len(merged) = %d, want 4", len(merged))
+	}
+	for i := 1; i < len(merged); i++ {
+		if merged[i].Timestamp.Before(merged[i-1].Timestamp) {
+			t.Fatalf("merged records out of order at %d", i)
+		}
+	}
+
+	sliced := SliceRecords(merged, RecordSlice{From: base.Add(time.Second), To: base.Add(3 * time.Second)})
+	if len(sliced) != 2 || sliced[0].Key != "::1" || sliced[1].Endpoint != "POST /api/orders" {
+		t.Fatalf("time slice = %+v", sliced)
+	}
+
+	sliced = SliceRecords(merged, RecordSlice{Keys: []string{"client-a"}, Endpoints: []string{"/api/profile"}})
+	if len(sliced) != 1 || !sliced[0].Timestamp.Equal(base) {
+		t.Fatalf("key/endpoint slice = %+v", sliced)
+	}
+}
+
+func TestAnonymizeRecords(t *testing.T) {
+	records := []chronorecorder.TrafficRecord{
+		{Key: "client-a", Endpoint: "GET /api/profile", Metadata: map[string]string{"ip": "10.0.0.1"}},
+		{Key: "::1", Endpoint: "GET /health"},
+		{Key: "client-a", Endpoint: "POST /api/orders"},
+	}
+
+	out, mapping, err := AnonymizeRecords(records, AnonymizeOptions{Mode: AnonymizePseudonym})
+	if err != nil {
+		t.Fatalf("AnonymizeRecords() error = %v", err)
+	}
+	if out[0].Key != "key-1" || out[1].Key != "key-2" || out[2].Key != "key-1" {
+		t.Fatalf("pseudonyms = %q %q %q", out[0].Key, out[1].Key, out[2].Key)
+	}
+	if mapping["client-a"] != "key-1" || mapping["::1"] != "key-2" {
+		t.Fatalf("mapping = %v", mapping)
+	}
+	if out[0].Metadata != nil {
+		t.Fatalf("metadata should be dropped by default, got %v", out[0].Metadata)
+	}
+	if records[0].Key != "client-a" {
+		t.Fatal("input records should not be modified")
+	}
+
+	first, _, err := AnonymizeRecords(records, AnonymizeOptions{Mode: AnonymizeHash, Salt: "s1", KeepMetadata: true})
+	if err != nil {
+		t.Fatalf("AnonymizeRecords(hash) error = %v", err)
+	}
+	again, _, _ := AnonymizeRecords(records[:1], AnonymizeOptions{Mode: AnonymizeHash, Salt: "s1"})
+	other, _, _ := AnonymizeRecords(records[:1], AnonymizeOptions{Mode: AnonymizeHash, Salt: "s2"})
+	if !strings.HasPrefix(first[0].Key, "anon-") || strings.Contains(first[0].Key, "client-a") {
+		t.Fatalf("hashed key = %q", first[0].Key)
+	}
+	if first[0].Key != again[0].Key {
+		t.Fatal("same salt should hash a key identically across files")
+	}
+	if first[0].Key == other[0].Key {
+		t.Fatal("different salts should produce different hashes")
+	}
+	if first[0].Metadata["ip"] != "10.0.0.1" {
+		t.Fatal("KeepMetadata should retain metadata")
+	}
+
+	if _, _, err := AnonymizeRecords(records, AnonymizeOptions{Mode: AnonymizeHash}); err == nil {
+		t.Fatal("hash mode without a salt should fail")
+	}
+	if _, _, err := AnonymizeRecords(records, AnonymizeOptions{Mode: "rot13"}); err == nil {
+		t.Fatal("unknown mode should fail")
+	}
+}
+
+func TestTransformTimestampsAndStats(t *testing.T) {
+	base := time.Date(2026, 2, 8, 12, 0, 0, 0, time.UTC)
+	records := []chronorecorder.TrafficRecord{
+		{Timestamp: base.Add(4 * time.Second), Key: "b", Endpoint: "GET /x"},
+		{Timestamp: base, Key: "a", Endpoint: "GET /x"},
+		{Timestamp: base.Add(2 * time.Second), Key: "a", Endpoint: "POST /y"},
+	}
+
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	out, err := TransformTimestamps(records, TimeTransform{Start: start, Scale: 0.25, Shift: time.Minute})
+	if err != nil {
+		t.Fatalf("TransformTimestamps() error = %v", err)
+	}
+	want := []time.Time{start.Add(time.Minute), start.Add(time.Minute + 500*time.Millisecond), start.Add(time.Minute + time.Second)}
+	for i, rec := range out {
+		if !rec.Timestamp.Equal(want[i]) {
+			t.Fatalf("out[%d].Timestamp = %s, want %s", i, rec.Timestamp, want[i])
+		}
+	}
+	if _, err := TransformTimestamps(records, TimeTransform{Scale: -1}); err == nil {
+		t.Fatal("negative scale should fail")
+	}
+
+	stats := ComputeRecordingStats(out)
+	if stats.Records != 3 || stats.PerKey["a"] != 2 || stats.PerEndpoint["GET /x"] != 2 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if stats.PeakRPS != 2 || !stats.PeakAt.Equal(want[0]) {
+		t.Fatalf("peak = %d at %s, want 2 at %s", stats.PeakRPS, stats.PeakAt, want[0])
+	}
+	if stats.Duration != "1s" {
+		t.Fatalf("duration = %q, want 1s", stats.Duration)
+	}
+}
@@ -0,0 +1,257 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+	chronoreplay "github.com/SmitUplenchwar2687/Chrono/pkg/replay"
+)
+
+// ReplayComparison holds the outcome of replaying one traffic set through
+// several limiter settings. Summaries, rows and divergences are keyed by
+// label: the algorithm name for algorithm comparisons, "baseline" and
+// "current" for baseline comparisons.
+type ReplayComparison struct {
+	Labels      []string                         `json:"labels"`
+	Algorithms  []limiter.Algorithm              `json:"algorithms,omitempty"`
+	Settings    map[string]ReplayRunOptions      `json:"settings"`
+	Summaries   map[string]*chronoreplay.Summary `json:"summaries"`
+	Rows        []ComparisonRow                  `json:"rows"`
+	Divergences []ComparisonDivergence           `json:"divergences"`
+}
+
+// ComparisonRow is the per-key, per-endpoint allowed/denied breakdown by label.
+type ComparisonRow struct {
+	Key       string                    `json:"key"`
+	Endpoint  string                    `json:"endpoint"`
+	Results   map[string]DecisionCounts `json:"results"`
+	Different bool                      `json:"different"`
+}
+
+// DecisionCounts are allowed/denied totals.
+type DecisionCounts struct {
+	Allowed int `json:"allowed"`
+	Denied  int `json:"denied"`
+}
+
+// ComparisonDivergence marks the first record of a key/endpoint pair where
+// the compared settings disagreed.
+type ComparisonDivergence struct {
+	Index     int             `json:"index"`
+	Timestamp time.Time       `json:"timestamp"`
+	Key       string          `json:"key"`
+	Endpoint  string          `json:"endpoint"`
+	Allowed   map[string]bool `json:"allowed"`
+}
+
+type comparisonKey struct {
+	key      string
+	endpoint string
+}
+
+type replayVariant struct {
+	label string
+	opts  ReplayOptions
+}
+
+// RunReplayComparison replays the same records through every algorithm in
+// opts.Compare, each on its own virtual clock, and prints a comparison table.
+func RunReplayComparison(ctx context.Context, records []chronorecorder.TrafficRecord, opts ReplayOptions, out io.Writer) (*ReplayComparison, error) {
+	if len(opts.Compare) < 2 {
+		return nil, fmt.Errorf("compare requires at least two algorithms, got %d", len(opts.Compare))
+	}
+
+	seen := make(map[limiter.Algorithm]bool, len(opts.Compare))
+	variants := make([]replayVariant, 0, len(opts.Compare))
+	for _, algo := range opts.Compare {
+		if seen[algo] {
+			return nil, fmt.Errorf("duplicate compare algorithm %q", algo)
+		}
+		seen[algo] = true
+
+		runOpts := opts
+		runOpts.Algorithm = algo
+		variants = append(variants, replayVariant{label: string(algo), opts: runOpts})
+	}
+
+	comparison, err := compareReplays(ctx, records, variants)
+	if err != nil {
+		return nil, err
+	}
+	comparison.Algorithms = append([]limiter.Algorithm(nil), opts.Compare...)
+
+	if out == nil {
+		out = io.Discard
+	}
+	printReplayComparison(out, comparison)
+
+	return comparison, nil
+}
+
+// RunReplayBaseline replays records under the limits, storage backend and
+// route policies captured with the recording (baseline) and under opts
+// (current), with the same filters and speed, and prints where their
+// decisions differ. A storage backend set in opts replaces the captured one.
+func RunReplayBaseline(ctx context.Context, records []chronorecorder.TrafficRecord, baseline, opts ReplayOptions, out io.Writer) (*ReplayComparison, error) {
+	baseline.File = opts.File
+	baseline.Speed = opts.Speed
+	baseline.Keys = opts.Keys
+	baseline.Endpoints = opts.Endpoints
+	if opts.StorageBackend != "" {
+		baseline.StorageBackend = opts.StorageBackend
+	}
+	baseline.Storage = opts.Storage
+
+	comparison, err := compareReplays(ctx, records, []replayVariant{
+		{label: "baseline", opts: baseline},
+		{label: "current", opts: opts},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if out == nil {
+		out = io.Discard
+	}
+	printReplayComparison(out, comparison)
+
+	return comparison, nil
+}
+
+func compareReplays(ctx context.Context, records []chronorecorder.TrafficRecord, variants []replayVariant) (*ReplayComparison, error) {
+	results := make([][]chronoreplay.Result, len(variants))
+	comparison := &ReplayComparison{
+		Labels:    make([]string, 0, len(variants)),
+		Settings:  make(map[string]ReplayRunOptions, len(variants)),
+		Summaries: make(map[string]*chronoreplay.Summary, len(variants)),
+	}
+
+	for i, v := range variants {
+		summary, err := runReplay(ctx, records, v.opts, func(res chronoreplay.Result) {
+			results[i] = append(results[i], res)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("replay %s: %w", v.label, err)
+		}
+		comparison.Labels = append(comparison.Labels, v.label)
+		comparison.Settings[v.label] = replayRunOptions(v.opts)
+		comparison.Summaries[v.label] = summary
+	}
+	// Rows pair results by index, so every variant must replay every record.
+	for i, v := range variants[1:] {
+		if len(results[i+1]) != len(results[0]) {
+			return nil, fmt.Errorf("replay %s produced %d results, %s produced %d", v.label, len(results[i+1]), variants[0].label, len(results[0]))
+		}
+	}
+
+	rows := make(map[comparisonKey]*ComparisonRow)
+	diverged := make(map[comparisonKey]bool)
+	for idx := range results[0] {
+		rec := results[0][idx].Record
+		ck := comparisonKey{key: rec.Key, endpoint: rec.Endpoint}
+
+		row, ok := rows[ck]
+		if !ok {
+			row = &ComparisonRow{Key: rec.Key, Endpoint: rec.Endpoint, Results: make(map[string]DecisionCounts, len(variants))}
+			rows[ck] = row
+		}
+
+		allowed := make(map[string]bool, len(variants))
+		differs := false
+		for i, v := range variants {
+			decision := results[i][idx].Decision
+			counts := row.Results[v.label]
+			if decision.Allowed {
+				counts.Allowed++
+			} else {
+				counts.Denied++
+			}
+			row.Results[v.label] = counts
+			allowed[v.label] = decision.Allowed
+			if decision.Allowed != results[0][idx].Decision.Allowed {
+				differs = true
+			}
+		}
+
+		if differs && !diverged[ck] {
+			diverged[ck] = true
+			comparison.Divergences = append(comparison.Divergences, ComparisonDivergence{
+				Index:     idx,
+				Timestamp: rec.Timestamp,
+				Key:       rec.Key,
+				Endpoint:  rec.Endpoint,
+				Allowed:   allowed,
+			})
+		}
+	}
+
+	comparison.Rows = make([]ComparisonRow, 0, len(rows))
+	for ck, row := range rows {
+		row.Different = diverged[ck]
+		comparison.Rows = append(comparison.Rows, *row)
+	}
+	sort.Slice(comparison.Rows, func(i, j int) bool {
+		if comparison.Rows[i].Key != comparison.Rows[j].Key {
+			return comparison.Rows[i].Key < comparison.Rows[j].Key
+		}
+		return comparison.Rows[i].Endpoint < comparison.Rows[j].Endpoint
+	})
+
+	return comparison, nil
+}
+
+func printReplayComparison(out io.Writer, c *ReplayComparison) {
+	names := c.Labels
+
+	fmt.Fprintf(out, "Comparison: %s\n", strings.Join(names, " vs "))
+	for _, name := range names {
+		s := c.Summaries[name]
+		settings := ""
+		if len(c.Algorithms) == 0 {
+			o := c.Settings[name]
+			settings = fmt.Sprintf(" (%s rate=%d window=%s burst=%d)", o.Algorithm, o.Rate, o.Window, o.Burst)
+		}
+		fmt.Fprintf(out, "  %s%s: replayed=%d allowed=%d denied=%d\n", name, settings, s.Replayed, s.Allowed, s.Denied)
+	}
+
+	fmt.Fprintln(out, "Per-key/endpoint (allowed/denied):")
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "  KEY\tENDPOINT\t%s\tDIFF\n", strings.Join(names, "\t"))
+	for _, row := range c.Rows {
+		cells := make([]string, 0, len(names))
+		for _, name := range names {
+			counts := row.Results[name]
+			cells = append(cells, fmt.Sprintf("%d/%d", counts.Allowed, counts.Denied))
+		}
+		diff := ""
+		if row.Different {
+			diff = "*"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", row.Key, row.Endpoint, strings.Join(cells, "\t"), diff)
+	}
+	_ = tw.Flush()
+
+	if len(c.Divergences) == 0 {
+		fmt.Fprintln(out, "Divergences: none")
+		return
+	}
+	fmt.Fprintln(out, "Divergences (earliest per key/endpoint):")
+	for _, d := range c.Divergences {
+		parts := make([]string, 0, len(names))
+		for _, name := range names {
+			verdict := "denied"
+			if d.Allowed[name] {
+				verdict = "allowed"
+			}
+			parts = append(parts, name+"="+verdict)
+		}
+		fmt.Fprintf(out, "  #%d %s %s %s: %s\n", d.Index, d.Timestamp.UTC().Format(time.RFC3339Nano), d.Key, d.Endpoint, strings.Join(parts, " "))
+	}
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"testing"
 	"time"
@@ -62,6 +63,7 @@ func TestRecordStopReplayAndReplayLast(t *testing.T) {
 		Recording bool                           `json:"recording"`
 		Count     int                            `json:"count"`
 		Records   []chronorecorder.TrafficRecord `json:"records"`
+		Config    *RecordingConfig               `json:"config"`
 	}
 	if err := json.Unmarshal(stopResp.Body.Bytes(), &stopBody); err != nil {
 		t.Fatalf("decode /api/record/stop response: %v", err)
@@ -72,6 +74,16 @@ func TestRecordStopReplayAndReplayLast(t *testing.T) {
 	if stopBody.Count == 0 || len(stopBody.Records) == 0 {
 		t.Fatal("expected non-empty recording export from /api/record/stop")
 	}
+	if stopBody.Config == nil || stopBody.Config.Rate != 5 || stopBody.Config.Algorithm != string(limiter.AlgorithmFixedWindow) {
+		t.Fatalf("stop config = %+v, want the active fixed_window rate=5 config", stopBody.Config)
+	}
+	routes := make(map[string]RoutePolicy)
+	for _, route := range stopBody.Config.Routes {
+		routes[route.Endpoint] = route
+	}
+	if routes["POST /api/orders"].Algorithm != string(limiter.AlgorithmFixedWindow) || routes["GET /api/token-bucket"].Algorithm != string(limiter.AlgorithmTokenBucket) {
+		t.Fatalf("route policies = %+v", stopBody.Config.Routes)
+	}
 
 	replayPayload := map[string]any{
 		"traffic":   stopBody.Records,
@@ -116,3 +128,333 @@ func TestRecordStopReplayAndReplayLast(t *testing.T) {
 		t.Fatalf("last replay replayed=%d, want %d", lastBody.Summary.Replayed, replayResult.Summary.Replayed)
 	}
 }
+
+func TestReplayHistoryListGetDelete(t *testing.T) {
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 2, 8, 14, 0, 0, 0, time.UTC))
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.ReplayHistoryDir = t.TempDir()
+
+	mainLimiter, mainStorage, err := NewStorageBackedLimiter(cfg, vc)
+	if err != nil {
+		t.Fatalf("NewStorageBackedLimiter() error = %v", err)
+	}
+	defer mainStorage.Close()
+
+	storageSet, cleanup := newMemoryOnlyStorageSet(t, cfg, vc)
+	defer cleanup()
+
+	handler := NewHandler(cfg, mainLimiter, vc, chronorecorder.New(nil), storageSet)
+
+	start := vc.Now()
+	replayBody := fmt.Sprintf(`{"traffic":[{"timestamp":%q,"key":"k1","endpoint":"GET /api/profile"}],"rate":1,"window":"1m","keys":["k1"]}`, start.Format(time.RFC3339))
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		resp := executeRequest(handler, http.MethodPost, "/api/replay", "", "", replayBody, "198.51.100.42:8080")
+		assertStatus(t, resp, http.StatusOK)
+		var body struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode /api/replay response: %v", err)
+		}
+		if body.ID == "" {
+			t.Fatal("expected /api/replay to return a run id")
+		}
+		ids = append(ids, body.ID)
+		vc.Advance(time.Second)
+	}
+
+	listResp := executeRequest(handler, http.MethodGet, "/api/replays", "", "", "", "198.51.100.42:8080")
+	assertStatus(t, listResp, http.StatusOK)
+	var list struct {
+		Count   int         `json:"count"`
+		Replays []ReplayRun `json:"replays"`
+	}
+	if err := json.Unmarshal(listResp.Body.Bytes(), &list); err != nil {
+		t.Fatalf("decode /api/replays response: %v", err)
+	}
+	if list.Count != 2 || list.Replays[0].ID != ids[1] {
+		t.Fatalf("list = %+v, want 2 runs newest first", list)
+	}
+	if list.Replays[0].Fingerprint == "" || list.Replays[0].Fingerprint != list.Replays[1].Fingerprint {
+		t.Fatal("expected identical inputs to share a fingerprint")
+	}
+	if got := list.Replays[0].Options; got.Rate != 1 || got.Window != "1m0s" || len(got.Keys) != 1 {
+		t.Fatalf("stored options = %+v", got)
+	}
+
+	getResp := executeRequest(handler, http.MethodGet, "/api/replays/"+ids[0], "", "", "", "198.51.100.42:8080")
+	assertStatus(t, getResp, http.StatusOK)
+
+	deleteResp := executeRequest(handler, http.MethodDelete, "/api/replays/"+ids[1], "", "", "", "198.51.100.42:8080")
+	assertStatus(t, deleteResp, http.StatusOK)
+
+	missingResp := executeRequest(handler, http.MethodGet, "/api/replays/"+ids[1], "", "", "", "198.51.100.42:8080")
+	assertStatus(t, missingResp, http.StatusNotFound)
+
+	lastResp := executeRequest(handler, http.MethodGet, "/api/replay/last", "", "", "", "198.51.100.42:8080")
+	assertStatus(t, lastResp, http.StatusOK)
+	var last struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(lastResp.Body.Bytes(), &last); err != nil {
+		t.Fatalf("decode /api/replay/last response: %v", err)
+	}
+	if last.ID != ids[0] {
+		t.Fatalf("last replay id = %q, want %q after deleting the newest run", last.ID, ids[0])
+	}
+
+	// A fresh store over the same directory sees the persisted run.
+	runs, err := NewFileReplayStore(cfg.ReplayHistoryDir, 0).List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(runs) != 1 || runs[0].ID != ids[0] {
+		t.Fatalf("persisted runs = %+v, want only %s", runs, ids[0])
+	}
+}
+
+func TestMemoryReplayStoreEvictsOldest(t *testing.T) {
+	store := NewMemoryReplayStore(2)
+	for _, id := range []string{"rpl_a", "rpl_b", "rpl_c"} {
+		if err := store.Save(ReplayRun{ID: id}); err != nil {
+			t.Fatalf("Save(%s) error = %v", id, err)
+		}
+	}
+
+	runs, err := store.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(runs) != 2 || runs[0].ID != "rpl_c" || runs[1].ID != "rpl_b" {
+		t.Fatalf("runs = %+v, want rpl_c, rpl_b", runs)
+	}
+}
+
+func TestFileReplayStoreEvictsOldest(t *testing.T) {
+	store := NewFileReplayStore(t.TempDir(), 2)
+	start := time.Date(2026, 2, 8, 14, 0, 0, 0, time.UTC)
+	for i, id := range []string{"rpl_a", "rpl_b", "rpl_c"} {
+		if err := store.Save(ReplayRun{ID: id, CreatedAt: start.Add(time.Duration(i) * time.Minute)}); err != nil {
+			t.Fatalf("Save(%s) error = %v", id, err)
+		}
+	}
+
+	runs, err := store.List()
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(runs) != 2 || runs[0].ID != "rpl_c" || runs[1].ID != "rpl_b" {
+		t.Fatalf("runs = %+v, want rpl_c, rpl_b", runs)
+	}
+}
+
+func TestReplayComparisonSavesOneRunPerAlgorithm(t *testing.T) {
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 2, 8, 14, 0, 0, 0, time.UTC))
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+
+	mainLimiter, mainStorage, err := NewStorageBackedLimiter(cfg, vc)
+	if err != nil {
+		t.Fatalf("NewStorageBackedLimiter() error = %v", err)
+	}
+	defer mainStorage.Close()
+
+	storageSet, cleanup := newMemoryOnlyStorageSet(t, cfg, vc)
+	defer cleanup()
+
+	handler := NewHandler(cfg, mainLimiter, vc, chronorecorder.New(nil), storageSet)
+	body := `{"traffic":[{"timestamp":"2026-02-08T14:00:00Z","key":"k","endpoint":"GET /api/profile"}],"compare":["fixed_window","token_bucket"]}`
+	resp := executeRequest(handler, http.MethodPost, "/api/replay", "", "", body, "198.51.100.43:8080")
+	assertStatus(t, resp, http.StatusOK)
+	var result struct {
+		IDs map[string]string `json:"ids"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decode comparison: %v", err)
+	}
+	if len(result.IDs) != 2 {
+		t.Fatalf("comparison ids = %v, want one per algorithm", result.IDs)
+	}
+
+	resp = executeRequest(handler, http.MethodGet, "/api/replays/"+result.IDs["token_bucket"], "", "", "", "198.51.100.43:8080")
+	assertStatus(t, resp, http.StatusOK)
+	var stored struct {
+		Replay ReplayRun `json:"replay"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &stored); err != nil {
+		t.Fatalf("decode replay: %v", err)
+	}
+	if stored.Replay.Options.Algorithm != string(limiter.AlgorithmTokenBucket) || stored.Replay.Summary == nil {
+		t.Fatalf("stored run = %+v", stored.Replay)
+	}
+}
+
+func TestReplayJobRunsToCompletion(t *testing.T) {
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 2, 8, 14, 0, 0, 0, time.UTC))
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+
+	mainLimiter, mainStorage, err := NewStorageBackedLimiter(cfg, vc)
+	if err != nil {
+		t.Fatalf("NewStorageBackedLimiter() error = %v", err)
+	}
+	defer mainStorage.Close()
+
+	storageSet, cleanup := newMemoryOnlyStorageSet(t, cfg, vc)
+	defer cleanup()
+
+	handler := NewHandler(cfg, mainLimiter, vc, chronorecorder.New(nil), storageSet)
+
+	start := vc.Now()
+	body := fmt.Sprintf(`{"traffic":[
+		{"timestamp":%q,"key":"k1","endpoint":"GET /api/profile"},
+		{"timestamp":%q,"key":"k1","endpoint":"GET /api/profile"},
+		{"timestamp":%q,"key":"k2","endpoint":"GET /api/profile"}
+	],"rate":1,"window":"1m","keys":["k1"]}`,
+		start.Format(time.RFC3339), start.Add(time.Second).Format(time.RFC3339), start.Add(2*time.Second).Format(time.RFC3339))
+
+	submitResp := executeRequest(handler, http.MethodPost, "/api/replay/jobs", "", "", body, "198.51.100.43:8080")
+	assertStatus(t, submitResp, http.StatusAccepted)
+	var submitted struct {
+		Job ReplayJob `json:"job"`
+	}
+	if err := json.Unmarshal(submitResp.Body.Bytes(), &submitted); err != nil {
+		t.Fatalf("decode submit response: %v", err)
+	}
+	if submitted.Job.ID == "" || submitted.Job.Progress.Total != 2 {
+		t.Fatalf("submitted job = %+v, want id and total=2 after key filter", submitted.Job)
+	}
+
+	job := waitForReplayJob(t, handler, submitted.Job.ID)
+	if job.Status != ReplayJobSucceeded {
+		t.Fatalf("job status = %q (error %q), want succeeded", job.Status, job.Error)
+	}
+	if job.Progress.Processed != 2 || job.Progress.Allowed != 1 || job.Progress.Denied != 1 {
+		t.Fatalf("job progress = %+v, want 2 processed, 1 allowed, 1 denied", job.Progress)
+	}
+	if job.ReplayID == "" {
+		t.Fatal("expected finished job to reference its stored replay run")
+	}
+
+	historyResp := executeRequest(handler, http.MethodGet, "/api/replays/"+job.ReplayID, "", "", "", "198.51.100.43:8080")
+	assertStatus(t, historyResp, http.StatusOK)
+}
+
+func TestReplayJobCancel(t *testing.T) {
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 2, 8, 14, 0, 0, 0, time.UTC))
+	jobs := NewReplayJobManager(1, NewReplayState(nil), vc)
+
+	start := vc.Now()
+	records := []chronorecorder.TrafficRecord{
+		{Timestamp: start, Key: "k1", Endpoint: "GET /api/profile"},
+		{Timestamp: start.Add(time.Hour), Key: "k1", Endpoint: "GET /api/profile"},
+	}
+	opts := ReplayOptions{Algorithm: limiter.AlgorithmFixedWindow, Rate: 1, Window: time.Minute, Burst: 1, Speed: 1}
+
+	// With one worker, the second job stays queued behind the first (which
+	// sleeps for an hour of real time at speed 1).
+	running, err := jobs.Submit(records, opts)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	queued, err := jobs.Submit(records, opts)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	for _, id := range []string{queued.ID, running.ID} {
+		if _, ok := jobs.Cancel(id); !ok {
+			t.Fatalf("Cancel(%s) found no job", id)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for _, id := range []string{running.ID, queued.ID} {
+		for {
+			job, _ := jobs.Get(id)
+			if job.Status == ReplayJobCanceled {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("job %s status = %q, want canceled", id, job.Status)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+}
+
+func TestReplayAsyncSubmitsJob(t *testing.T) {
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 2, 8, 14, 0, 0, 0, time.UTC))
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+
+	mainLimiter, mainStorage, err := NewStorageBackedLimiter(cfg, vc)
+	if err != nil {
+		t.Fatalf("NewStorageBackedLimiter() error = %v", err)
+	}
+	defer mainStorage.Close()
+
+	storageSet, cleanup := newMemoryOnlyStorageSet(t, cfg, vc)
+	defer cleanup()
+
+	handler := NewHandler(cfg, mainLimiter, vc, chronorecorder.New(nil), storageSet)
+	body := `[{"timestamp":"2026-02-08T14:00:00Z","key":"k","endpoint":"GET /api/profile"}]`
+	assertStatus(t, executeRequest(handler, http.MethodPost, "/api/replay?async=maybe", "", "", body, "198.51.100.43:8080"), http.StatusBadRequest)
+
+	resp := executeRequest(handler, http.MethodPost, "/api/replay?async=true", "", "", body, "198.51.100.43:8080")
+	assertStatus(t, resp, http.StatusAccepted)
+	var submitted struct {
+		Job ReplayJob `json:"job"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &submitted); err != nil {
+		t.Fatalf("decode submit response: %v", err)
+	}
+	if got := resp.Header().Get("Location"); got != "/api/replay/jobs/"+submitted.Job.ID {
+		t.Fatalf("Location = %q", got)
+	}
+	job := waitForReplayJob(t, handler, submitted.Job.ID)
+	if job.Status != ReplayJobSucceeded || job.ReplayID == "" {
+		t.Fatalf("job = %+v, want succeeded with a stored run", job)
+	}
+}
+
+func waitForReplayJob(t *testing.T, handler http.Handler, id string) ReplayJob {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp := executeRequest(handler, http.MethodGet, "/api/replay/jobs/"+id, "", "", "", "198.51.100.43:8080")
+		assertStatus(t, resp, http.StatusOK)
+		var body struct {
+			Job ReplayJob `json:"job"`
+		}
+		if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+			t.Fatalf("decode job response: %v", err)
+		}
+		if body.Job.FinishedAt != nil {
+			return body.Job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s did not finish, last status %q", id, body.Job.Status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestReplayTimelineFormatNeedsBucket(t *testing.T) {
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 2, 8, 14, 0, 0, 0, time.UTC))
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+
+	mainLimiter, mainStorage, err := NewStorageBackedLimiter(cfg, vc)
+	if err != nil {
+		t.Fatalf("NewStorageBackedLimiter() error = %v", err)
+	}
+	defer mainStorage.Close()
+
+	storageSet, cleanup := newMemoryOnlyStorageSet(t, cfg, vc)
+	defer cleanup()
+
+	handler := NewHandler(cfg, mainLimiter, vc, chronorecorder.New(nil), storageSet)
+	body := `{"traffic":[{"timestamp":"2026-02-08T14:00:00Z","key":"k","endpoint":"GET /api/profile"}],"timeline_format":"csv"}`
+	resp := executeRequest(handler, http.MethodPost, "/api/replay", "", "", body, "198.51.100.42:8080")
+	assertStatus(t, resp, http.StatusBadRequest)
+}
@@ -2,36 +2,50 @@ package app
 
 import (
 	"bytes"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"strings"
 	"time"
 
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
 	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
 	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+	chronoreplay "github.com/SmitUplenchwar2687/Chrono/pkg/replay"
 )
 
+// replayRequest is the object form of a replay request body; its "traffic"
+// array is decoded separately by scanRecordingDocument.
 type replayRequest struct {
-	Traffic   []chronorecorder.TrafficRecord `json:"traffic"`
-	Algorithm string                         `json:"algorithm"`
-	Rate      int                            `json:"rate"`
-	Window    string                         `json:"window"`
-	Burst     int                            `json:"burst"`
-	Speed     float64                        `json:"speed"`
-	Keys      []string                       `json:"keys"`
-	Endpoints []string                       `json:"endpoints"`
+	Algorithm string   `json:"algorithm"`
+	Rate      int      `json:"rate"`
+	Window    string   `json:"window"`
+	Burst     int      `json:"burst"`
+	Speed     float64  `json:"speed"`
+	Keys      []string `json:"keys"`
+	Endpoints []string `json:"endpoints"`
+	// Strict rejects traffic with validation warnings, not just errors.
+	Strict bool `json:"strict"`
+
+	Compare        []string `json:"compare"`
+	StorageBackend string   `json:"storage_backend"`
+	TimelineBucket string   `json:"timeline_bucket"`
+	TimelineFormat string   `json:"timeline_format"`
 }
 
-func parseReplayRequest(r *http.Request, defaults Config) (ReplayOptions, []chronorecorder.TrafficRecord, error) {
+// parseReplayRequest decodes a bare traffic array or a replayRequest object.
+// Traffic is validated like a recording file: record errors (and warnings in
+// strict mode) reject the request, other warnings are returned.
+func parseReplayRequest(r *http.Request, defaults Config) (ReplayOptions, []chronorecorder.TrafficRecord, []RecordingIssue, error) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		return ReplayOptions{}, nil, fmt.Errorf("read request body: %w", err)
+		return ReplayOptions{}, nil, nil, fmt.Errorf("read request body: %w", err)
 	}
 	trimmed := bytes.TrimSpace(body)
 	if len(trimmed) == 0 {
-		return ReplayOptions{}, nil, fmt.Errorf("request body is required")
+		return ReplayOptions{}, nil, nil, fmt.Errorf("request body is required")
 	}
 
 	opts := ReplayOptions{
@@ -40,28 +54,34 @@ func parseReplayRequest(r *http.Request, defaults Config) (ReplayOptions, []chro
 		Window:    defaults.Window,
 		Burst:     defaults.Burst,
 		Speed:     0,
+		Storage:   defaults.Storage,
 	}
 
-	if trimmed[0] == '[' {
-		var records []chronorecorder.TrafficRecord
-		if err := json.Unmarshal(trimmed, &records); err != nil {
-			return ReplayOptions{}, nil, fmt.Errorf("decode replay traffic array: %w", err)
-		}
-		if len(records) == 0 {
-			return ReplayOptions{}, nil, fmt.Errorf("traffic records cannot be empty")
+	header, entries, err := scanRecordingDocument(body, "traffic")
+	if err != nil {
+		return ReplayOptions{}, nil, nil, fmt.Errorf("decode replay request: %w", err)
+	}
+	if len(entries) == 0 {
+		return ReplayOptions{}, nil, nil, fmt.Errorf("traffic records cannot be empty")
+	}
+	records, validation := validateRecords(entries, recordedEndpoints(defaults.RouteTable()))
+
+	if header == nil {
+		if err := validation.Err(false); err != nil {
+			return ReplayOptions{}, nil, nil, err
 		}
-		return opts, records, nil
+		return opts, records, validation.WarningIssues(), nil
 	}
 
 	var req replayRequest
-	if err := json.Unmarshal(trimmed, &req); err != nil {
-		return ReplayOptions{}, nil, fmt.Errorf("decode replay request: %w", err)
+	if err := decodeJSONHeader(header, &req); err != nil {
+		return ReplayOptions{}, nil, nil, fmt.Errorf("decode replay request: %w", err)
 	}
 
 	if strings.TrimSpace(req.Algorithm) != "" {
 		algo, err := ParseAlgorithm(strings.TrimSpace(req.Algorithm))
 		if err != nil {
-			return ReplayOptions{}, nil, err
+			return ReplayOptions{}, nil, nil, err
 		}
 		opts.Algorithm = algo
 	}
@@ -74,7 +94,7 @@ func parseReplayRequest(r *http.Request, defaults Config) (ReplayOptions, []chro
 	if strings.TrimSpace(req.Window) != "" {
 		d, err := time.ParseDuration(strings.TrimSpace(req.Window))
 		if err != nil {
-			return ReplayOptions{}, nil, fmt.Errorf("invalid window %q: %w", req.Window, err)
+			return ReplayOptions{}, nil, nil, fmt.Errorf("invalid window %q: %w", req.Window, err)
 		}
 		opts.Window = d
 	}
@@ -83,9 +103,36 @@ func parseReplayRequest(r *http.Request, defaults Config) (ReplayOptions, []chro
 	}
 	opts.Keys = append([]string(nil), req.Keys...)
 	opts.Endpoints = append([]string(nil), req.Endpoints...)
+	opts.StorageBackend = strings.TrimSpace(req.StorageBackend)
+	if strings.TrimSpace(req.TimelineBucket) != "" {
+		d, err := time.ParseDuration(strings.TrimSpace(req.TimelineBucket))
+		if err != nil {
+			return ReplayOptions{}, nil, nil, fmt.Errorf("invalid timeline_bucket %q: %w", req.TimelineBucket, err)
+		}
+		if d <= 0 {
+			return ReplayOptions{}, nil, nil, fmt.Errorf("timeline_bucket must be > 0, got %s", d)
+		}
+		opts.TimelineBucket = d
+	}
+	switch format := strings.ToLower(strings.TrimSpace(req.TimelineFormat)); format {
+	case "", TimelineFormatJSON, TimelineFormatCSV:
+		opts.TimelineFormat = format
+	default:
+		return ReplayOptions{}, nil, nil, fmt.Errorf("invalid timeline_format %q", req.TimelineFormat)
+	}
+	if opts.TimelineFormat != "" && opts.TimelineBucket == 0 {
+		return ReplayOptions{}, nil, nil, fmt.Errorf("timeline_format requires timeline_bucket")
+	}
+	if len(req.Compare) > 0 {
+		compare, err := ParseAlgorithmList(req.Compare)
+		if err != nil {
+			return ReplayOptions{}, nil, nil, err
+		}
+		opts.Compare = compare
+	}
 
-	if len(req.Traffic) == 0 {
-		return ReplayOptions{}, nil, fmt.Errorf("traffic records cannot be empty")
+	if err := validation.Err(req.Strict); err != nil {
+		return ReplayOptions{}, nil, nil, err
 	}
 
 	checkCfg := defaults
@@ -93,9 +140,185 @@ func parseReplayRequest(r *http.Request, defaults Config) (ReplayOptions, []chro
 	checkCfg.Rate = opts.Rate
 	checkCfg.Window = opts.Window
 	checkCfg.Burst = opts.Burst
+	if opts.StorageBackend != "" {
+		checkCfg.StorageBackend = opts.StorageBackend
+	}
 	if err := checkCfg.Validate(); err != nil {
-		return ReplayOptions{}, nil, err
+		return ReplayOptions{}, nil, nil, err
+	}
+	for _, algo := range opts.Compare {
+		checkCfg.Algorithm = algo
+		if err := checkCfg.Validate(); err != nil {
+			return ReplayOptions{}, nil, nil, err
+		}
+	}
+
+	return opts, records, validation.WarningIssues(), nil
+}
+
+// writeReplayRequestError reports a parseReplayRequest failure, listing the
+// validation issues when the traffic itself was rejected.
+func writeReplayRequestError(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := APIError{Status: http.StatusBadRequest, Code: ErrCodeInvalidReplayRequest, Detail: err.Error()}
+	var invalid *RecordingValidationError
+	if errors.As(err, &invalid) {
+		apiErr.Extensions = map[string]any{"issues": invalid.Issues}
+	}
+	writeAPIError(w, r, apiErr)
+}
+
+func withReplayWarnings(body map[string]any, warnings []RecordingIssue) map[string]any {
+	if len(warnings) > 0 {
+		body["warnings"] = warnings
+	}
+	return body
+}
+
+func newReplayStore(cfg Config) ReplayStore {
+	if dir := strings.TrimSpace(cfg.ReplayHistoryDir); dir != "" {
+		return NewFileReplayStore(dir, 0)
+	}
+	return NewMemoryReplayStore(0)
+}
+
+// saveReplayRun stores a completed run and returns its ID. A storage failure is
+// logged rather than failing the request, since the replay itself succeeded.
+func saveReplayRun(state *ReplayState, records []chronorecorder.TrafficRecord, opts ReplayOptions, summary *chronoreplay.Summary, clk chronoclock.Clock) string {
+	run, err := NewReplayRun(records, opts, summary, clk.Now())
+	if err != nil {
+		log.Printf("build replay run: %v", err)
+		return ""
+	}
+	if err := state.Save(run); err != nil {
+		log.Printf("save replay run %s: %v", run.ID, err)
+		return ""
+	}
+	return run.ID
+}
+
+// saveComparisonRuns stores one run per compared setting and returns their
+// IDs by label. Failures are logged like saveReplayRun's.
+func saveComparisonRuns(state *ReplayState, records []chronorecorder.TrafficRecord, comparison *ReplayComparison, clk chronoclock.Clock) map[string]string {
+	runs, err := NewComparisonRuns(records, comparison, clk.Now())
+	if err != nil {
+		log.Printf("build replay runs: %v", err)
+		return nil
+	}
+	ids := make(map[string]string, len(runs))
+	for i, run := range runs {
+		if err := state.Save(run); err != nil {
+			log.Printf("save replay run %s: %v", run.ID, err)
+			continue
+		}
+		ids[comparison.Labels[i]] = run.ID
 	}
+	return ids
+}
+
+func replayHistoryListHandler(state *ReplayState) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		runs, err := state.List()
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, ErrCodeReplayHistoryFailed, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"count":   len(runs),
+			"replays": runs,
+		})
+	}
+}
+
+func replayHistoryGetHandler(state *ReplayState) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		run, ok, err := state.Get(id)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, ErrCodeReplayHistoryFailed, err.Error())
+			return
+		}
+		if !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("replay %q not found", id))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"replay": run})
+	}
+}
+
+func replayHistoryDeleteHandler(state *ReplayState) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		deleted, err := state.Delete(id)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, ErrCodeReplayHistoryFailed, err.Error())
+			return
+		}
+		if !deleted {
+			writeError(w, r, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("replay %q not found", id))
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"id": id, "deleted": true})
+	}
+}
 
-	return opts, req.Traffic, nil
+func replayJobListHandler(jobs *ReplayJobManager) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		list := jobs.List()
+		writeJSON(w, http.StatusOK, map[string]any{
+			"count": len(list),
+			"jobs":  list,
+		})
+	}
+}
+
+func replayJobSubmitHandler(jobs *ReplayJobManager, cfg Config) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		opts, records, warnings, err := parseReplayRequest(r, cfg)
+		if err != nil {
+			writeReplayRequestError(w, r, err)
+			return
+		}
+		submitReplayJob(w, r, jobs, records, opts, warnings)
+	}
+}
+
+// submitReplayJob queues a parsed replay and answers 202 with the job. It
+// serves POST /api/replay/jobs and POST /api/replay?async=true.
+func submitReplayJob(w http.ResponseWriter, r *http.Request, jobs *ReplayJobManager, records []chronorecorder.TrafficRecord, opts ReplayOptions, warnings []RecordingIssue) {
+	job, err := jobs.Submit(records, opts)
+	if errors.Is(err, ErrReplayQueueFull) {
+		w.Header().Set("Retry-After", "1")
+		writeError(w, r, http.StatusServiceUnavailable, ErrCodeReplayQueueFull, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidReplayRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Location", "/api/replay/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, withReplayWarnings(map[string]any{"job": job}, warnings))
+}
+
+func replayJobGetHandler(jobs *ReplayJobManager) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeReplayJob(w, r, jobs.Get)
+	}
+}
+
+func replayJobCancelHandler(jobs *ReplayJobManager) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeReplayJob(w, r, jobs.Cancel)
+	}
+}
+
+// writeReplayJob looks up the {id} job with lookup (Get or Cancel) and writes it.
+func writeReplayJob(w http.ResponseWriter, r *http.Request, lookup func(string) (ReplayJob, bool)) {
+	id := r.PathValue("id")
+	job, ok := lookup(id)
+	if !ok {
+		writeError(w, r, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("replay job %q not found", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"job": job})
 }
@@ -0,0 +1,367 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+	chronoreplay "github.com/SmitUplenchwar2687/Chrono/pkg/replay"
+)
+
+const defaultReplayHistoryLimit = 100
+
+var replayIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ReplayRun is one stored replay execution.
+type ReplayRun struct {
+	ID          string                `json:"id"`
+	CreatedAt   time.Time             `json:"created_at"`
+	Options     ReplayRunOptions      `json:"options"`
+	Fingerprint string                `json:"fingerprint"`
+	RecordCount int                   `json:"record_count"`
+	Summary     *chronoreplay.Summary `json:"summary"`
+}
+
+// ReplayRunOptions is the serializable form of the ReplayOptions a run used.
+type ReplayRunOptions struct {
+	Algorithm      string   `json:"algorithm"`
+	Rate           int      `json:"rate"`
+	Window         string   `json:"window"`
+	Burst          int      `json:"burst"`
+	Speed          float64  `json:"speed"`
+	Keys           []string `json:"keys,omitempty"`
+	Endpoints      []string `json:"endpoints,omitempty"`
+	StorageBackend string   `json:"storage_backend,omitempty"`
+	File           string   `json:"file,omitempty"`
+}
+
+// ReplayStore persists replay runs.
+type ReplayStore interface {
+	Save(run ReplayRun) error
+	Get(id string) (ReplayRun, bool, error)
+	// List returns runs newest first.
+	List() ([]ReplayRun, error)
+	Delete(id string) (bool, error)
+}
+
+// NewReplayRun builds a run record with a fresh ID and the fingerprint of records.
+func NewReplayRun(records []chronorecorder.TrafficRecord, opts ReplayOptions, summary *chronoreplay.Summary, createdAt time.Time) (ReplayRun, error) {
+	return newReplayRun(records, replayRunOptions(opts), summary, createdAt)
+}
+
+// NewComparisonRuns builds one run per compared setting, in label order, so
+// each side of a comparison shows up in history like a single replay.
+func NewComparisonRuns(records []chronorecorder.TrafficRecord, comparison *ReplayComparison, createdAt time.Time) ([]ReplayRun, error) {
+	runs := make([]ReplayRun, 0, len(comparison.Labels))
+	for _, label := range comparison.Labels {
+		run, err := newReplayRun(records, comparison.Settings[label], comparison.Summaries[label], createdAt)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+func newReplayRun(records []chronorecorder.TrafficRecord, opts ReplayRunOptions, summary *chronoreplay.Summary, createdAt time.Time) (ReplayRun, error) {
+	id, err := newReplayID()
+	if err != nil {
+		return ReplayRun{}, err
+	}
+	fingerprint, err := FingerprintRecords(records)
+	if err != nil {
+		return ReplayRun{}, err
+	}
+
+	return ReplayRun{
+		ID:          id,
+		CreatedAt:   createdAt.UTC(),
+		Options:     opts,
+		Fingerprint: fingerprint,
+		RecordCount: len(records),
+		Summary:     cloneSummary(summary),
+	}, nil
+}
+
+func replayRunOptions(opts ReplayOptions) ReplayRunOptions {
+	return ReplayRunOptions{
+		Algorithm:      string(opts.Algorithm),
+		Rate:           opts.Rate,
+		Window:         opts.Window.String(),
+		Burst:          opts.Burst,
+		Speed:          opts.Speed,
+		Keys:           append([]string(nil), opts.Keys...),
+		Endpoints:      append([]string(nil), opts.Endpoints...),
+		StorageBackend: opts.StorageBackend,
+		File:           opts.File,
+	}
+}
+
+// FingerprintRecords returns a SHA-256 over the records in timestamp order, so
+// the same capture yields the same fingerprint regardless of input ordering.
+func FingerprintRecords(records []chronorecorder.TrafficRecord) (string, error) {
+	sorted := append([]chronorecorder.TrafficRecord(nil), records...)
+	sortRecords(sorted)
+
+	h := sha256.New()
+	if err := json.NewEncoder(h).Encode(sorted); err != nil {
+		return "", fmt.Errorf("fingerprint records: %w", err)
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func newReplayID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate replay id: %w", err)
+	}
+	return "rpl_" + hex.EncodeToString(buf), nil
+}
+
+func cloneReplayRun(in ReplayRun) ReplayRun {
+	out := in
+	out.Options.Keys = append([]string(nil), in.Options.Keys...)
+	out.Options.Endpoints = append([]string(nil), in.Options.Endpoints...)
+	out.Summary = cloneSummary(in.Summary)
+	return out
+}
+
+func sortReplayRuns(runs []ReplayRun) {
+	sort.Slice(runs, func(i, j int) bool {
+		if !runs[i].CreatedAt.Equal(runs[j].CreatedAt) {
+			return runs[i].CreatedAt.After(runs[j].CreatedAt)
+		}
+		return runs[i].ID > runs[j].ID
+	})
+}
+
+// MemoryReplayStore keeps replay runs in process memory, evicting the oldest
+// runs beyond its limit.
+type MemoryReplayStore struct {
+	mu    sync.RWMutex
+	limit int
+	seq   int
+	runs  map[string]memoryReplayEntry
+}
+
+type memoryReplayEntry struct {
+	seq int
+	run ReplayRun
+}
+
+// NewMemoryReplayStore creates a memory store holding at most limit runs
+// (limit <= 0 uses the default of 100).
+func NewMemoryReplayStore(limit int) *MemoryReplayStore {
+	if limit <= 0 {
+		limit = defaultReplayHistoryLimit
+	}
+	return &MemoryReplayStore{limit: limit, runs: make(map[string]memoryReplayEntry)}
+}
+
+func (s *MemoryReplayStore) Save(run ReplayRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	s.runs[run.ID] = memoryReplayEntry{seq: s.seq, run: cloneReplayRun(run)}
+	for len(s.runs) > s.limit {
+		oldestID, oldestSeq := "", 0
+		for id, entry := range s.runs {
+			if oldestID == "" || entry.seq < oldestSeq {
+				oldestID, oldestSeq = id, entry.seq
+			}
+		}
+		delete(s.runs, oldestID)
+	}
+	return nil
+}
+
+func (s *MemoryReplayStore) Get(id string) (ReplayRun, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	entry, ok := s.runs[id]
+	if !ok {
+		return ReplayRun{}, false, nil
+	}
+	return cloneReplayRun(entry.run), true, nil
+}
+
+func (s *MemoryReplayStore) List() ([]ReplayRun, error) {
+	s.mu.RLock()
+	entries := make([]memoryReplayEntry, 0, len(s.runs))
+	for _, entry := range s.runs {
+		entries = append(entries, entry)
+	}
+	s.mu.RUnlock()
+
+	// Insertion order breaks CreatedAt ties deterministically for virtual clocks.
+	sort.Slice(entries, func(i, j int) bool { return entries[i].seq > entries[j].seq })
+	out := make([]ReplayRun, 0, len(entries))
+	for _, entry := range entries {
+		out = append(out, cloneReplayRun(entry.run))
+	}
+	return out, nil
+}
+
+func (s *MemoryReplayStore) Delete(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.runs[id]; !ok {
+		return false, nil
+	}
+	delete(s.runs, id)
+	return true, nil
+}
+
+// FileReplayStore persists each replay run as <id>.json in a directory,
+// deleting the oldest runs beyond its limit.
+type FileReplayStore struct {
+	mu    sync.Mutex
+	dir   string
+	limit int
+}
+
+// NewFileReplayStore creates a store rooted at dir holding at most limit runs
+// (limit <= 0 uses the default of 100); the directory is created on first save.
+func NewFileReplayStore(dir string, limit int) *FileReplayStore {
+	if limit <= 0 {
+		limit = defaultReplayHistoryLimit
+	}
+	return &FileReplayStore{dir: dir, limit: limit}
+}
+
+func (s *FileReplayStore) Save(run ReplayRun) error {
+	path, err := s.path(run.ID)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("create replay history dir: %w", err)
+	}
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode replay run: %w", err)
+	}
+
+	if err := writeFileAtomic(path, data, 0o644); err != nil {
+		return fmt.Errorf("write replay run: %w", err)
+	}
+	return s.pruneLocked()
+}
+
+// pruneLocked deletes the oldest runs beyond the limit.
+func (s *FileReplayStore) pruneLocked() error {
+	runs, err := s.listLocked()
+	if err != nil {
+		return err
+	}
+	for _, run := range runs[min(len(runs), s.limit):] {
+		if err := os.Remove(filepath.Join(s.dir, run.ID+".json")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("prune replay history: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *FileReplayStore) Get(id string) (ReplayRun, bool, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return ReplayRun{}, false, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return readReplayRunFile(path)
+}
+
+func (s *FileReplayStore) List() ([]ReplayRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listLocked()
+}
+
+func (s *FileReplayStore) listLocked() ([]ReplayRun, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []ReplayRun{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read replay history dir: %w", err)
+	}
+
+	runs := make([]ReplayRun, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		run, ok, err := readReplayRunFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			runs = append(runs, run)
+		}
+	}
+	sortReplayRuns(runs)
+	return runs, nil
+}
+
+func (s *FileReplayStore) Delete(id string) (bool, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return false, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("delete replay run: %w", err)
+	}
+	return true, nil
+}
+
+func (s *FileReplayStore) path(id string) (string, error) {
+	if !replayIDPattern.MatchString(id) {
+		return "", fmt.Errorf("invalid replay id %q", id)
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+func readReplayRunFile(path string) (ReplayRun, bool, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ReplayRun{}, false, nil
+	}
+	if err != nil {
+		return ReplayRun{}, false, fmt.Errorf("read replay run: %w", err)
+	}
+
+	var run ReplayRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return ReplayRun{}, false, fmt.Errorf("decode replay run %s: %w", filepath.Base(path), err)
+	}
+	if strings.TrimSpace(run.ID) == "" {
+		return ReplayRun{}, false, fmt.Errorf("decode replay run %s: missing id", filepath.Base(path))
+	}
+	return run, true, nil
+}
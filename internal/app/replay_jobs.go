@@ -0,0 +1,313 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+	chronoreplay "github.com/SmitUplenchwar2687/Chrono/pkg/replay"
+)
+
+const (
+	// ReplayJobQueued means the job is waiting for a free worker slot.
+	ReplayJobQueued = "queued"
+	// ReplayJobRunning means the job is replaying records.
+	ReplayJobRunning = "running"
+	// ReplayJobSucceeded means the replay finished and its run was stored.
+	ReplayJobSucceeded = "succeeded"
+	// ReplayJobFailed means the replay returned an error.
+	ReplayJobFailed = "failed"
+	// ReplayJobCanceled means the job was canceled before it finished.
+	ReplayJobCanceled = "canceled"
+
+	defaultReplayWorkers      = 2
+	defaultReplayJobQueue     = 64
+	defaultReplayJobRetention = 100
+)
+
+// ErrReplayQueueFull is returned when too many jobs are already waiting.
+var ErrReplayQueueFull = errors.New("replay job queue is full")
+
+// ReplayJob is a snapshot of an asynchronous replay.
+type ReplayJob struct {
+	ID         string                `json:"id"`
+	Status     string                `json:"status"`
+	CreatedAt  time.Time             `json:"created_at"`
+	StartedAt  *time.Time            `json:"started_at,omitempty"`
+	FinishedAt *time.Time            `json:"finished_at,omitempty"`
+	Progress   ReplayJobProgress     `json:"progress"`
+	ReplayID   string                `json:"replay_id,omitempty"`
+	Summary    *chronoreplay.Summary `json:"summary,omitempty"`
+	Timeline   *ReplayTimeline       `json:"timeline,omitempty"`
+	Error      string                `json:"error,omitempty"`
+}
+
+// ReplayJobProgress reports how far a job has advanced.
+type ReplayJobProgress struct {
+	Total      int     `json:"total"`
+	Processed  int     `json:"processed"`
+	Allowed    int     `json:"allowed"`
+	Denied     int     `json:"denied"`
+	Percent    float64 `json:"percent"`
+	ETASeconds float64 `json:"eta_seconds"`
+}
+
+type replayJob struct {
+	ReplayJob
+
+	cancel    context.CancelFunc
+	wallStart time.Time
+}
+
+// ReplayJobManager runs replays in the background with at most `workers`
+// replays executing at once.
+type ReplayJobManager struct {
+	mu        sync.RWMutex
+	jobs      map[string]*replayJob
+	slots     chan struct{}
+	queued    int
+	maxQueued int
+	retention int
+
+	state *ReplayState
+	clk   chronoclock.Clock
+}
+
+// NewReplayJobManager creates a manager; workers <= 0 uses the default of 2.
+func NewReplayJobManager(workers int, state *ReplayState, clk chronoclock.Clock) *ReplayJobManager {
+	if workers <= 0 {
+		workers = defaultReplayWorkers
+	}
+	return &ReplayJobManager{
+		jobs:      make(map[string]*replayJob),
+		slots:     make(chan struct{}, workers),
+		maxQueued: defaultReplayJobQueue,
+		retention: defaultReplayJobRetention,
+		state:     state,
+		clk:       clk,
+	}
+}
+
+// withState returns a manager with its own jobs that stores runs in state
+// but shares m's worker slots, so together they run at most m's workers.
+func (m *ReplayJobManager) withState(state *ReplayState) *ReplayJobManager {
+	return &ReplayJobManager{
+		jobs:      make(map[string]*replayJob),
+		slots:     m.slots,
+		maxQueued: m.maxQueued,
+		retention: m.retention,
+		state:     state,
+		clk:       m.clk,
+	}
+}
+
+// Submit queues a replay and returns its initial snapshot.
+func (m *ReplayJobManager) Submit(records []chronorecorder.TrafficRecord, opts ReplayOptions) (ReplayJob, error) {
+	if len(opts.Compare) > 0 {
+		return ReplayJob{}, fmt.Errorf("compare is not supported for replay jobs")
+	}
+	if len(records) == 0 {
+		return ReplayJob{}, fmt.Errorf("no records provided")
+	}
+
+	id, err := newReplayID()
+	if err != nil {
+		return ReplayJob{}, err
+	}
+	id = "job_" + id[len("rpl_"):]
+
+	filter := &chronoreplay.Filter{Keys: opts.Keys, Endpoints: opts.Endpoints}
+	total := 0
+	for _, rec := range records {
+		if filter.Match(rec) {
+			total++
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &replayJob{
+		ReplayJob: ReplayJob{
+			ID:        id,
+			Status:    ReplayJobQueued,
+			CreatedAt: m.clk.Now().UTC(),
+			Progress:  ReplayJobProgress{Total: total},
+		},
+		cancel: cancel,
+	}
+
+	m.mu.Lock()
+	if m.queued >= m.maxQueued {
+		m.mu.Unlock()
+		cancel()
+		return ReplayJob{}, ErrReplayQueueFull
+	}
+	m.queued++
+	m.jobs[id] = job
+	m.pruneLocked()
+	snapshot := job.snapshot()
+	m.mu.Unlock()
+
+	go m.run(ctx, job, records, opts)
+
+	return snapshot, nil
+}
+
+// Get returns a snapshot of the job.
+func (m *ReplayJobManager) Get(id string) (ReplayJob, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return ReplayJob{}, false
+	}
+	return job.snapshot(), true
+}
+
+// List returns job snapshots newest first.
+func (m *ReplayJobManager) List() []ReplayJob {
+	m.mu.RLock()
+	out := make([]ReplayJob, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		out = append(out, job.snapshot())
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.After(out[j].CreatedAt)
+		}
+		return out[i].ID > out[j].ID
+	})
+	return out
+}
+
+// Cancel cancels a queued or running job. Finished jobs are left unchanged.
+func (m *ReplayJobManager) Cancel(id string) (ReplayJob, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return ReplayJob{}, false
+	}
+	if job.Status == ReplayJobQueued || job.Status == ReplayJobRunning {
+		job.cancel()
+	}
+	return job.snapshot(), true
+}
+
+func (m *ReplayJobManager) run(ctx context.Context, job *replayJob, records []chronorecorder.TrafficRecord, opts ReplayOptions) {
+	defer job.cancel()
+
+	select {
+	case m.slots <- struct{}{}:
+	case <-ctx.Done():
+		m.mu.Lock()
+		m.queued--
+		m.finishLocked(job, ReplayJobCanceled, ctx.Err())
+		m.mu.Unlock()
+		return
+	}
+	defer func() { <-m.slots }()
+
+	m.mu.Lock()
+	m.queued--
+	started := m.clk.Now().UTC()
+	job.Status = ReplayJobRunning
+	job.StartedAt = &started
+	job.wallStart = time.Now()
+	m.mu.Unlock()
+
+	var builder *timelineBuilder
+	if opts.TimelineBucket > 0 {
+		builder = newTimelineBuilder(opts.TimelineBucket)
+	}
+
+	summary, err := runReplay(ctx, records, opts, func(res chronoreplay.Result) {
+		if builder != nil {
+			builder.add(res)
+		}
+		m.mu.Lock()
+		job.Progress.Processed++
+		if res.Decision.Allowed {
+			job.Progress.Allowed++
+		} else {
+			job.Progress.Denied++
+		}
+		m.mu.Unlock()
+	})
+
+	var timeline *ReplayTimeline
+	if err == nil && builder != nil {
+		timeline, err = builder.build()
+	}
+
+	// Once the run is saved the job has succeeded, even if a cancel arrives
+	// before its status is updated.
+	canceled := ctx.Err()
+	var replayID string
+	if err == nil && canceled == nil {
+		replayID = saveReplayRun(m.state, records, opts, summary, m.clk)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch {
+	case canceled != nil:
+		m.finishLocked(job, ReplayJobCanceled, canceled)
+	case err != nil:
+		m.finishLocked(job, ReplayJobFailed, err)
+	default:
+		job.Summary = summary
+		job.Timeline = timeline
+		job.ReplayID = replayID
+		m.finishLocked(job, ReplayJobSucceeded, nil)
+	}
+}
+
+func (m *ReplayJobManager) finishLocked(job *replayJob, status string, err error) {
+	finished := m.clk.Now().UTC()
+	job.Status = status
+	job.FinishedAt = &finished
+	if err != nil {
+		job.Error = err.Error()
+	}
+}
+
+// pruneLocked drops the oldest finished jobs beyond the retention limit.
+func (m *ReplayJobManager) pruneLocked() {
+	var finished []*replayJob
+	for _, job := range m.jobs {
+		if job.FinishedAt != nil {
+			finished = append(finished, job)
+		}
+	}
+	if len(finished) <= m.retention {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].FinishedAt.Before(*finished[j].FinishedAt)
+	})
+	for _, job := range finished[:len(finished)-m.retention] {
+		delete(m.jobs, job.ID)
+	}
+}
+
+func (j *replayJob) snapshot() ReplayJob {
+	out := j.ReplayJob
+	out.Summary = cloneSummary(j.Summary)
+
+	p := &out.Progress
+	if p.Total > 0 {
+		p.Percent = float64(p.Processed) / float64(p.Total) * 100
+	}
+	if j.Status == ReplayJobRunning && p.Processed > 0 && p.Total > p.Processed {
+		perRecord := time.Since(j.wallStart).Seconds() / float64(p.Processed)
+		p.ETASeconds = perRecord * float64(p.Total-p.Processed)
+	}
+	return out
+}
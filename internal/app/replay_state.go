@@ -1,34 +1,46 @@
 package app
 
 import (
-	"sync"
-
 	chronoreplay "github.com/SmitUplenchwar2687/Chrono/pkg/replay"
 )
 
-// ReplayState stores the most recent replay summary.
+// ReplayState records replay runs in a ReplayStore and serves the most recent one.
 type ReplayState struct {
-	mu      sync.RWMutex
-	summary *chronoreplay.Summary
+	store ReplayStore
 }
 
-func NewReplayState() *ReplayState {
-	return &ReplayState{}
+func NewReplayState(store ReplayStore) *ReplayState {
+	if store == nil {
+		store = NewMemoryReplayStore(0)
+	}
+	return &ReplayState{store: store}
 }
 
-func (s *ReplayState) Set(summary *chronoreplay.Summary) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.summary = cloneSummary(summary)
+func (s *ReplayState) Save(run ReplayRun) error {
+	return s.store.Save(run)
 }
 
-func (s *ReplayState) Get() (*chronoreplay.Summary, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	if s.summary == nil {
-		return nil, false
+func (s *ReplayState) Last() (ReplayRun, bool, error) {
+	runs, err := s.store.List()
+	if err != nil {
+		return ReplayRun{}, false, err
+	}
+	if len(runs) == 0 {
+		return ReplayRun{}, false, nil
 	}
-	return cloneSummary(s.summary), true
+	return runs[0], true, nil
+}
+
+func (s *ReplayState) Get(id string) (ReplayRun, bool, error) {
+	return s.store.Get(id)
+}
+
+func (s *ReplayState) List() ([]ReplayRun, error) {
+	return s.store.List()
+}
+
+func (s *ReplayState) Delete(id string) (bool, error) {
+	return s.store.Delete(id)
 }
 
 func cloneSummary(in *chronoreplay.Summary) *chronoreplay.Summary {
@@ -4,12 +4,15 @@ import (
 	"bytes"
 	"context"
 	"os"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
 	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+	chronostorage "github.com/SmitUplenchwar2687/Chrono/pkg/storage"
+	"github.com/alicebob/miniredis/v2"
 )
 
 func TestRunReplaySummary(t *testing.T) {
@@ -71,3 +74,299 @@ func TestRunReplaySummary(t *testing.T) {
 		t.Fatalf("unexpected replay output:\n%s", out)
 	}
 }
+
+func TestRunReplayRecordsThroughStorageBackends(t *testing.T) {
+	start := time.Date(2026, 2, 8, 12, 0, 0, 0, time.UTC)
+	records := []chronorecorder.TrafficRecord{
+		{Timestamp: start, Key: "k1", Endpoint: "GET /api/profile"},
+		{Timestamp: start.Add(time.Second), Key: "k1", Endpoint: "GET /api/profile"},
+		{Timestamp: start.Add(2 * time.Second), Key: "k1", Endpoint: "GET /api/profile"},
+		{Timestamp: start.Add(61 * time.Second), Key: "k1", Endpoint: "GET /api/profile"},
+	}
+
+	mr := miniredis.RunT(t)
+	port, err := strconv.Atoi(mr.Port())
+	if err != nil {
+		t.Fatalf("parse miniredis port: %v", err)
+	}
+	storageCfg := mustTestConfig(limiter.AlgorithmSlidingWindow).Storage
+	storageCfg.Redis = &chronostorage.RedisConfig{Host: mr.Host(), Port: port}
+
+	tests := []struct {
+		name      string
+		algorithm limiter.Algorithm
+		backend   string
+	}{
+		{name: "memory fixed window", algorithm: limiter.AlgorithmFixedWindow, backend: chronostorage.BackendMemory},
+		{name: "memory sliding window", algorithm: limiter.AlgorithmSlidingWindow, backend: chronostorage.BackendMemory},
+		{name: "redis", algorithm: limiter.AlgorithmSlidingWindow, backend: chronostorage.BackendRedis},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Run twice to prove each replay starts from clean backend state.
+			for run := 0; run < 2; run++ {
+				summary, err := RunReplayRecords(context.Background(), records, ReplayOptions{
+					Algorithm:      tt.algorithm,
+					Rate:           2,
+					Window:         time.Minute,
+					Burst:          2,
+					StorageBackend: tt.backend,
+					Storage:        storageCfg,
+				}, nil)
+				if err != nil {
+					t.Fatalf("RunReplayRecords() error = %v", err)
+				}
+				if summary.Allowed != 3 || summary.Denied != 1 {
+					t.Fatalf("run %d Allowed/Denied = %d/%d, want 3/1", run, summary.Allowed, summary.Denied)
+				}
+			}
+		})
+	}
+
+	if storageCfg.Redis.Clock != nil {
+		t.Fatal("replay must not inject its virtual clock into the caller's storage config")
+	}
+}
+
+func TestRunReplayRecordsRejectsUnsupportedStorageAlgorithm(t *testing.T) {
+	start := time.Date(2026, 2, 8, 12, 0, 0, 0, time.UTC)
+	_, err := RunReplayRecords(context.Background(), []chronorecorder.TrafficRecord{
+		{Timestamp: start, Key: "k1", Endpoint: "GET /api/profile"},
+	}, ReplayOptions{
+		Algorithm:      limiter.AlgorithmTokenBucket,
+		Rate:           2,
+		Window:         time.Minute,
+		Burst:          2,
+		StorageBackend: chronostorage.BackendRedis,
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), "unsupported") {
+		t.Fatalf("RunReplayRecords() error = %v, want unsupported algorithm error", err)
+	}
+}
+
+func TestRunReplayComparisonReportsDivergence(t *testing.T) {
+	start := time.Date(2026, 2, 8, 12, 0, 0, 0, time.UTC)
+	records := []chronorecorder.TrafficRecord{
+		{Timestamp: start, Key: "k1", Endpoint: "GET /api/profile"},
+		{Timestamp: start.Add(time.Second), Key: "k1", Endpoint: "GET /api/profile"},
+		{Timestamp: start.Add(2 * time.Second), Key: "k1", Endpoint: "GET /api/profile"},
+		{Timestamp: start.Add(31 * time.Second), Key: "k1", Endpoint: "GET /api/profile"},
+		{Timestamp: start.Add(32 * time.Second), Key: "k2", Endpoint: "POST /api/orders"},
+	}
+
+	var output bytes.Buffer
+	comparison, err := RunReplayComparison(context.Background(), records, ReplayOptions{
+		Rate:    2,
+		Window:  time.Minute,
+		Burst:   2,
+		Compare: []limiter.Algorithm{limiter.AlgorithmTokenBucket, limiter.AlgorithmSlidingWindow, limiter.AlgorithmFixedWindow},
+	}, &output)
+	if err != nil {
+		t.Fatalf("RunReplayComparison() error = %v", err)
+	}
+
+	if len(comparison.Rows) != 2 {
+		t.Fatalf("len(Rows) = %d, want 2", len(comparison.Rows))
+	}
+	k1 := comparison.Rows[0]
+	if k1.Key != "k1" || !k1.Different {
+		t.Fatalf("Rows[0] = %+v, want diverging k1 row", k1)
+	}
+	if got := k1.Results[string(limiter.AlgorithmTokenBucket)]; got.Allowed != 3 || got.Denied != 1 {
+		t.Fatalf("token_bucket k1 = %+v, want 3/1", got)
+	}
+	if got := k1.Results[string(limiter.AlgorithmFixedWindow)]; got.Allowed != 2 || got.Denied != 2 {
+		t.Fatalf("fixed_window k1 = %+v, want 2/2", got)
+	}
+	if comparison.Rows[1].Different {
+		t.Fatalf("Rows[1] = %+v, want no divergence for k2", comparison.Rows[1])
+	}
+
+	if len(comparison.Divergences) != 1 {
+		t.Fatalf("len(Divergences) = %d, want 1", len(comparison.Divergences))
+	}
+	if d := comparison.Divergences[0]; d.Index != 3 || !d.Timestamp.Equal(start.Add(31*time.Second)) {
+		t.Fatalf("Divergences[0] = %+v, want index 3 at +31s", d)
+	}
+
+	if out := output.String(); !strings.Contains(out, "Comparison: token_bucket vs sliding_window vs fixed_window") {
+		t.Fatalf("unexpected comparison output:\n%s", out)
+	}
+}
+
+func TestRunReplayBaselineComparesCapturedLimits(t *testing.T) {
+	start := time.Date(2026, 2, 8, 12, 0, 0, 0, time.UTC)
+	records := []chronorecorder.TrafficRecord{
+		{Timestamp: start, Key: "k1", Endpoint: "GET /api/profile"},
+		{Timestamp: start.Add(time.Second), Key: "k1", Endpoint: "GET /api/profile"},
+		{Timestamp: start.Add(2 * time.Second), Key: "k1", Endpoint: "GET /api/profile"},
+		{Timestamp: start.Add(3 * time.Second), Key: "k2", Endpoint: "GET /api/profile"},
+	}
+
+	captured := &RecordingConfig{Algorithm: string(limiter.AlgorithmFixedWindow), Rate: 2, Window: "1m", Burst: 2}
+	baseline, err := captured.ReplayOptions()
+	if err != nil {
+		t.Fatalf("ReplayOptions() error = %v", err)
+	}
+
+	var output bytes.Buffer
+	comparison, err := RunReplayBaseline(context.Background(), records, baseline, ReplayOptions{
+		Algorithm: limiter.AlgorithmFixedWindow,
+		Rate:      5,
+		Window:    time.Minute,
+		Burst:     2,
+	}, &output)
+	if err != nil {
+		t.Fatalf("RunReplayBaseline() error = %v", err)
+	}
+
+	if b, c := comparison.Summaries["baseline"], comparison.Summaries["current"]; b.Denied != 1 || c.Denied != 0 {
+		t.Fatalf("denied baseline/current = %d/%d, want 1/0", b.Denied, c.Denied)
+	}
+	if comparison.Settings["baseline"].Rate != 2 || comparison.Settings["current"].Rate != 5 {
+		t.Fatalf("settings = %+v", comparison.Settings)
+	}
+	if len(comparison.Divergences) != 1 || comparison.Divergences[0].Index != 2 {
+		t.Fatalf("divergences = %+v, want one at index 2", comparison.Divergences)
+	}
+	if out := output.String(); !strings.Contains(out, "baseline (fixed_window rate=2 window=1m0s burst=2)") {
+		t.Fatalf("unexpected baseline output:\n%s", out)
+	}
+}
+
+func TestCompareReplaysRejectsMismatchedResults(t *testing.T) {
+	start := time.Date(2026, 2, 8, 12, 0, 0, 0, time.UTC)
+	records := []chronorecorder.TrafficRecord{
+		{Timestamp: start, Key: "k1", Endpoint: "GET /api/profile"},
+		{Timestamp: start.Add(time.Second), Key: "k2", Endpoint: "GET /api/profile"},
+	}
+	opts := ReplayOptions{Algorithm: limiter.AlgorithmFixedWindow, Rate: 2, Window: time.Minute, Burst: 2}
+	filtered := opts
+	filtered.Keys = []string{"k1"}
+
+	_, err := compareReplays(context.Background(), records, []replayVariant{
+		{label: "all", opts: opts},
+		{label: "k1", opts: filtered},
+	})
+	if err == nil || !strings.Contains(err.Error(), "replay k1 produced 1 results, all produced 2") {
+		t.Fatalf("compareReplays() error = %v, want a result count mismatch", err)
+	}
+}
+
+func TestRunReplayTimelineBucketsDecisions(t *testing.T) {
+	start := time.Date(2026, 2, 8, 12, 0, 0, 0, time.UTC)
+	records := []chronorecorder.TrafficRecord{
+		{Timestamp: start, Key: "k1", Endpoint: "GET /api/profile"},
+		{Timestamp: start.Add(time.Second), Key: "k1", Endpoint: "GET /api/profile"},
+		{Timestamp: start.Add(2 * time.Second), Key: "k1", Endpoint: "POST /api/orders"},
+		{Timestamp: start.Add(25 * time.Second), Key: "k2", Endpoint: "GET /api/profile"},
+	}
+
+	summary, timeline, err := RunReplayTimeline(context.Background(), records, ReplayOptions{
+		Algorithm:      limiter.AlgorithmFixedWindow,
+		Rate:           2,
+		Window:         time.Minute,
+		Burst:          2,
+		TimelineBucket: 10 * time.Second,
+	}, nil)
+	if err != nil {
+		t.Fatalf("RunReplayTimeline() error = %v", err)
+	}
+	if summary.Denied != 1 {
+		t.Fatalf("summary.Denied = %d, want 1", summary.Denied)
+	}
+
+	// 12:00:00, 12:00:10 (empty, gap-filled) and 12:00:20.
+	if len(timeline.Buckets) != 3 {
+		t.Fatalf("len(Buckets) = %d, want 3", len(timeline.Buckets))
+	}
+	first := timeline.Buckets[0]
+	if first.Allowed != 2 || first.Denied != 1 {
+		t.Fatalf("Buckets[0] allowed/denied = %d/%d, want 2/1", first.Allowed, first.Denied)
+	}
+	if got := first.PerEndpoint["POST /api/orders"]; got.Denied != 1 {
+		t.Fatalf("Buckets[0].PerEndpoint[POST /api/orders] = %+v, want 1 denied", got)
+	}
+	if gap := timeline.Buckets[1]; gap.Allowed != 0 || gap.Denied != 0 || !gap.Start.Equal(start.Add(10*time.Second)) {
+		t.Fatalf("Buckets[1] = %+v, want empty bucket at +10s", gap)
+	}
+	if got := timeline.FirstDenied["k1"]; !got.Equal(start.Add(2 * time.Second)) {
+		t.Fatalf("FirstDenied[k1] = %s, want %s", got, start.Add(2*time.Second))
+	}
+
+	var csvOut bytes.Buffer
+	if err := WriteTimeline(&csvOut, timeline, TimelineFormatCSV); err != nil {
+		t.Fatalf("WriteTimeline(csv) error = %v", err)
+	}
+	if !strings.Contains(csvOut.String(), "2026-02-08T12:00:00Z,key,k1,2,1") {
+		t.Fatalf("unexpected timeline CSV:\n%s", csvOut.String())
+	}
+}
+
+func TestRunReplayBaselineAppliesCapturedRoutePolicies(t *testing.T) {
+	start := time.Date(2026, 2, 8, 12, 0, 0, 0, time.UTC)
+	records := []chronorecorder.TrafficRecord{
+		{Timestamp: start, Key: "k1", Endpoint: "GET /api/profile"},
+		{Timestamp: start.Add(time.Second), Key: "k1", Endpoint: "GET /api/profile"},
+		{Timestamp: start.Add(2 * time.Second), Key: "k1", Endpoint: "GET /public"},
+		{Timestamp: start.Add(3 * time.Second), Key: "k1", Endpoint: "GET /public"},
+		{Timestamp: start.Add(4 * time.Second), Key: "k1", Endpoint: "GET /api/token-bucket"},
+	}
+
+	captured := &RecordingConfig{
+		Algorithm: string(limiter.AlgorithmFixedWindow), Rate: 1, Window: "1m0s", Burst: 1,
+		StorageBackend: "memory",
+		Routes: []RoutePolicy{
+			{Endpoint: "GET /api/profile", Limited: true, Algorithm: string(limiter.AlgorithmFixedWindow), Rate: 1, Window: "1m0s", Burst: 1},
+			{Endpoint: "GET /public"},
+			{Endpoint: "GET /api/token-bucket", Limited: true, Algorithm: string(limiter.AlgorithmTokenBucket), Rate: 1, Window: "1m0s", Burst: 1},
+		},
+	}
+	baseline, err := captured.ReplayOptions()
+	if err != nil {
+		t.Fatalf("ReplayOptions() error = %v", err)
+	}
+	if baseline.StorageBackend != "memory" || len(baseline.Routes) != 3 {
+		t.Fatalf("captured options = %+v", baseline)
+	}
+
+	comparison, err := RunReplayBaseline(context.Background(), records, baseline, ReplayOptions{
+		Algorithm: limiter.AlgorithmFixedWindow,
+		Rate:      1,
+		Window:    time.Minute,
+		Burst:     1,
+	}, nil)
+	if err != nil {
+		t.Fatalf("RunReplayBaseline() error = %v", err)
+	}
+
+	// The unlimited route and the pinned route do not spend the main budget.
+	b := comparison.Summaries["baseline"]
+	if b.Allowed != 4 || b.Denied != 1 || b.PerKey["k1"].Allowed != 4 || b.TotalRecords != 5 {
+		t.Fatalf("baseline summary = %+v", b)
+	}
+	if c := comparison.Summaries["current"]; c.Denied != 4 {
+		t.Fatalf("current denied = %d, want 4 under one shared limiter", c.Denied)
+	}
+	for _, row := range comparison.Rows {
+		if row.Key != "k1" {
+			t.Fatalf("row key = %q, want the decoded key", row.Key)
+		}
+	}
+}
+
+func TestReadRecordingFileVersion1Endpoints(t *testing.T) {
+	data := []byte(`{"version": 1, "config": {"algorithm": "fixed_window", "rate": 1, "window": "1m", "burst": 1, "endpoints": ["GET /custom"]},
+  "records": [{"timestamp": "2026-02-08T12:00:00Z", "key": "k1", "endpoint": "GET /custom"}]}`)
+	file, validation, err := ReadRecordingFile(data)
+	if err != nil {
+		t.Fatalf("ReadRecordingFile() error = %v", err)
+	}
+	if len(validation.Issues) != 0 {
+		t.Fatalf("issues = %v, want the version 1 endpoint list honored", validation.Issues)
+	}
+	if got := file.Endpoints(); len(got) != 1 || got[0] != "GET /custom" {
+		t.Fatalf("Endpoints() = %v", got)
+	}
+}
@@ -0,0 +1,204 @@
+package app
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+	chronoreplay "github.com/SmitUplenchwar2687/Chrono/pkg/replay"
+)
+
+const (
+	// TimelineFormatJSON emits the timeline as a JSON document.
+	TimelineFormatJSON = "json"
+	// TimelineFormatCSV emits the timeline as long-form CSV rows.
+	TimelineFormatCSV = "csv"
+
+	maxTimelineBuckets = 100000
+)
+
+// ReplayTimeline is a per-bucket time series of replay decisions.
+type ReplayTimeline struct {
+	Bucket      string               `json:"bucket"`
+	Start       time.Time            `json:"start"`
+	End         time.Time            `json:"end"`
+	Buckets     []TimelineBucket     `json:"buckets"`
+	FirstDenied map[string]time.Time `json:"first_denied,omitempty"`
+}
+
+// TimelineBucket holds decision counts for one bucket, overall and broken down
+// by key and endpoint.
+type TimelineBucket struct {
+	Start       time.Time                 `json:"start"`
+	Allowed     int                       `json:"allowed"`
+	Denied      int                       `json:"denied"`
+	PerKey      map[string]DecisionCounts `json:"per_key,omitempty"`
+	PerEndpoint map[string]DecisionCounts `json:"per_endpoint,omitempty"`
+}
+
+type timelineBuilder struct {
+	bucket      time.Duration
+	buckets     map[int64]*TimelineBucket
+	firstDenied map[string]time.Time
+}
+
+func newTimelineBuilder(bucket time.Duration) *timelineBuilder {
+	return &timelineBuilder{
+		bucket:      bucket,
+		buckets:     make(map[int64]*TimelineBucket),
+		firstDenied: make(map[string]time.Time),
+	}
+}
+
+func (b *timelineBuilder) add(res chronoreplay.Result) {
+	start := res.Record.Timestamp.UTC().Truncate(b.bucket)
+	tb, ok := b.buckets[start.UnixNano()]
+	if !ok {
+		tb = &TimelineBucket{Start: start}
+		b.buckets[start.UnixNano()] = tb
+	}
+
+	if res.Decision.Allowed {
+		tb.Allowed++
+	} else {
+		tb.Denied++
+		if _, seen := b.firstDenied[res.Record.Key]; !seen {
+			b.firstDenied[res.Record.Key] = res.Record.Timestamp
+		}
+	}
+	tb.PerKey = addDecision(tb.PerKey, res.Record.Key, res.Decision.Allowed)
+	tb.PerEndpoint = addDecision(tb.PerEndpoint, res.Record.Endpoint, res.Decision.Allowed)
+}
+
+// build returns the timeline with empty buckets filled in so the series is
+// continuous for charting.
+func (b *timelineBuilder) build() (*ReplayTimeline, error) {
+	timeline := &ReplayTimeline{Bucket: b.bucket.String()}
+	if len(b.firstDenied) > 0 {
+		timeline.FirstDenied = b.firstDenied
+	}
+	if len(b.buckets) == 0 {
+		return timeline, nil
+	}
+
+	starts := make([]int64, 0, len(b.buckets))
+	for start := range b.buckets {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	first := time.Unix(0, starts[0]).UTC()
+	last := time.Unix(0, starts[len(starts)-1]).UTC()
+	if count := int64(last.Sub(first)/b.bucket) + 1; count > maxTimelineBuckets {
+		return nil, fmt.Errorf("timeline bucket %s is too small: %d buckets exceeds limit of %d", b.bucket, count, maxTimelineBuckets)
+	}
+
+	for t := first; !t.After(last); t = t.Add(b.bucket) {
+		if tb, ok := b.buckets[t.UnixNano()]; ok {
+			timeline.Buckets = append(timeline.Buckets, *tb)
+			continue
+		}
+		timeline.Buckets = append(timeline.Buckets, TimelineBucket{Start: t})
+	}
+	timeline.Start = first
+	timeline.End = last.Add(b.bucket)
+	return timeline, nil
+}
+
+func addDecision(m map[string]DecisionCounts, name string, allowed bool) map[string]DecisionCounts {
+	if m == nil {
+		m = make(map[string]DecisionCounts)
+	}
+	counts := m[name]
+	if allowed {
+		counts.Allowed++
+	} else {
+		counts.Denied++
+	}
+	m[name] = counts
+	return m
+}
+
+// RunReplayTimeline replays records like RunReplayRecords and additionally
+// buckets every decision into a time series of opts.TimelineBucket width.
+func RunReplayTimeline(ctx context.Context, records []chronorecorder.TrafficRecord, opts ReplayOptions, out io.Writer) (*chronoreplay.Summary, *ReplayTimeline, error) {
+	if opts.TimelineBucket <= 0 {
+		return nil, nil, fmt.Errorf("timeline bucket must be > 0, got %s", opts.TimelineBucket)
+	}
+
+	builder := newTimelineBuilder(opts.TimelineBucket)
+	summary, err := runReplay(ctx, records, opts, builder.add)
+	if err != nil {
+		return nil, nil, err
+	}
+	timeline, err := builder.build()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if out == nil {
+		out = io.Discard
+	}
+	printReplaySummary(out, summary)
+
+	return summary, timeline, nil
+}
+
+// WriteTimeline encodes the timeline in the given format (json|csv).
+func WriteTimeline(w io.Writer, timeline *ReplayTimeline, format string) error {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", TimelineFormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(timeline)
+	case TimelineFormatCSV:
+		return writeTimelineCSV(w, timeline)
+	default:
+		return fmt.Errorf("invalid timeline format %q", format)
+	}
+}
+
+// writeTimelineCSV writes one row per bucket for the total series and one per
+// bucket/key and bucket/endpoint pair, which most charting tools pivot directly.
+func writeTimelineCSV(w io.Writer, timeline *ReplayTimeline) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"bucket_start", "dimension", "name", "allowed", "denied"}); err != nil {
+		return err
+	}
+
+	for _, tb := range timeline.Buckets {
+		start := tb.Start.Format(time.RFC3339Nano)
+		if err := cw.Write([]string{start, "total", "", strconv.Itoa(tb.Allowed), strconv.Itoa(tb.Denied)}); err != nil {
+			return err
+		}
+		for _, dim := range []struct {
+			name   string
+			counts map[string]DecisionCounts
+		}{
+			{name: "key", counts: tb.PerKey},
+			{name: "endpoint", counts: tb.PerEndpoint},
+		} {
+			names := make([]string, 0, len(dim.counts))
+			for name := range dim.counts {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				c := dim.counts[name]
+				if err := cw.Write([]string{start, dim.name, name, strconv.Itoa(c.Allowed), strconv.Itoa(c.Denied)}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"os"
 	"sort"
 	"strings"
 	"time"
@@ -13,6 +12,7 @@ import (
 	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
 	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
 	chronoreplay "github.com/SmitUplenchwar2687/Chrono/pkg/replay"
+	chronostorage "github.com/SmitUplenchwar2687/Chrono/pkg/storage"
 )
 
 // ReplayOptions configures replay execution.
@@ -25,31 +25,89 @@ type ReplayOptions struct {
 	Speed     float64
 	Keys      []string
 	Endpoints []string
+
+	// TimelineBucket, when > 0, buckets decisions into a time series (see RunReplayTimeline).
+	TimelineBucket time.Duration
+	TimelineFormat string
+
+	// Compare lists algorithms to replay side-by-side (see RunReplayComparison).
+	Compare []limiter.Algorithm
+
+	// StorageBackend, when set (memory|redis|crdt), replays through
+	// NewStorageBackedLimiter instead of the direct algorithm limiters.
+	StorageBackend string
+	Storage        chronostorage.Config
+
+	// Routes, when set, replays each record under its route's captured
+	// policy, like the gateway enforced it: unlimited routes spend no budget,
+	// routes with the top-level limits share the main limiter, and other
+	// routes get their own in-process limiter.
+	Routes []RoutePolicy
 }
 
 // RunReplay loads recorded traffic from file, replays it through the selected limiter,
 // and prints summary stats.
 func RunReplay(ctx context.Context, opts ReplayOptions, out io.Writer) (*chronoreplay.Summary, error) {
-	if strings.TrimSpace(opts.File) == "" {
-		return nil, fmt.Errorf("replay file is required")
+	records, err := LoadReplayRecords(opts.File)
+	if err != nil {
+		return nil, err
 	}
 
-	f, err := os.Open(opts.File)
-	if err != nil {
-		return nil, fmt.Errorf("open replay file: %w", err)
+	return RunReplayRecords(ctx, records, opts, out)
+}
+
+// LoadReplayRecords reads a recordings JSON file.
+func LoadReplayRecords(path string) ([]chronorecorder.TrafficRecord, error) {
+	if strings.TrimSpace(path) == "" {
+		return nil, fmt.Errorf("replay file is required")
 	}
-	defer f.Close()
 
-	records, err := chronorecorder.LoadJSON(f)
+	file, validation, err := LoadRecordingFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("load records: %w", err)
 	}
-
-	return RunReplayRecords(ctx, records, opts, out)
+	if err := validation.Err(false); err != nil {
+		return nil, err
+	}
+	return file.Records, nil
 }
 
 // RunReplayRecords replays in-memory traffic records and prints summary stats.
 func RunReplayRecords(ctx context.Context, records []chronorecorder.TrafficRecord, opts ReplayOptions, out io.Writer) (*chronoreplay.Summary, error) {
+	summary, err := runReplay(ctx, records, opts, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if out == nil {
+		out = io.Discard
+	}
+	printReplaySummary(out, summary)
+
+	return summary, nil
+}
+
+func printReplaySummary(out io.Writer, summary *chronoreplay.Summary) {
+	fmt.Fprintf(out, "Total: %d\n", summary.TotalRecords)
+	fmt.Fprintf(out, "Replayed: %d\n", summary.Replayed)
+	fmt.Fprintf(out, "Allowed: %d\n", summary.Allowed)
+	fmt.Fprintf(out, "Denied: %d\n", summary.Denied)
+	fmt.Fprintf(out, "Per-key:\n")
+
+	keys := make([]string, 0, len(summary.PerKey))
+	for key := range summary.PerKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		ks := summary.PerKey[key]
+		fmt.Fprintf(out, "  %s: allowed=%d denied=%d\n", key, ks.Allowed, ks.Denied)
+	}
+}
+
+// runReplay replays records on a fresh virtual clock starting at the earliest
+// record, invoking cb (if non-nil) for every replayed decision.
+func runReplay(ctx context.Context, records []chronorecorder.TrafficRecord, opts ReplayOptions, cb func(chronoreplay.Result)) (*chronoreplay.Summary, error) {
 	if len(records) == 0 {
 		return nil, fmt.Errorf("no records provided")
 	}
@@ -60,17 +118,15 @@ func RunReplayRecords(ctx context.Context, records []chronorecorder.TrafficRecor
 	})
 
 	vc := chronoclock.NewVirtualClock(sorted[0].Timestamp)
-	lim, err := NewLimiter(Config{
-		Algorithm:      opts.Algorithm,
-		Rate:           opts.Rate,
-		Window:         opts.Window,
-		Burst:          opts.Burst,
-		Addr:           ":0",
-		StorageBackend: "memory",
-	}, vc)
+	lim, closeLimiter, err := newReplayLimiter(opts, vc)
 	if err != nil {
 		return nil, fmt.Errorf("create limiter: %w", err)
 	}
+	defer closeLimiter()
+
+	if len(opts.Routes) > 0 {
+		return runRouteReplay(ctx, sorted, opts, vc, lim, cb)
+	}
 
 	replayer := chronoreplay.New(lim, vc, opts.Speed, &chronoreplay.Filter{
 		Keys:      opts.Keys,
@@ -78,30 +134,190 @@ func RunReplayRecords(ctx context.Context, records []chronorecorder.TrafficRecor
 	})
 	replayer.LoadRecords(sorted)
 
-	summary, err := replayer.Run(ctx, nil)
+	summary, err := replayer.Run(ctx, cb)
 	if err != nil {
 		return nil, fmt.Errorf("run replay: %w", err)
 	}
+	return summary, nil
+}
 
-	if out == nil {
-		out = io.Discard
-	}
+// routeKeySeparator joins endpoint and key for routeReplayLimiter. The
+// replayer only hands keys to the limiter, so the endpoint rides along in
+// the key and is split off again before anything sees it.
+const routeKeySeparator = "\x00"
 
-	fmt.Fprintf(out, "Total: %d\n", summary.TotalRecords)
-	fmt.Fprintf(out, "Replayed: %d\n", summary.Replayed)
-	fmt.Fprintf(out, "Allowed: %d\n", summary.Allowed)
-	fmt.Fprintf(out, "Denied: %d\n", summary.Denied)
-	fmt.Fprintf(out, "Per-key:\n")
+// runRouteReplay is runReplay under opts.Routes, with main as the limiter of
+// routes that use the top-level limits.
+func runRouteReplay(ctx context.Context, sorted []chronorecorder.TrafficRecord, opts ReplayOptions, vc *chronoclock.VirtualClock, main limiter.Limiter, cb func(chronoreplay.Result)) (*chronoreplay.Summary, error) {
+	routes, err := newRouteReplayLimiter(opts, vc, main)
+	if err != nil {
+		return nil, fmt.Errorf("create limiter: %w", err)
+	}
 
-	keys := make([]string, 0, len(summary.PerKey))
-	for key := range summary.PerKey {
-		keys = append(keys, key)
+	// The key filter sees encoded keys, so it is applied here instead.
+	keys := make(map[string]bool, len(opts.Keys))
+	for _, key := range opts.Keys {
+		keys[key] = true
 	}
-	sort.Strings(keys)
-	for _, key := range keys {
-		ks := summary.PerKey[key]
-		fmt.Fprintf(out, "  %s: allowed=%d denied=%d\n", key, ks.Allowed, ks.Denied)
+	encoded := make([]chronorecorder.TrafficRecord, 0, len(sorted))
+	for _, rec := range sorted {
+		if len(keys) > 0 && !keys[rec.Key] {
+			continue
+		}
+		rec.Key = rec.Endpoint + routeKeySeparator + rec.Key
+		encoded = append(encoded, rec)
+	}
+
+	replayer := chronoreplay.New(routes, vc, opts.Speed, &chronoreplay.Filter{Endpoints: opts.Endpoints})
+	replayer.LoadRecords(encoded)
+	summary, err := replayer.Run(ctx, func(res chronoreplay.Result) {
+		_, res.Record.Key, _ = strings.Cut(res.Record.Key, routeKeySeparator)
+		if cb != nil {
+			cb(res)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("run replay: %w", err)
 	}
 
+	summary.TotalRecords = len(sorted)
+	perKey := make(map[string]chronoreplay.KeySummary, len(summary.PerKey))
+	for encodedKey, ks := range summary.PerKey {
+		_, key, _ := strings.Cut(encodedKey, routeKeySeparator)
+		merged := perKey[key]
+		merged.Allowed += ks.Allowed
+		merged.Denied += ks.Denied
+		perKey[key] = merged
+	}
+	summary.PerKey = perKey
 	return summary, nil
 }
+
+// routeReplayLimiter applies each endpoint's route policy to keys encoded by
+// runRouteReplay. Endpoints without a policy use the main limiter.
+type routeReplayLimiter struct {
+	main   limiter.Limiter
+	routes map[string]limiter.Limiter
+}
+
+func newRouteReplayLimiter(opts ReplayOptions, vc *chronoclock.VirtualClock, main limiter.Limiter) (*routeReplayLimiter, error) {
+	type policy struct {
+		algorithm string
+		rate      int
+		window    string
+		burst     int
+	}
+	top := policy{string(opts.Algorithm), opts.Rate, opts.Window.String(), opts.Burst}
+	shared := map[policy]limiter.Limiter{top: main}
+
+	l := &routeReplayLimiter{main: main, routes: make(map[string]limiter.Limiter, len(opts.Routes))}
+	for _, route := range opts.Routes {
+		if !route.Limited {
+			l.routes[route.Endpoint] = nil
+			continue
+		}
+		p := policy{route.Algorithm, route.Rate, route.Window, route.Burst}
+		lim, ok := shared[p]
+		if !ok {
+			window, err := time.ParseDuration(route.Window)
+			if err != nil {
+				return nil, fmt.Errorf("route %q: invalid window %q: %w", route.Endpoint, route.Window, err)
+			}
+			lim, err = NewLimiter(Config{Algorithm: limiter.Algorithm(route.Algorithm), Rate: route.Rate, Window: window, Burst: route.Burst}, vc)
+			if err != nil {
+				return nil, fmt.Errorf("route %q: %w", route.Endpoint, err)
+			}
+			shared[p] = lim
+		}
+		l.routes[route.Endpoint] = lim
+	}
+	return l, nil
+}
+
+func (l *routeReplayLimiter) Allow(ctx context.Context, encoded string) limiter.Decision {
+	endpoint, key, _ := strings.Cut(encoded, routeKeySeparator)
+	lim, ok := l.routes[endpoint]
+	if !ok {
+		lim = l.main
+	}
+	if lim == nil {
+		return limiter.Decision{Allowed: true}
+	}
+	return lim.Allow(ctx, key)
+}
+
+// newReplayLimiter builds the limiter for a replay run on the virtual clock.
+// Storage-backed runs get a fresh backend whose keys are namespaced per run, so
+// replaying against a shared Redis never collides with live or earlier state.
+func newReplayLimiter(opts ReplayOptions, vc *chronoclock.VirtualClock) (limiter.Limiter, func(), error) {
+	cfg := Config{
+		Algorithm:      opts.Algorithm,
+		Rate:           opts.Rate,
+		Window:         opts.Window,
+		Burst:          opts.Burst,
+		Addr:           ":0",
+		StorageBackend: chronostorage.BackendMemory,
+	}
+
+	backend := strings.TrimSpace(opts.StorageBackend)
+	if backend == "" {
+		lim, err := NewLimiter(cfg, vc)
+		if err != nil {
+			return nil, nil, err
+		}
+		return lim, func() {}, nil
+	}
+
+	cfg.StorageBackend = backend
+	cfg.Storage = cloneStorageConfig(opts.Storage)
+	cfg.Storage.Backend = backend
+	if cfg.Storage.Memory == nil {
+		cfg.Storage.Memory = &chronostorage.MemoryConfig{CleanupInterval: time.Minute}
+	}
+	cfg.Storage.Memory.Algorithm = string(opts.Algorithm)
+	cfg.Storage.Memory.Burst = opts.Burst
+	if cfg.Storage.CRDT == nil {
+		cfg.Storage.CRDT = &chronostorage.CRDTConfig{}
+	}
+	if cfg.Storage.CRDT.NodeID == "" {
+		cfg.Storage.CRDT.NodeID = fmt.Sprintf("chronogate-replay-%d", time.Now().UnixNano())
+	}
+	if cfg.Storage.CRDT.BindAddr == "" {
+		cfg.Storage.CRDT.BindAddr = "127.0.0.1:0"
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	lim, store, err := NewStorageBackedLimiter(cfg, vc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prefixed := &keyPrefixLimiter{
+		next:   lim,
+		prefix: fmt.Sprintf("replay:%d:", time.Now().UnixNano()),
+	}
+	return prefixed, func() { _ = store.Close() }, nil
+}
+
+// cloneStorageConfig deep-copies backend configs so clock injection for a
+// replay never leaks into the caller's (often server-wide) storage config.
+func cloneStorageConfig(in chronostorage.Config) chronostorage.Config {
+	out := in
+	if in.Memory != nil {
+		memory := *in.Memory
+		out.Memory = &memory
+	}
+	if in.Redis != nil {
+		redis := *in.Redis
+		redis.ClusterNodes = append([]string(nil), in.Redis.ClusterNodes...)
+		out.Redis = &redis
+	}
+	if in.CRDT != nil {
+		crdt := *in.CRDT
+		crdt.Peers = append([]string(nil), in.CRDT.Peers...)
+		out.CRDT = &crdt
+	}
+	return out
+}
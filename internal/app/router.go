@@ -0,0 +1,130 @@
+package app
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Router is an http.ServeMux with JSON errors. Routes are registered per
+// method; the mux does the matching, "{name}" path values, precedence of
+// static segments and path cleaning. Unknown paths get a JSON 404; known paths
+// with an unregistered method get a JSON 405 whose Allow header lists every
+// method. HEAD runs the GET handler without a body and OPTIONS answers 204
+// with Allow, unless registered.
+//
+// With a CORS policy, preflight requests are answered by the router itself,
+// before any route handler or rate limiter runs.
+type Router struct {
+	mux *http.ServeMux
+	// paths matches a request's path regardless of method, to find the
+	// methods and CORS policy of a path the mux has no handler for.
+	paths       *http.ServeMux
+	methods     map[string][]string
+	defaultCORS *CORSPolicy
+	cors        map[string]*CORSPolicy
+}
+
+// NewRouter returns an empty router.
+func NewRouter() *Router {
+	return &Router{
+		mux:     http.NewServeMux(),
+		paths:   http.NewServeMux(),
+		methods: make(map[string][]string),
+	}
+}
+
+// Handle registers h for method and pattern. Like http.ServeMux, it panics
+// on a duplicate or malformed pattern.
+func (rt *Router) Handle(method, pattern string, h http.Handler) {
+	rt.mux.Handle(method+" "+pattern, h)
+	if _, ok := rt.methods[pattern]; !ok {
+		rt.paths.Handle(pattern, http.NotFoundHandler())
+	}
+	rt.methods[pattern] = append(rt.methods[pattern], method)
+}
+
+// HandleFunc registers fn for method and pattern.
+func (rt *Router) HandleFunc(method, pattern string, fn func(http.ResponseWriter, *http.Request)) {
+	rt.Handle(method, pattern, http.HandlerFunc(fn))
+}
+
+// SetDefaultCORS sets the CORS policy of paths without their own; nil disables it.
+func (rt *Router) SetDefaultCORS(policy *CORSPolicy) {
+	rt.defaultCORS = policy
+}
+
+// SetCORS sets the CORS policy of one pattern, overriding the default.
+func (rt *Router) SetCORS(pattern string, policy *CORSPolicy) {
+	if rt.cors == nil {
+		rt.cors = make(map[string]*CORSPolicy)
+	}
+	rt.cors[pattern] = policy
+}
+
+func (rt *Router) corsPolicy(pattern string) *CORSPolicy {
+	if policy, ok := rt.cors[pattern]; ok {
+		return policy
+	}
+	return rt.defaultCORS
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_, pattern := rt.paths.Handler(r)
+	methods, known := rt.methods[pattern]
+
+	if policy := rt.corsPolicy(pattern); known && policy != nil && len(policy.AllowedOrigins) > 0 {
+		if isPreflight(r) {
+			policy.preflight(w, r, allowedMethods(methods))
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" {
+			policy.apply(w, origin)
+		}
+	}
+
+	if _, matched := rt.mux.Handler(r); matched != "" {
+		if r.Method == http.MethodHead {
+			w = headResponseWriter{w}
+		}
+		rt.mux.ServeHTTP(w, r)
+		return
+	}
+	if !known {
+		writeError(w, r, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("no route for %s", r.URL.Path))
+		return
+	}
+
+	allow := strings.Join(allowedMethods(methods), ", ")
+	w.Header().Set("Allow", allow)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeError(w, r, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed,
+		fmt.Sprintf("method %s not allowed; use %s", r.Method, allow))
+}
+
+// allowedMethods lists the registered methods plus the implicit HEAD and OPTIONS.
+func allowedMethods(registered []string) []string {
+	methods := slices.Clone(registered)
+	if slices.Contains(methods, http.MethodGet) && !slices.Contains(methods, http.MethodHead) {
+		methods = append(methods, http.MethodHead)
+	}
+	if !slices.Contains(methods, http.MethodOptions) {
+		methods = append(methods, http.MethodOptions)
+	}
+	slices.Sort(methods)
+	return methods
+}
+
+// headResponseWriter drops the body of a GET handler serving a HEAD request.
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w headResponseWriter) Write(p []byte) (int, error) {
+	return io.Discard.Write(p)
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouterMatchesMethodsAndPathParams(t *testing.T) {
+	rt := NewRouter()
+	rt.HandleFunc(http.MethodGet, "/admin/keys/{key}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"key": r.PathValue("key")})
+	})
+	rt.HandleFunc(http.MethodDelete, "/admin/keys/{key}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	rt.HandleFunc(http.MethodGet, "/admin/keys/export", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"key": "static"})
+	})
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		rt.ServeHTTP(resp, httptest.NewRequest(method, path, nil))
+		return resp
+	}
+
+	resp := serve(http.MethodGet, "/admin/keys/k-1")
+	assertStatus(t, resp, http.StatusOK)
+	var body map[string]string
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil || body["key"] != "k-1" {
+		t.Fatalf("param body = %s (err %v)", resp.Body.String(), err)
+	}
+
+	resp = serve(http.MethodGet, "/admin/keys/export")
+	if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil || body["key"] != "static" {
+		t.Fatalf("static route should win over param, got %s", resp.Body.String())
+	}
+
+	assertStatus(t, serve(http.MethodDelete, "/admin/keys/k-1"), http.StatusNoContent)
+
+	resp = serve(http.MethodHead, "/admin/keys/k-1")
+	assertStatus(t, resp, http.StatusOK)
+	if resp.Body.Len() != 0 {
+		t.Fatalf("HEAD body = %q, want empty", resp.Body.String())
+	}
+
+	resp = serve(http.MethodOptions, "/admin/keys/k-1")
+	assertStatus(t, resp, http.StatusNoContent)
+	if got := resp.Header().Get("Allow"); got != "DELETE, GET, HEAD, OPTIONS" {
+		t.Fatalf("OPTIONS Allow = %q", got)
+	}
+
+	resp = serve(http.MethodPut, "/admin/keys/k-1")
+	assertStatus(t, resp, http.StatusMethodNotAllowed)
+	if got := resp.Header().Get("Allow"); got != "DELETE, GET, HEAD, OPTIONS" {
+		t.Fatalf("405 Allow = %q", got)
+	}
+	if ct := resp.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("405 Content-Type = %q", ct)
+	}
+
+	resp = serve(http.MethodGet, "/admin//keys/k-1")
+	assertStatus(t, resp, http.StatusTemporaryRedirect)
+	if got := resp.Header().Get("Location"); got != "/admin/keys/k-1" {
+		t.Fatalf("cleaned Location = %q", got)
+	}
+
+	for _, path := range []string{"/admin/keys/", "/admin/keys", "/admin/keys/a/b", "/nope"} {
+		resp = serve(http.MethodGet, path)
+		assertStatus(t, resp, http.StatusNotFound)
+		var problem map[string]any
+		if err := json.Unmarshal(resp.Body.Bytes(), &problem); err != nil || problem["error"] != ErrCodeNotFound {
+			t.Fatalf("GET %s body = %s (err %v)", path, resp.Body.String(), err)
+		}
+	}
+}
+
+func TestRouterPanicsOnDuplicateRoute(t *testing.T) {
+	rt := NewRouter()
+	rt.HandleFunc(http.MethodGet, "/a/{id}", func(http.ResponseWriter, *http.Request) {})
+	defer func() {
+		if recover() == nil {
+			t.Fatal("duplicate route did not panic")
+		}
+	}()
+	rt.HandleFunc(http.MethodGet, "/a/{id}", func(http.ResponseWriter, *http.Request) {})
+}
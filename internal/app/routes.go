@@ -0,0 +1,222 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
+)
+
+const (
+	// RecordingScopeLimited is the recording of rate-limited routes, used for replay.
+	RecordingScopeLimited = "limited"
+	// RecordingScopeUnlimited is the separate recording of unlimited routes,
+	// used for capacity analysis.
+	RecordingScopeUnlimited = "unlimited"
+)
+
+// RouteScope says whether a gateway route is rate limited and whether its
+// traffic is recorded. Recorded unlimited routes go to the unlimited-scope
+// recording so they never mix with replayable limited traffic.
+type RouteScope struct {
+	Endpoint string `json:"endpoint"`
+	Limited  bool   `json:"limited"`
+	Recorded bool   `json:"recorded"`
+	// Algorithm pins a limited route to one algorithm; empty uses the configured one.
+	Algorithm limiter.Algorithm `json:"algorithm,omitempty"`
+	// Deny overrides the configured deny response for this route.
+	Deny *DenyOverride `json:"deny,omitempty"`
+	// CORS replaces the configured CORS policy for this route.
+	CORS *CORSPolicy `json:"cors,omitempty"`
+	// Authenticated routes require a valid API key when auth is enabled, and
+	// the key must hold every scope in Scopes.
+	Authenticated bool     `json:"authenticated"`
+	Scopes        []string `json:"scopes,omitempty"`
+	// Adaptive scales a limited route's rate with upstream health.
+	Adaptive *AdaptivePolicy `json:"adaptive,omitempty"`
+	// Priority is the route's load-shedding class; critical routes are never shed.
+	Priority Priority `json:"priority,omitempty"`
+	// Shadow is evaluated next to a limited route's limits without blocking,
+	// and can be promoted to enforcing through the admin API.
+	Shadow *LimitPolicy `json:"shadow,omitempty"`
+	// Canary is enforced for a stable percentage of a limited route's keys.
+	Canary *CanaryPolicy `json:"canary,omitempty"`
+}
+
+// DefaultRouteTable returns the built-in scope of every gateway route.
+func DefaultRouteTable() []RouteScope {
+	return []RouteScope{
+		{Endpoint: "GET /health", Priority: PriorityCritical},
+		{Endpoint: "GET /public"},
+		{Endpoint: "GET /api/profile", Limited: true, Recorded: true, Authenticated: true},
+		{Endpoint: "POST /api/orders", Limited: true, Recorded: true, Authenticated: true},
+		{Endpoint: "GET /api/token-bucket", Limited: true, Recorded: true, Authenticated: true, Algorithm: limiter.AlgorithmTokenBucket},
+		{Endpoint: "GET /api/sliding-window", Limited: true, Recorded: true, Authenticated: true, Algorithm: limiter.AlgorithmSlidingWindow},
+		{Endpoint: "GET /api/fixed-window", Limited: true, Recorded: true, Authenticated: true, Algorithm: limiter.AlgorithmFixedWindow},
+	}
+}
+
+// routeOverride is one routes-file entry; omitted fields keep the default.
+type routeOverride struct {
+	Endpoint  string        `json:"endpoint"`
+	Limited   *bool         `json:"limited"`
+	Recorded  *bool         `json:"recorded"`
+	Algorithm *string       `json:"algorithm"`
+	Deny      *DenyOverride `json:"deny"`
+	CORS      *CORSPolicy   `json:"cors"`
+
+	Authenticated *bool           `json:"authenticated"`
+	Scopes        *[]string       `json:"scopes"`
+	Adaptive      *AdaptivePolicy `json:"adaptive"`
+	Priority      *string         `json:"priority"`
+	Shadow        *LimitPolicy    `json:"shadow"`
+	Canary        *CanaryPolicy   `json:"canary"`
+}
+
+// LoadRouteTable applies the overrides in a routes JSON file to the default table.
+func LoadRouteTable(path string) ([]RouteScope, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read routes file: %w", err)
+	}
+	var overrides []routeOverride
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return nil, fmt.Errorf("decode routes file: %w", err)
+	}
+
+	table := DefaultRouteTable()
+	index := make(map[string]int, len(table))
+	for i, route := range table {
+		index[route.Endpoint] = i
+	}
+	for _, o := range overrides {
+		i, ok := index[strings.TrimSpace(o.Endpoint)]
+		if !ok {
+			return nil, fmt.Errorf("routes file: unknown route %q", o.Endpoint)
+		}
+		if o.Limited != nil {
+			table[i].Limited = *o.Limited
+		}
+		if o.Recorded != nil {
+			table[i].Recorded = *o.Recorded
+		}
+		if o.Algorithm != nil {
+			table[i].Algorithm = limiter.Algorithm(strings.TrimSpace(*o.Algorithm))
+		}
+		if o.Deny != nil {
+			table[i].Deny = o.Deny
+		}
+		if o.CORS != nil {
+			table[i].CORS = o.CORS
+		}
+		if o.Authenticated != nil {
+			table[i].Authenticated = *o.Authenticated
+		}
+		if o.Scopes != nil {
+			table[i].Scopes = *o.Scopes
+		}
+		if o.Adaptive != nil {
+			table[i].Adaptive = o.Adaptive
+		}
+		if o.Priority != nil {
+			table[i].Priority = Priority(strings.TrimSpace(*o.Priority))
+		}
+		if o.Shadow != nil {
+			table[i].Shadow = o.Shadow
+		}
+		if o.Canary != nil {
+			table[i].Canary = o.Canary
+		}
+	}
+	return table, nil
+}
+
+// RouteTable returns the configured route table merged over the default:
+// routes that Routes does not list keep their default scope.
+func (c Config) RouteTable() []RouteScope {
+	table := DefaultRouteTable()
+	if len(c.Routes) == 0 {
+		return table
+	}
+	index := make(map[string]int, len(table))
+	for i, route := range table {
+		index[route.Endpoint] = i
+	}
+	for _, route := range c.Routes {
+		if i, ok := index[route.Endpoint]; ok {
+			table[i] = route
+		}
+	}
+	return table
+}
+
+func validateRouteTable(routes []RouteScope, deny DenyResponse) error {
+	known := make(map[string]bool)
+	for _, route := range DefaultRouteTable() {
+		known[route.Endpoint] = true
+	}
+	seen := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		if !known[route.Endpoint] {
+			return fmt.Errorf("unknown route %q", route.Endpoint)
+		}
+		if seen[route.Endpoint] {
+			return fmt.Errorf("duplicate route %q", route.Endpoint)
+		}
+		seen[route.Endpoint] = true
+		if route.Algorithm != "" {
+			if _, err := ParseAlgorithm(string(route.Algorithm)); err != nil {
+				return fmt.Errorf("route %q: %w", route.Endpoint, err)
+			}
+		}
+		if err := deny.With(route.Deny).Validate(); err != nil {
+			return fmt.Errorf("route %q: %w", route.Endpoint, err)
+		}
+		if route.Adaptive != nil {
+			if !route.Limited {
+				return fmt.Errorf("route %q: adaptive limits need a limited route", route.Endpoint)
+			}
+			if err := route.Adaptive.Validate(); err != nil {
+				return fmt.Errorf("route %q: %w", route.Endpoint, err)
+			}
+		}
+		if route.Shadow != nil {
+			if !route.Limited {
+				return fmt.Errorf("route %q: shadow policies need a limited route", route.Endpoint)
+			}
+			if err := route.Shadow.Validate(); err != nil {
+				return fmt.Errorf("route %q: shadow: %w", route.Endpoint, err)
+			}
+		}
+		if route.Canary != nil {
+			if !route.Limited {
+				return fmt.Errorf("route %q: canary policies need a limited route", route.Endpoint)
+			}
+			if err := route.Canary.Validate(); err != nil {
+				return fmt.Errorf("route %q: canary: %w", route.Endpoint, err)
+			}
+		}
+		if route.Priority != "" {
+			if _, err := ParsePriority(string(route.Priority)); err != nil {
+				return fmt.Errorf("route %q: %w", route.Endpoint, err)
+			}
+		}
+		if err := route.CORS.Validate(); err != nil {
+			return fmt.Errorf("route %q: %w", route.Endpoint, err)
+		}
+	}
+	return nil
+}
+
+// recordedEndpoints lists the endpoints of every recorded route.
+func recordedEndpoints(routes []RouteScope) []string {
+	var out []string
+	for _, route := range routes {
+		if route.Recorded {
+			out = append(out, route.Endpoint)
+		}
+	}
+	return out
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+)
+
+func TestLoadRouteTableAppliesOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.json")
+	if err := os.WriteFile(path, []byte(`[
+		{"endpoint": "GET /public", "recorded": true},
+		{"endpoint": "POST /api/orders", "limited": false}
+	]`), 0o644); err != nil {
+		t.Fatalf("write routes file: %v", err)
+	}
+
+	table, err := LoadRouteTable(path)
+	if err != nil {
+		t.Fatalf("LoadRouteTable() error = %v", err)
+	}
+	routes := make(map[string]RouteScope, len(table))
+	for _, route := range table {
+		routes[route.Endpoint] = route
+	}
+	if r := routes["GET /public"]; r.Limited || !r.Recorded {
+		t.Fatalf("GET /public = %+v, want recorded and unlimited", r)
+	}
+	if r := routes["POST /api/orders"]; r.Limited || !r.Recorded {
+		t.Fatalf("POST /api/orders = %+v, want recorded and unlimited", r)
+	}
+	if r := routes["GET /health"]; r.Limited || r.Recorded {
+		t.Fatalf("GET /health = %+v, want untouched default", r)
+	}
+
+	if err := os.WriteFile(path, []byte(`[{"endpoint": "GET /nope", "recorded": true}]`), 0o644); err != nil {
+		t.Fatalf("write routes file: %v", err)
+	}
+	if _, err := LoadRouteTable(path); err == nil || !strings.Contains(err.Error(), "unknown route") {
+		t.Fatalf("LoadRouteTable() error = %v, want unknown route", err)
+	}
+}
+
+func TestRouteTableScopesRecording(t *testing.T) {
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 2, 8, 15, 0, 0, 0, time.UTC))
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.Rate = 1
+	cfg.Routes = DefaultRouteTable()
+	for i := range cfg.Routes {
+		if cfg.Routes[i].Endpoint == "GET /public" {
+			cfg.Routes[i].Recorded = true
+		}
+	}
+
+	mainLimiter, mainStorage, err := NewStorageBackedLimiter(cfg, vc)
+	if err != nil {
+		t.Fatalf("NewStorageBackedLimiter() error = %v", err)
+	}
+	defer mainStorage.Close()
+
+	storageSet, cleanup := newMemoryOnlyStorageSet(t, cfg, vc)
+	defer cleanup()
+
+	handler := NewHandler(cfg, mainLimiter, vc, chronorecorder.New(nil), storageSet)
+
+	for i := 0; i < 3; i++ {
+		assertStatus(t, executeRequest(handler, http.MethodGet, "/health", "", "", "", "198.51.100.50:8080"), http.StatusOK)
+		assertStatus(t, executeRequest(handler, http.MethodGet, "/public", "", "", "", "198.51.100.50:8080"), http.StatusOK)
+	}
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/api/profile", "scope-key", "", "", "198.51.100.50:8080"), http.StatusOK)
+
+	export := func(query string) RecordingFile {
+		t.Helper()
+		resp := executeRequest(handler, http.MethodGet, "/api/recordings/export"+query, "", "", "", "198.51.100.50:8080")
+		assertStatus(t, resp, http.StatusOK)
+		var file RecordingFile
+		if err := json.Unmarshal(resp.Body.Bytes(), &file); err != nil {
+			t.Fatalf("decode export: %v", err)
+		}
+		return file
+	}
+
+	limited := export("")
+	if limited.Scope != RecordingScopeLimited || len(limited.Records) != 1 || limited.Records[0].Endpoint != "GET /api/profile" {
+		t.Fatalf("limited export = scope %q, records %+v; want only GET /api/profile", limited.Scope, limited.Records)
+	}
+
+	unlimited := export("?scope=unlimited")
+	if unlimited.Scope != RecordingScopeUnlimited || len(unlimited.Records) != 3 {
+		t.Fatalf("unlimited export = scope %q, %d records; want 3 GET /public", unlimited.Scope, len(unlimited.Records))
+	}
+	for _, rec := range unlimited.Records {
+		if rec.Endpoint != "GET /public" {
+			t.Fatalf("unlimited export contains %q, want only GET /public", rec.Endpoint)
+		}
+	}
+
+	var public *RoutePolicy
+	for i, route := range limited.Config.Routes {
+		if route.Endpoint == "GET /public" {
+			public = &limited.Config.Routes[i]
+		}
+	}
+	if public == nil || public.Limited || public.Algorithm != "" {
+		t.Fatalf("GET /public policy = %+v, want recorded unlimited route", public)
+	}
+
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/api/recordings/export?scope=all", "", "", "", "198.51.100.50:8080"), http.StatusBadRequest)
+
+	resp := executeRequest(handler, http.MethodPost, "/api/record/stop", "", "", "", "198.51.100.50:8080")
+	assertStatus(t, resp, http.StatusOK)
+	var stop struct {
+		UnlimitedCount int           `json:"unlimited_count"`
+		Unlimited      RecordingFile `json:"unlimited"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &stop); err != nil {
+		t.Fatalf("decode stop: %v", err)
+	}
+	if stop.UnlimitedCount != 3 || stop.Unlimited.Scope != RecordingScopeUnlimited || len(stop.Unlimited.Records) != 3 {
+		t.Fatalf("stop unlimited = count %d, scope %q, %d records; want 3", stop.UnlimitedCount, stop.Unlimited.Scope, len(stop.Unlimited.Records))
+	}
+}
+
+func TestPartialRouteTableKeepsDefaults(t *testing.T) {
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.Routes = []RouteScope{{Endpoint: "GET /public", Recorded: true}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	table := cfg.RouteTable()
+	if len(table) != len(DefaultRouteTable()) {
+		t.Fatalf("RouteTable() has %d routes, want %d", len(table), len(DefaultRouteTable()))
+	}
+	for _, route := range table {
+		switch route.Endpoint {
+		case "GET /public":
+			if !route.Recorded {
+				t.Fatalf("GET /public = %+v, want recorded", route)
+			}
+		case "GET /api/profile":
+			if !route.Limited || !route.Recorded {
+				t.Fatalf("GET /api/profile = %+v, want the default limited route", route)
+			}
+		}
+	}
+}
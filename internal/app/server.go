@@ -3,7 +3,9 @@ package app
 import (
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
@@ -20,192 +22,508 @@ func NewHandler(
 	rec *chronorecorder.Recorder,
 	storageSet *StorageLimiterSet,
 ) http.Handler {
-	recordingState := NewRecordingState(rec, true)
-	replayState := NewReplayState()
+	replayState := NewReplayState(newReplayStore(cfg))
+	tenants := newTenantScopes(cfg, clk, &tenantScope{
+		limitedRec:   NewRecordingState(rec, true),
+		unlimitedRec: NewRecordingState(nil, true),
+		replayState:  replayState,
+		replayJobs:   NewReplayJobManager(cfg.ReplayWorkers, replayState, clk),
+	})
 	storageDemoStore := chronokv.NewMemoryStorage(clk)
 
 	if storageSet == nil {
 		storageSet = NewStorageLimiterSet(cfg, clk)
 	}
 
-	tokenCfg := cfg
-	tokenCfg.Algorithm = limiter.AlgorithmTokenBucket
-	tokenLimiter, _ := NewLimiter(tokenCfg, clk)
+	limiters := newRouteLimiters(cfg, clk, mainLimiter, storageSet)
 
-	slidingCfg := cfg
-	slidingCfg.Algorithm = limiter.AlgorithmSlidingWindow
-	slidingLimiter, _ := NewLimiter(slidingCfg, clk)
+	routeHandlers := map[string]func(http.ResponseWriter, *http.Request){
+		// Validates: pkg/config + general runtime health path
+		"GET /health": func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		},
 
-	fixedCfg := cfg
-	fixedCfg.Algorithm = limiter.AlgorithmFixedWindow
-	fixedLimiter, _ := NewLimiter(fixedCfg, clk)
+		// Validates: unrestricted public route behavior in a Chrono consumer app
+		"GET /public": func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, http.StatusOK, map[string]string{
+				"service": "chronogate",
+				"message": "public endpoint",
+			})
+		},
 
-	mux := http.NewServeMux()
+		// Validates: pkg/limiter + pkg/storage via selected backend StorageLimiter
+		"GET /api/profile": func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, http.StatusOK, map[string]string{
+				"id":   "demo-user",
+				"name": "Chrono Demo",
+			})
+		},
 
-	// Validates: pkg/config + general runtime health path
-	mux.HandleFunc("/health", methodHandler(http.MethodGet, func(w http.ResponseWriter, _ *http.Request) {
-		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
-	}))
+		// Validates: pkg/limiter + pkg/storage deny path under write route
+		"POST /api/orders": func(w http.ResponseWriter, _ *http.Request) {
+			orderID := fmt.Sprintf("ord_%d", clk.Now().UnixNano())
+			writeJSON(w, http.StatusCreated, map[string]string{
+				"order_id": orderID,
+				"status":   "created",
+			})
+		},
 
-	// Validates: unrestricted public route behavior in a Chrono consumer app
-	mux.HandleFunc("/public", methodHandler(http.MethodGet, func(w http.ResponseWriter, _ *http.Request) {
-		writeJSON(w, http.StatusOK, map[string]string{
-			"service": "chronogate",
-			"message": "public endpoint",
-		})
-	}))
+		// Validates: pkg/limiter.NewTokenBucket
+		"GET /api/token-bucket": func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, http.StatusOK, map[string]string{"algorithm": string(limiter.AlgorithmTokenBucket), "status": "allowed"})
+		},
 
-	// Validates: pkg/limiter + pkg/storage via selected backend StorageLimiter
-	mux.Handle("/api/profile", wrapRecordedLimit(mainLimiter, clk, recordingState, http.HandlerFunc(methodHandler(http.MethodGet, func(w http.ResponseWriter, _ *http.Request) {
-		writeJSON(w, http.StatusOK, map[string]string{
-			"id":   "demo-user",
-			"name": "Chrono Demo",
-		})
-	}))))
-
-	// Validates: pkg/limiter + pkg/storage deny path under write route
-	mux.Handle("/api/orders", wrapRecordedLimit(mainLimiter, clk, recordingState, http.HandlerFunc(methodHandler(http.MethodPost, func(w http.ResponseWriter, _ *http.Request) {
-		orderID := fmt.Sprintf("ord_%d", clk.Now().UnixNano())
-		writeJSON(w, http.StatusCreated, map[string]string{
-			"order_id": orderID,
-			"status":   "created",
-		})
-	}))))
+		// Validates: pkg/limiter.NewSlidingWindow
+		"GET /api/sliding-window": func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, http.StatusOK, map[string]string{"algorithm": string(limiter.AlgorithmSlidingWindow), "status": "allowed"})
+		},
 
-	// Validates: pkg/limiter.NewTokenBucket
-	mux.Handle("/api/token-bucket", wrapRecordedLimit(tokenLimiter, clk, recordingState, http.HandlerFunc(methodHandler(http.MethodGet, func(w http.ResponseWriter, _ *http.Request) {
-		writeJSON(w, http.StatusOK, map[string]string{"algorithm": string(limiter.AlgorithmTokenBucket), "status": "allowed"})
-	}))))
+		// Validates: pkg/limiter.NewFixedWindow
+		"GET /api/fixed-window": func(w http.ResponseWriter, _ *http.Request) {
+			writeJSON(w, http.StatusOK, map[string]string{"algorithm": string(limiter.AlgorithmFixedWindow), "status": "allowed"})
+		},
+	}
 
-	// Validates: pkg/limiter.NewSlidingWindow
-	mux.Handle("/api/sliding-window", wrapRecordedLimit(slidingLimiter, clk, recordingState, http.HandlerFunc(methodHandler(http.MethodGet, func(w http.ResponseWriter, _ *http.Request) {
-		writeJSON(w, http.StatusOK, map[string]string{"algorithm": string(limiter.AlgorithmSlidingWindow), "status": "allowed"})
-	}))))
+	auth := newAuthenticator(cfg, clk)
 
-	// Validates: pkg/limiter.NewFixedWindow
-	mux.Handle("/api/fixed-window", wrapRecordedLimit(fixedLimiter, clk, recordingState, http.HandlerFunc(methodHandler(http.MethodGet, func(w http.ResponseWriter, _ *http.Request) {
-		writeJSON(w, http.StatusOK, map[string]string{"algorithm": string(limiter.AlgorithmFixedWindow), "status": "allowed"})
-	}))))
+	ipFilter, err := NewIPFilter(cfg.IPRules, cfg.IPRulesFile)
+	if err != nil {
+		// Config.Validate rejects bad lists; fall back to no static rules.
+		log.Printf("ip rules: %v", err)
+		ipFilter, _ = NewIPFilter(IPRules{}, cfg.IPRulesFile)
+	}
+
+	router := NewRouter()
+	router.SetDefaultCORS(cfg.CORS)
+
+	stack := routeStack{
+		limiters: limiters,
+		shedder:  NewShedder(cfg.Shed, clk),
+		quotas:   newQuotas(cfg.Quota, clk, storageSet.Quotas, storageSet.QuotaErr),
+		policies: newRoutePolicies(cfg, clk, limiters),
+		usage:    newUsage(cfg.Usage, clk, storageSet.Usage, storageSet.UsageErr),
+		clk:      clk,
+		auth:     auth,
+		tenants:  tenants,
+	}
+
+	// Endpoints outside the route table are shed at normal priority. They
+	// run no auth of their own, so only a valid X-API-Key resolved by
+	// Identify lifts them to its tier; bearer tokens are not verified here.
+	// Operational endpoints check the admin scope before shedding.
+	handle := func(method, pattern string, fn func(http.ResponseWriter, *http.Request)) {
+		router.Handle(method, pattern, stack.shed(http.HandlerFunc(fn)))
+	}
+	// Operational endpoints (recording, replay, history, limits and storage
+	// writes) need the admin scope when auth is on.
+	handleOperational := func(method, pattern string, fn func(http.ResponseWriter, *http.Request)) {
+		h := stack.shed(http.HandlerFunc(fn))
+		if auth != nil {
+			h = auth.Middleware([]string{ScopeAdmin})(h)
+		}
+		router.Handle(method, pattern, h)
+	}
+
+	// Validates: routing table deciding which routes are limited and recorded
+	adaptive := make(map[string]*adaptiveController)
+	for _, route := range cfg.RouteTable() {
+		method, path, _ := splitEndpoint(route.Endpoint)
+		handler := http.HandlerFunc(routeHandlers[route.Endpoint])
+		if route.CORS != nil {
+			router.SetCORS(path, route.CORS)
+		}
+		if route.Limited && route.Adaptive != nil {
+			adaptive[route.Endpoint] = newAdaptiveController(*route.Adaptive, cfg.Rate, clk)
+		}
+		router.Handle(method, path, stack.wrap(route, cfg.Deny.With(route.Deny), adaptive[route.Endpoint], handler))
+	}
+
+	// Validates: effective limits, including adaptive ones driven by upstream health
+	handleOperational(http.MethodGet, "/api/limits", limitsHandler(cfg, adaptive, stack.shedder))
+
+	// Validates: calendar-aligned daily and monthly quotas per key
+	if stack.quotas != nil {
+		quota := stack.shed(http.HandlerFunc(quotaHandler(stack.quotas, auth)))
+		if auth != nil {
+			quota = auth.Middleware(nil)(quota)
+		}
+		router.Handle(http.MethodGet, "/api/quota", quota)
+	}
+
+	// Validates: hourly usage rollups per key and route for billing
+	if stack.usage != nil {
+		usage := stack.shed(http.HandlerFunc(usageHandler(stack.usage, auth != nil)))
+		if auth != nil {
+			usage = auth.Middleware(nil)(usage)
+		}
+		router.Handle(http.MethodGet, "/api/usage", usage)
+	}
+
+	// Validates: API key management (hashed at rest, admin scope required)
+	if auth != nil {
+		requireAdmin := auth.Middleware([]string{ScopeAdmin})
+		admin := func(h http.Handler) http.Handler { return requireAdmin(stack.shed(h)) }
+		store := auth.Store()
+		router.Handle(http.MethodGet, "/admin/keys", admin(http.HandlerFunc(apiKeyListHandler(store))))
+		router.Handle(http.MethodPost, "/admin/keys", admin(http.HandlerFunc(apiKeyCreateHandler(store, clk.Now))))
+		router.Handle(http.MethodGet, "/admin/keys/{id}", admin(http.HandlerFunc(apiKeyGetHandler(store))))
+		router.Handle(http.MethodDelete, "/admin/keys/{id}", admin(http.HandlerFunc(apiKeyRevokeHandler(store))))
+
+		// Validates: runtime-reloadable CIDR allow, deny and exempt lists
+		router.Handle(http.MethodGet, "/admin/ip-rules", admin(http.HandlerFunc(ipRulesGetHandler(ipFilter))))
+		router.Handle(http.MethodPut, "/admin/ip-rules", admin(http.HandlerFunc(ipRulesPutHandler(ipFilter))))
+
+		// Validates: shadow policies evaluated without blocking, then promoted
+		router.Handle(http.MethodGet, "/admin/shadow", admin(http.HandlerFunc(routePoliciesHandler(stack.policies))))
+		router.Handle(http.MethodPut, "/admin/shadow", admin(http.HandlerFunc(shadowPutHandler(stack.policies))))
+		router.Handle(http.MethodDelete, "/admin/shadow", admin(http.HandlerFunc(shadowDeleteHandler(stack.policies))))
+		router.Handle(http.MethodPost, "/admin/shadow/promote", admin(http.HandlerFunc(shadowPromoteHandler(stack.policies))))
+
+		// Validates: canary policies enforced for a stable percentage of keys
+		router.Handle(http.MethodGet, "/admin/canary", admin(http.HandlerFunc(routePoliciesHandler(stack.policies))))
+		router.Handle(http.MethodPut, "/admin/canary", admin(http.HandlerFunc(canaryPutHandler(stack.policies))))
+		router.Handle(http.MethodPatch, "/admin/canary", admin(http.HandlerFunc(canaryPatchHandler(stack.policies))))
+		router.Handle(http.MethodDelete, "/admin/canary", admin(http.HandlerFunc(canaryDeleteHandler(stack.policies))))
+		router.Handle(http.MethodPost, "/admin/canary/promote", admin(http.HandlerFunc(canaryPromoteHandler(stack.policies))))
+	}
 
 	// Validates: pkg/storage memory backend + pkg/limiter.StorageLimiter
-	mux.HandleFunc("/api/storage/memory", methodHandler(http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+	handle(http.MethodGet, "/api/storage/memory", func(w http.ResponseWriter, r *http.Request) {
 		serveStorageDecision(w, r, clk, "memory", storageSet.Memory, nil, "")
-	}))
+	})
 
 	// Validates: pkg/storage redis backend + pkg/limiter.StorageLimiter
-	mux.HandleFunc("/api/storage/redis", methodHandler(http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+	handle(http.MethodGet, "/api/storage/redis", func(w http.ResponseWriter, r *http.Request) {
 		serveStorageDecision(w, r, clk, "redis", storageSet.Redis, storageSet.RedisErr, "")
-	}))
+	})
 
 	// Validates: pkg/storage CRDT backend + pkg/limiter.StorageLimiter
-	mux.HandleFunc("/api/storage/crdt", methodHandler(http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+	handle(http.MethodGet, "/api/storage/crdt", func(w http.ResponseWriter, r *http.Request) {
 		serveStorageDecision(w, r, clk, "crdt", storageSet.CRDT, storageSet.CRDTErr, "⚠️ EXPERIMENTAL - eventual consistency may cause minor discrepancies")
-	}))
+	})
 
 	// Validates: side-by-side backend behavior comparison (memory vs redis vs crdt)
-	mux.HandleFunc("/api/storage/compare", methodHandler(http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+	handle(http.MethodGet, "/api/storage/compare", func(w http.ResponseWriter, r *http.Request) {
 		serveStorageCompare(w, r, clk, storageSet)
-	}))
+	})
 
 	// Validates: pkg/storage MemoryStorage read/write/increment/expiry behavior
-	mux.HandleFunc("/api/storage/demo", storageDemoHandler(storageDemoStore))
+	handle(http.MethodGet, "/api/storage/demo", storageReadHandler(storageDemoStore))
+	handleOperational(http.MethodPut, "/api/storage/demo", storageWriteHandler(storageDemoStore))
+	handleOperational(http.MethodPost, "/api/storage/demo", storageIncrementHandler(storageDemoStore))
 
 	// Validates: pkg/recorder recording lifecycle control
-	mux.HandleFunc("/api/record/start", methodHandler(http.MethodPost, func(w http.ResponseWriter, _ *http.Request) {
-		recordingState.Start()
+	handleOperational(http.MethodPost, "/api/record/start", func(w http.ResponseWriter, r *http.Request) {
+		recordings := tenants.forRequest(r)
+		recordings.limitedRec.Start()
+		recordings.unlimitedRec.Start()
 		writeJSON(w, http.StatusOK, map[string]any{
-			"recording": recordingState.IsEnabled(),
-			"count":     recordingState.Len(),
+			"recording": recordings.limitedRec.IsEnabled(),
+			"count":     recordings.limitedRec.Len(),
 		})
-	}))
+	})
 
-	// Validates: pkg/recorder export as JSON at stop time
-	mux.HandleFunc("/api/record/stop", methodHandler(http.MethodPost, func(w http.ResponseWriter, _ *http.Request) {
-		records := recordingState.Stop()
-		writeJSON(w, http.StatusOK, map[string]any{
-			"recording": false,
-			"count":     len(records),
-			"records":   records,
+	// Validates: pkg/recorder export as JSON at stop time, in the recording
+	// envelope so the response can be saved and replayed as-is; the capacity
+	// recording of unlimited routes comes back under "unlimited"
+	handleOperational(http.MethodPost, "/api/record/stop", func(w http.ResponseWriter, r *http.Request) {
+		recordings := tenants.forRequest(r)
+		records := recordings.limitedRec.Stop()
+		unlimited := recordings.unlimitedRec.Stop()
+		now := clk.Now()
+		recCfg := tenantConfig(r, cfg)
+		writeJSON(w, http.StatusOK, struct {
+			Recording      bool          `json:"recording"`
+			Count          int           `json:"count"`
+			UnlimitedCount int           `json:"unlimited_count"`
+			Unlimited      RecordingFile `json:"unlimited"`
+			RecordingFile
+		}{
+			Recording:      false,
+			Count:          len(records),
+			UnlimitedCount: len(unlimited),
+			Unlimited:      NewRecordingFile(unlimited, recCfg, RecordingScopeUnlimited, now),
+			RecordingFile:  NewRecordingFile(records, recCfg, RecordingScopeLimited, now),
 		})
-	}))
+	})
 
 	// Validates: pkg/replay.Replayer + pkg/replay.Filter + pkg/replay.Summary
-	mux.HandleFunc("/api/replay", methodHandler(http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
-		opts, records, err := parseReplayRequest(r, cfg)
+	// (?async=true runs it as a replay job instead)
+	handleOperational(http.MethodPost, "/api/replay", func(w http.ResponseWriter, r *http.Request) {
+		async := false
+		if raw := r.URL.Query().Get("async"); raw != "" {
+			parsed, err := strconv.ParseBool(raw)
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("invalid async %q", raw))
+				return
+			}
+			async = parsed
+		}
+		opts, records, warnings, err := parseReplayRequest(r, cfg)
 		if err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{
-				"error":   "invalid_replay_request",
-				"message": err.Error(),
-			})
+			writeReplayRequestError(w, r, err)
+			return
+		}
+		if async {
+			submitReplayJob(w, r, tenants.forRequest(r).replayJobs, records, opts, warnings)
+			return
+		}
+
+		if len(opts.Compare) > 0 {
+			comparison, err := RunReplayComparison(r.Context(), records, opts, io.Discard)
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, ErrCodeReplayFailed, err.Error())
+				return
+			}
+			ids := saveComparisonRuns(tenants.forRequest(r).replayState, records, comparison, clk)
+			writeJSON(w, http.StatusOK, withReplayWarnings(map[string]any{
+				"ids":        ids,
+				"comparison": comparison,
+			}, warnings))
+			return
+		}
+
+		if opts.TimelineBucket > 0 {
+			summary, timeline, err := RunReplayTimeline(r.Context(), records, opts, io.Discard)
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, ErrCodeReplayFailed, err.Error())
+				return
+			}
+
+			runID := saveReplayRun(tenants.forRequest(r).replayState, records, opts, summary, clk)
+			if opts.TimelineFormat == TimelineFormatCSV {
+				if runID != "" {
+					w.Header().Set("X-Replay-ID", runID)
+				}
+				if len(warnings) > 0 {
+					w.Header().Set("X-Replay-Warnings", strconv.Itoa(len(warnings)))
+				}
+				w.Header().Set("Content-Type", "text/csv")
+				if err := WriteTimeline(w, timeline, TimelineFormatCSV); err != nil {
+					log.Printf("write replay timeline: %v", err)
+				}
+				return
+			}
+			writeJSON(w, http.StatusOK, withReplayWarnings(map[string]any{
+				"id":       runID,
+				"summary":  summary,
+				"timeline": timeline,
+			}, warnings))
 			return
 		}
 
 		summary, err := RunReplayRecords(r.Context(), records, opts, io.Discard)
 		if err != nil {
-			writeJSON(w, http.StatusBadRequest, map[string]string{
-				"error":   "replay_failed",
-				"message": err.Error(),
-			})
+			writeError(w, r, http.StatusBadRequest, ErrCodeReplayFailed, err.Error())
 			return
 		}
 
-		replayState.Set(summary)
-		writeJSON(w, http.StatusOK, map[string]any{
+		runID := saveReplayRun(tenants.forRequest(r).replayState, records, opts, summary, clk)
+		writeJSON(w, http.StatusOK, withReplayWarnings(map[string]any{
+			"id":      runID,
 			"summary": summary,
-		})
-	}))
+		}, warnings))
+	})
 
 	// Validates: replay summary caching in ChronoGate validator flow
-	mux.HandleFunc("/api/replay/last", methodHandler(http.MethodGet, func(w http.ResponseWriter, _ *http.Request) {
-		summary, ok := replayState.Get()
+	handleOperational(http.MethodGet, "/api/replay/last", func(w http.ResponseWriter, r *http.Request) {
+		run, ok, err := tenants.forRequest(r).replayState.Last()
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, ErrCodeReplayHistoryFailed, err.Error())
+			return
+		}
 		if !ok {
-			writeJSON(w, http.StatusNotFound, map[string]string{
-				"error":   "not_found",
-				"message": "no replay has been run yet",
-			})
+			writeError(w, r, http.StatusNotFound, ErrCodeNotFound, "no replay has been run yet")
 			return
 		}
-		writeJSON(w, http.StatusOK, map[string]any{"summary": summary})
+		writeJSON(w, http.StatusOK, map[string]any{"id": run.ID, "summary": run.Summary})
+	})
+
+	// Validates: asynchronous replay jobs with progress and cancellation
+	// (per tenant, like replay history and recordings)
+	perTenant := func(handler func(*tenantScope) func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			handler(tenants.forRequest(r))(w, r)
+		}
+	}
+	handleOperational(http.MethodGet, "/api/replay/jobs", perTenant(func(s *tenantScope) func(http.ResponseWriter, *http.Request) {
+		return replayJobListHandler(s.replayJobs)
+	}))
+	handleOperational(http.MethodPost, "/api/replay/jobs", perTenant(func(s *tenantScope) func(http.ResponseWriter, *http.Request) {
+		return replayJobSubmitHandler(s.replayJobs, cfg)
+	}))
+	handleOperational(http.MethodGet, "/api/replay/jobs/{id}", perTenant(func(s *tenantScope) func(http.ResponseWriter, *http.Request) {
+		return replayJobGetHandler(s.replayJobs)
+	}))
+	handleOperational(http.MethodDelete, "/api/replay/jobs/{id}", perTenant(func(s *tenantScope) func(http.ResponseWriter, *http.Request) {
+		return replayJobCancelHandler(s.replayJobs)
+	}))
+
+	// Validates: persisted replay history (list, fetch by ID, delete)
+	handleOperational(http.MethodGet, "/api/replays", perTenant(func(s *tenantScope) func(http.ResponseWriter, *http.Request) {
+		return replayHistoryListHandler(s.replayState)
+	}))
+	handleOperational(http.MethodGet, "/api/replays/{id}", perTenant(func(s *tenantScope) func(http.ResponseWriter, *http.Request) {
+		return replayHistoryGetHandler(s.replayState)
+	}))
+	handleOperational(http.MethodDelete, "/api/replays/{id}", perTenant(func(s *tenantScope) func(http.ResponseWriter, *http.Request) {
+		return replayHistoryDeleteHandler(s.replayState)
 	}))
 
-	// Validates: pkg/recorder export of recorded request traffic
-	mux.HandleFunc("/api/recordings/export", methodHandler(http.MethodGet, func(w http.ResponseWriter, _ *http.Request) {
+	// Validates: pkg/recorder export wrapped in the versioned recording envelope
+	// (?format=array keeps the legacy bare array; ?scope=unlimited exports the
+	// capacity recording of unlimited routes)
+	handleOperational(http.MethodGet, "/api/recordings/export", func(w http.ResponseWriter, r *http.Request) {
+		scope := r.URL.Query().Get("scope")
+		recordings := tenants.forRequest(r)
+		state := recordings.limitedRec
+		switch scope {
+		case "", RecordingScopeLimited:
+			scope = RecordingScopeLimited
+		case RecordingScopeUnlimited:
+			state = recordings.unlimitedRec
+		default:
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidScope, fmt.Sprintf("scope must be %s or %s", RecordingScopeLimited, RecordingScopeUnlimited))
+			return
+		}
+
 		w.Header().Set("Content-Type", "application/json")
-		if err := recordingState.ExportJSON(w); err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{
-				"error":   "export_failed",
-				"message": err.Error(),
-			})
+		if r.URL.Query().Get("format") == "array" {
+			if err := state.ExportJSON(w); err != nil {
+				writeError(w, r, http.StatusInternalServerError, ErrCodeExportFailed, err.Error())
+			}
+			return
 		}
-	}))
+		file := NewRecordingFile(state.Records(), tenantConfig(r, cfg), scope, clk.Now())
+		if err := WriteRecordingFile(w, file); err != nil {
+			writeError(w, r, http.StatusInternalServerError, ErrCodeExportFailed, err.Error())
+		}
+	})
 
-	return mux
+	// Denied addresses are rejected before auth, recording and limiting; the
+	// tenant is resolved first so its path prefix is stripped before routing.
+	// Config.Validate rejects bad proxy entries.
+	var handler http.Handler = router
+	if auth != nil {
+		handler = auth.Identify(handler)
+	}
+	trusted, _ := parseIPPrefixes("trusted proxy", cfg.TrustedProxies)
+	return trustedProxyMiddleware(trusted, tenantMiddleware(cfg.Tenants, ipKeyMiddleware(cfg.IPKeyPrefixes, ipFilter.Middleware(handler))))
 }
 
-func wrapRecordedLimit(lim limiter.Limiter, clk chronoclock.Clock, state *RecordingState, next http.Handler) http.Handler {
-	if lim == nil {
-		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-			writeJSON(w, http.StatusServiceUnavailable, map[string]string{
-				"error":   "limiter_unavailable",
-				"message": "limiter is not configured",
-			})
-		})
+// shed applies the shedder, if any, at the default priority.
+func (s routeStack) shed(next http.Handler) http.Handler {
+	if s.shedder == nil {
+		return next
 	}
-	return RecordingMiddleware(state, clk)(RateLimitMiddleware(lim, clk)(next))
+	return s.shedder.Middleware("")(next)
+}
+
+// routeStack is the middleware applied to every route-table route.
+type routeStack struct {
+	limiters *routeLimiters
+	shedder  *Shedder
+	quotas   *Quotas
+	policies *routePolicies
+	usage    *Usage
+	clk      chronoclock.Clock
+	auth     *Authenticator
+	tenants  *tenantScopes
 }
 
-func methodHandler(method string, next func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
-	return func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != method {
-			w.Header().Set("Allow", method)
-			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{
-				"error":   "method_not_allowed",
-				"message": "method not allowed",
+// wrap applies a route's scope: limited routes go through the rate limiter,
+// and recorded routes are captured into the limited recording or, for
+// unlimited routes, the separate capacity recording. deny is the route's
+// effective deny response. With auth, authenticated routes check the API key
+// or bearer token first, so rejected requests are neither recorded nor rate
+// limited; limits selected by token claims replace the route's limits. With
+// an adaptive controller, the limit is scaled to the route's effective rate
+// and the responses of admitted requests feed its health. With a shedder,
+// requests are shed by priority after auth and before recording, so shed
+// requests spend no budget. With quotas, requests the rate limiter allowed
+// are counted against the key's daily and monthly quotas. With usage
+// accounting, every request past the shedder is counted per key, route and
+// hour as allowed or denied by the rate limiter and quotas. A route's shadow
+// policy sees the same keys as its limiter, and a promoted policy replaces
+// the route's configured limits.
+func (s routeStack) wrap(route RouteScope, deny DenyResponse, adaptive *adaptiveController, next http.Handler) http.Handler {
+	if route.Limited {
+		var ceiling *PlanLimits
+		if adaptive != nil && adaptive.policy.Ceiling != s.limiters.cfg.Rate {
+			ceiling = &PlanLimits{Rate: adaptive.policy.Ceiling}
+		}
+		base := s.limiters.get(route.Algorithm, ceiling)
+		if base == nil {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				writeError(w, r, http.StatusServiceUnavailable, ErrCodeLimiterUnavailable, "limiter is not configured")
 			})
-			return
 		}
-		next(w, r)
+		policy := s.policies.get(route.Endpoint)
+		// Token claim and tenant limits replace the route's limits, so the
+		// route's promoted, shadow and canary policies do not apply to them.
+		override := func(r *http.Request) limiter.Limiter {
+			if id, ok := JWTIdentityFromContext(r.Context()); ok && id.Limits != nil {
+				if lim := s.limiters.claim(route.Algorithm, id.Limits); lim != nil {
+					return lim
+				}
+			}
+			if t, ok := TenantFromContext(r.Context()); ok && t.Limits != nil {
+				if lim := s.limiters.claim(route.Algorithm, t.Limits); lim != nil {
+					return lim
+				}
+			}
+			return nil
+		}
+		limiterFor := func(r *http.Request) limiter.Limiter {
+			if lim := override(r); lim != nil {
+				return lim
+			}
+			if lim := canaryFromContext(r.Context()); lim != nil {
+				return lim
+			}
+			if lim := policy.enforcingLimiter(); lim != nil {
+				return lim
+			}
+			return base
+		}
+		shadowFor := func(r *http.Request) *routePolicy {
+			if override(r) != nil {
+				return nil
+			}
+			return policy
+		}
+		if adaptive != nil {
+			next = adaptive.Middleware(next)
+			fixed := limiterFor
+			limiterFor = func(r *http.Request) limiter.Limiter {
+				return &adaptiveLimiter{next: fixed(r), ctrl: adaptive}
+			}
+		}
+		if s.quotas != nil {
+			next = s.quotas.Middleware(next)
+		}
+		next = rateLimitMiddleware(limiterFor, shadowFor, s.clk, deny)(next)
+		next = policy.canaryMiddleware(func(r *http.Request) bool { return override(r) != nil })(next)
+	}
+	if s.usage != nil {
+		next = s.usage.Middleware(next)
+	}
+	if route.Recorded {
+		limited := route.Limited
+		next = recordingMiddleware(func(r *http.Request) *RecordingState {
+			if limited {
+				return s.tenants.forRequest(r).limitedRec
+			}
+			return s.tenants.forRequest(r).unlimitedRec
+		}, s.clk)(next)
 	}
+	if s.shedder != nil {
+		next = s.shedder.Middleware(route.Priority)(next)
+	}
+	if s.auth != nil && route.Authenticated {
+		next = s.auth.Middleware(route.Scopes)(next)
+	}
+	return next
 }
 
 type compareResult struct {
@@ -219,25 +537,27 @@ type compareResult struct {
 
 func serveStorageDecision(w http.ResponseWriter, r *http.Request, clk chronoclock.Clock, backend string, lim limiter.Limiter, limErr error, note string) {
 	if limErr != nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{
-			"error":   "backend_unavailable",
-			"backend": backend,
-			"message": limErr.Error(),
+		writeAPIError(w, r, APIError{
+			Status:     http.StatusServiceUnavailable,
+			Code:       ErrCodeBackendUnavailable,
+			Detail:     limErr.Error(),
+			Extensions: map[string]any{"backend": backend},
 		})
 		return
 	}
 	if lim == nil {
-		writeJSON(w, http.StatusServiceUnavailable, map[string]string{
-			"error":   "backend_unavailable",
-			"backend": backend,
-			"message": "limiter not initialized",
+		writeAPIError(w, r, APIError{
+			Status:     http.StatusServiceUnavailable,
+			Code:       ErrCodeBackendUnavailable,
+			Detail:     "limiter not initialized",
+			Extensions: map[string]any{"backend": backend},
 		})
 		return
 	}
 
 	key := clientKeyFromRequest(r)
 	start := time.Now()
-	decision := lim.Allow(r.Context(), key)
+	decision := lim.Allow(r.Context(), tenantKey(r.Context(), key))
 	latency := time.Since(start)
 
 	w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", decision.Limit))
@@ -272,7 +592,7 @@ func serveStorageDecision(w http.ResponseWriter, r *http.Request, clk chronocloc
 }
 
 func serveStorageCompare(w http.ResponseWriter, r *http.Request, clk chronoclock.Clock, set *StorageLimiterSet) {
-	key := clientKeyFromRequest(r)
+	key := tenantKey(r.Context(), clientKeyFromRequest(r))
 
 	run := func(lim limiter.Limiter, err error, note string) compareResult {
 		if err != nil {
@@ -0,0 +1,325 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
+)
+
+// maxShadowKeys bounds the per-key shadow counters of one route; requests of
+// further keys are only counted in the route totals.
+const maxShadowKeys = 10000
+
+// LimitPolicy is a candidate set of limits for a route. Zero limits keep the
+// configured ones; an empty algorithm keeps the route's.
+type LimitPolicy struct {
+	Algorithm limiter.Algorithm `json:"algorithm,omitempty"`
+	Limits    PlanLimits        `json:"limits"`
+}
+
+// Validate rejects unknown algorithms and negative limits.
+func (p LimitPolicy) Validate() error {
+	if p.Algorithm != "" {
+		if _, err := ParseAlgorithm(string(p.Algorithm)); err != nil {
+			return err
+		}
+	}
+	return p.Limits.Validate()
+}
+
+// ShadowCounts tallies a shadow policy's verdicts.
+type ShadowCounts struct {
+	Evaluated int64 `json:"evaluated"`
+	WouldDeny int64 `json:"would_deny"`
+	// NewDenials are requests the shadow policy denies but the enforcing
+	// limiter allowed: the denials promoting it would add.
+	NewDenials int64 `json:"new_denials"`
+}
+
+func (c *ShadowCounts) add(shadowAllowed, enforcingAllowed bool) {
+	c.Evaluated++
+	if !shadowAllowed {
+		c.WouldDeny++
+		if enforcingAllowed {
+			c.NewDenials++
+		}
+	}
+}
+
+// ShadowState is a route's shadow policy and what it would have done.
+type ShadowState struct {
+	Policy LimitPolicy `json:"policy"`
+	Since  time.Time   `json:"since"`
+	ShadowCounts
+	Keys map[string]ShadowCounts `json:"keys"`
+}
+
+// RoutePolicyState is one limited route in GET /admin/shadow and
+// GET /admin/canary.
+type RoutePolicyState struct {
+	Endpoint string `json:"endpoint"`
+	// Promoted is the policy enforced in place of the configured limits.
+	Promoted *LimitPolicy `json:"promoted,omitempty"`
+	Shadow   *ShadowState `json:"shadow,omitempty"`
+	Canary   *CanaryState `json:"canary,omitempty"`
+}
+
+type shadowPolicy struct {
+	policy LimitPolicy
+	lim    limiter.Limiter
+	since  time.Time
+	counts ShadowCounts
+	keys   map[string]*ShadowCounts
+}
+
+// routePolicy is a limited route's runtime policy: a promoted policy that
+// replaces the configured limits, a shadow policy evaluated alongside the
+// enforcing limiter without ever blocking, and a canary policy enforced for a
+// percentage of keys.
+type routePolicy struct {
+	endpoint  string
+	algorithm limiter.Algorithm
+	limiters  *routeLimiters
+	clk       chronoclock.Clock
+
+	mu        sync.Mutex
+	promoted  *LimitPolicy
+	enforcing limiter.Limiter
+	shadow    *shadowPolicy
+	canary    *canaryPolicy
+}
+
+// newLimiter builds a limiter for p on the configured storage backend, like
+// token claim limits, so replicas sharing the backend share its counters.
+// Keys are prefixed with kind and the endpoint, e.g. "shadow:GET /x:", so
+// shadow and canary policies never spend the enforcing limiters' budget.
+func (rp *routePolicy) newLimiter(kind string, p LimitPolicy) (limiter.Limiter, error) {
+	cfg := p.Limits.Apply(rp.limiters.cfg)
+	switch {
+	case p.Algorithm != "":
+		cfg.Algorithm = p.Algorithm
+	case rp.algorithm != "":
+		cfg.Algorithm = rp.algorithm
+	}
+	lim, err := rp.limiters.newLimiter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &keyPrefixLimiter{next: lim, prefix: kind + ":" + rp.endpoint + ":"}, nil
+}
+
+// setShadow replaces the shadow policy and resets its counters.
+func (rp *routePolicy) setShadow(p LimitPolicy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	lim, err := rp.newLimiter("shadow", p)
+	if err != nil {
+		return err
+	}
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.shadow = &shadowPolicy{policy: p, lim: lim, since: rp.clk.Now(), keys: make(map[string]*ShadowCounts)}
+	return nil
+}
+
+func (rp *routePolicy) clearShadow() bool {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	had := rp.shadow != nil
+	rp.shadow = nil
+	return had
+}
+
+// promote makes the shadow policy enforcing. Its limiter moves over with the
+// counters it built up in shadow, so clients keep their current windows.
+// Promotion is per process: it is not persisted, other replicas keep their
+// own policy, and a restart goes back to the route table.
+func (rp *routePolicy) promote() (LimitPolicy, bool) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if rp.shadow == nil {
+		return LimitPolicy{}, false
+	}
+	p := rp.shadow.policy
+	rp.promoted, rp.enforcing, rp.shadow = &p, rp.shadow.lim, nil
+	return p, true
+}
+
+// enforcingLimiter returns the promoted policy's limiter, or nil.
+func (rp *routePolicy) enforcingLimiter() limiter.Limiter {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	return rp.enforcing
+}
+
+// evaluateShadow runs the shadow policy for key and counts its verdict
+// against the enforcing limiter's.
+func (rp *routePolicy) evaluateShadow(ctx context.Context, key string, enforcingAllowed bool) {
+	rp.mu.Lock()
+	shadow := rp.shadow
+	rp.mu.Unlock()
+	if shadow == nil {
+		return
+	}
+	allowed := shadow.lim.Allow(ctx, key).Allowed
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	if rp.shadow != shadow {
+		return
+	}
+	shadow.counts.add(allowed, enforcingAllowed)
+	counts, ok := shadow.keys[key]
+	if !ok {
+		if len(shadow.keys) >= maxShadowKeys {
+			return
+		}
+		counts = &ShadowCounts{}
+		shadow.keys[key] = counts
+	}
+	counts.add(allowed, enforcingAllowed)
+}
+
+func (rp *routePolicy) state() RoutePolicyState {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	out := RoutePolicyState{Endpoint: rp.endpoint, Promoted: rp.promoted}
+	if s := rp.shadow; s != nil {
+		keys := make(map[string]ShadowCounts, len(s.keys))
+		for key, counts := range s.keys {
+			keys[key] = *counts
+		}
+		out.Shadow = &ShadowState{Policy: s.policy, Since: s.since, ShadowCounts: s.counts, Keys: keys}
+	}
+	if c := rp.canary; c != nil {
+		out.Canary = &CanaryState{CanaryPolicy: c.policy, Since: c.since, Requests: c.requests, Enforced: c.enforced}
+	}
+	return out
+}
+
+// routePolicies holds the runtime policy of every limited route.
+type routePolicies struct {
+	routes map[string]*routePolicy
+}
+
+// newRoutePolicies starts each limited route with the shadow and canary
+// policies of its route-table entry, if any. Their limiters are built by
+// limiters.
+func newRoutePolicies(cfg Config, clk chronoclock.Clock, limiters *routeLimiters) *routePolicies {
+	p := &routePolicies{routes: make(map[string]*routePolicy)}
+	for _, route := range cfg.RouteTable() {
+		if !route.Limited {
+			continue
+		}
+		rp := &routePolicy{endpoint: route.Endpoint, algorithm: route.Algorithm, limiters: limiters, clk: clk}
+		if route.Shadow != nil {
+			if err := rp.setShadow(*route.Shadow); err != nil {
+				// validateRouteTable rejects bad policies; run without one.
+				rp.shadow = nil
+			}
+		}
+		if route.Canary != nil {
+			if err := rp.setCanary(*route.Canary); err != nil {
+				rp.canary = nil
+			}
+		}
+		p.routes[route.Endpoint] = rp
+	}
+	return p
+}
+
+func (p *routePolicies) get(endpoint string) *routePolicy {
+	return p.routes[strings.TrimSpace(endpoint)]
+}
+
+func (p *routePolicies) states() []RoutePolicyState {
+	out := make([]RoutePolicyState, 0, len(p.routes))
+	for _, rp := range p.routes {
+		out = append(out, rp.state())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Endpoint < out[j].Endpoint })
+	return out
+}
+
+// shadowRequest is the body of PUT /admin/shadow and POST /admin/shadow/promote.
+type shadowRequest struct {
+	Endpoint string `json:"endpoint"`
+	LimitPolicy
+}
+
+// lookup decodes the request body into req and finds the limited route named
+// by *endpoint, which points into req.
+func (p *routePolicies) lookup(w http.ResponseWriter, r *http.Request, req any, endpoint *string) (*routePolicy, bool) {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("decode policy request: %v", err))
+		return nil, false
+	}
+	rp := p.get(*endpoint)
+	if rp == nil {
+		writeError(w, r, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("no limited route %q", *endpoint))
+		return nil, false
+	}
+	return rp, true
+}
+
+// routePoliciesHandler serves GET /admin/shadow and GET /admin/canary.
+func routePoliciesHandler(p *routePolicies) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"routes": p.states()})
+	}
+}
+
+// shadowPutHandler serves PUT /admin/shadow: it sets a route's shadow policy.
+func shadowPutHandler(p *routePolicies) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req shadowRequest
+		rp, ok := p.lookup(w, r, &req, &req.Endpoint)
+		if !ok {
+			return
+		}
+		if err := rp.setShadow(req.LimitPolicy); err != nil {
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, rp.state())
+	}
+}
+
+// shadowDeleteHandler serves DELETE /admin/shadow?endpoint=.
+func shadowDeleteHandler(p *routePolicies) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		endpoint := r.URL.Query().Get("endpoint")
+		rp := p.get(endpoint)
+		if rp == nil || !rp.clearShadow() {
+			writeError(w, r, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("no shadow policy for %q", endpoint))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// shadowPromoteHandler serves POST /admin/shadow/promote: the route's shadow
+// policy becomes its enforcing policy.
+func shadowPromoteHandler(p *routePolicies) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req shadowRequest
+		rp, ok := p.lookup(w, r, &req, &req.Endpoint)
+		if !ok {
+			return
+		}
+		if _, ok := rp.promote(); !ok {
+			writeError(w, r, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("no shadow policy for %q", req.Endpoint))
+			return
+		}
+		writeJSON(w, http.StatusOK, rp.state())
+	}
+}
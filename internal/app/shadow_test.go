@@ -0,0 +1,180 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
+	chronostorage "github.com/SmitUplenchwar2687/Chrono/pkg/storage"
+	"github.com/alicebob/miniredis/v2"
+)
+
+func adminRequest(handler http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("X-API-Key", "bootstrap-secret")
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestShadowPolicyCountsWithoutBlockingAndPromotes(t *testing.T) {
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.Rate = 5
+	cfg.Window = time.Minute
+	cfg.AdminAPIKey = "bootstrap-secret"
+	cfg.Routes = DefaultRouteTable()
+	for i := range cfg.Routes {
+		if cfg.Routes[i].Endpoint == "GET /api/profile" {
+			cfg.Routes[i].Authenticated = false
+			cfg.Routes[i].Shadow = &LimitPolicy{Limits: PlanLimits{Rate: 2}}
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	handler := newAuthTestHandler(t, cfg)
+
+	// With auth on, clients without a valid key are told apart by address.
+
+	for i := 0; i < 4; i++ {
+		resp := executeRequest(handler, http.MethodGet, "/api/profile", "", "", "", "198.51.100.1:4000")
+		assertStatus(t, resp, http.StatusOK)
+		if got := resp.Header().Get("X-RateLimit-Limit"); got != "5" {
+			t.Fatalf("X-RateLimit-Limit = %q, want the enforcing 5", got)
+		}
+	}
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/api/profile", "", "", "", "198.51.100.2:4000"), http.StatusOK)
+
+	resp := adminRequest(handler, http.MethodGet, "/admin/shadow", "")
+	assertStatus(t, resp, http.StatusOK)
+	var listed struct {
+		Routes []RoutePolicyState `json:"routes"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("decode shadow list: %v", err)
+	}
+	var profile *RoutePolicyState
+	for i := range listed.Routes {
+		if listed.Routes[i].Endpoint == "GET /api/profile" {
+			profile = &listed.Routes[i]
+		}
+	}
+	if profile == nil || profile.Shadow == nil {
+		t.Fatalf("shadow list = %s", resp.Body.String())
+	}
+	if got := profile.Shadow.ShadowCounts; got != (ShadowCounts{Evaluated: 5, WouldDeny: 2, NewDenials: 2}) {
+		t.Fatalf("route counts = %+v", got)
+	}
+	if got := profile.Shadow.Keys["198.51.100.1"]; got != (ShadowCounts{Evaluated: 4, WouldDeny: 2, NewDenials: 2}) {
+		t.Fatalf("198.51.100.1 counts = %+v", got)
+	}
+
+	// Non-admins cannot manage policies.
+	resp = executeRequest(handler, http.MethodPost, "/admin/shadow/promote", "client-a", "", `{"endpoint":"GET /api/profile"}`, "")
+	if resp.Code != http.StatusUnauthorized && resp.Code != http.StatusForbidden {
+		t.Fatalf("non-admin promote = %d", resp.Code)
+	}
+
+	resp = adminRequest(handler, http.MethodPost, "/admin/shadow/promote", `{"endpoint":"GET /api/profile"}`)
+	assertStatus(t, resp, http.StatusOK)
+	if !strings.Contains(resp.Body.String(), `"promoted":{"limits":{"rate":2}}`) {
+		t.Fatalf("promote body = %s", resp.Body.String())
+	}
+
+	// The promoted limiter keeps the windows it built in shadow.
+	resp = executeRequest(handler, http.MethodGet, "/api/profile", "", "", "", "198.51.100.1:4000")
+	assertStatus(t, resp, http.StatusTooManyRequests)
+	if got := resp.Header().Get("X-RateLimit-Limit"); got != "2" {
+		t.Fatalf("promoted X-RateLimit-Limit = %q, want 2", got)
+	}
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/api/profile", "", "", "", "198.51.100.2:4000"), http.StatusOK)
+	assertStatus(t, executeRequest(handler, http.MethodGet, "/api/profile", "", "", "", "198.51.100.2:4000"), http.StatusTooManyRequests)
+
+	assertStatus(t, adminRequest(handler, http.MethodPost, "/admin/shadow/promote", `{"endpoint":"GET /api/profile"}`), http.StatusNotFound)
+	assertStatus(t, adminRequest(handler, http.MethodPut, "/admin/shadow", `{"endpoint":"GET /public","limits":{"rate":1}}`), http.StatusNotFound)
+	assertStatus(t, adminRequest(handler, http.MethodPut, "/admin/shadow", `{"endpoint":"POST /api/orders","algorithm":"leaky"}`), http.StatusBadRequest)
+
+	assertStatus(t, adminRequest(handler, http.MethodPut, "/admin/shadow", `{"endpoint":"POST /api/orders","limits":{"rate":1}}`), http.StatusOK)
+	assertStatus(t, adminRequest(handler, http.MethodDelete, "/admin/shadow?endpoint="+url.QueryEscape("POST /api/orders"), ""), http.StatusNoContent)
+	assertStatus(t, adminRequest(handler, http.MethodDelete, "/admin/shadow?endpoint="+url.QueryEscape("POST /api/orders"), ""), http.StatusNotFound)
+}
+
+func TestShadowPolicyNeedsLimitedRoute(t *testing.T) {
+	deny := mustTestConfig(limiter.AlgorithmFixedWindow).Deny
+	routes := DefaultRouteTable()
+	if err := validateRouteTable(routes, deny); err != nil {
+		t.Fatalf("validateRouteTable() error = %v", err)
+	}
+	for i := range routes {
+		if routes[i].Endpoint == "GET /public" {
+			routes[i].Shadow = &LimitPolicy{Limits: PlanLimits{Rate: 1}}
+		}
+	}
+	if err := validateRouteTable(routes, deny); err == nil {
+		t.Fatal("validateRouteTable() = nil, want error for shadow on an unlimited route")
+	}
+}
+
+func TestShadowPolicyNeedsAuth(t *testing.T) {
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.Routes = []RouteScope{{Endpoint: "GET /api/profile", Limited: true, Recorded: true, Shadow: &LimitPolicy{Limits: PlanLimits{Rate: 1}}}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "need auth") {
+		t.Fatalf("Validate() error = %v, want shadow needs auth", err)
+	}
+	cfg.AdminAPIKey = "bootstrap-secret"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() with auth error = %v", err)
+	}
+}
+
+func TestShadowAndPromotedLimitersShareTheStorageBackend(t *testing.T) {
+	mr := miniredis.RunT(t)
+	port, err := strconv.Atoi(mr.Port())
+	if err != nil {
+		t.Fatalf("parse miniredis port: %v", err)
+	}
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC))
+	cfg := mustTestConfig(limiter.AlgorithmSlidingWindow)
+	cfg.StorageBackend = chronostorage.BackendRedis
+	cfg.Storage.Redis = &chronostorage.RedisConfig{Host: mr.Host(), Port: port}
+
+	// Two replicas on one Redis server.
+	replica := func() *routePolicy {
+		stores := NewStorageLimiterSet(cfg, vc)
+		t.Cleanup(func() { _ = stores.Close() })
+		rp := newRoutePolicies(cfg, vc, newRouteLimiters(cfg, vc, nil, stores)).get("GET /api/profile")
+		if err := rp.setShadow(LimitPolicy{Limits: PlanLimits{Rate: 2}}); err != nil {
+			t.Fatalf("setShadow() error = %v", err)
+		}
+		return rp
+	}
+	a, b := replica(), replica()
+
+	// Redis members are named by time and a per-replica sequence, so the
+	// clock moves between requests as it would in production.
+	ctx := context.Background()
+	for _, rp := range []*routePolicy{a, b, b} {
+		rp.evaluateShadow(ctx, "client", true)
+		vc.Advance(time.Millisecond)
+	}
+	if got := b.state().Shadow.WouldDeny; got != 1 {
+		t.Fatalf("replica b would deny %d, want 1 once the shared shadow budget is spent", got)
+	}
+
+	// The promoted limiter keeps the shared counters.
+	if _, ok := a.promote(); !ok {
+		t.Fatal("promote() = false")
+	}
+	vc.Advance(time.Millisecond)
+	if a.enforcingLimiter().Allow(ctx, "client").Allowed {
+		t.Fatal("promoted limiter allowed a key whose shadow budget another replica spent")
+	}
+}
@@ -0,0 +1,243 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+)
+
+// Priority is a request's load-shedding class.
+type Priority string
+
+const (
+	// PriorityCritical is never shed.
+	PriorityCritical Priority = "critical"
+	PriorityHigh     Priority = "high"
+	PriorityNormal   Priority = "normal"
+	PriorityLow      Priority = "low"
+)
+
+// shedThresholds is the load at which each class starts being shed; load 1
+// means MaxInFlight requests in flight or mean latency at TargetLatency.
+var shedThresholds = map[Priority]float64{
+	PriorityLow:    0.5,
+	PriorityNormal: 0.8,
+	PriorityHigh:   1,
+}
+
+// shedLatencyWindow is how long latency samples are averaged for.
+const shedLatencyWindow = time.Second
+
+// ParsePriority resolves a priority class name.
+func ParsePriority(raw string) (Priority, error) {
+	p := Priority(strings.ToLower(strings.TrimSpace(raw)))
+	switch p {
+	case PriorityCritical, PriorityHigh, PriorityNormal, PriorityLow:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid priority %q; use critical, high, normal or low", raw)
+}
+
+// ShedConfig turns on load shedding when MaxInFlight or TargetLatency is set.
+type ShedConfig struct {
+	// MaxInFlight is the number of concurrent requests that counts as full load.
+	MaxInFlight int
+	// TargetLatency is the mean handler latency that counts as full load.
+	TargetLatency time.Duration
+	// RetryAfter is sent with 503 responses; defaults to 1s.
+	RetryAfter time.Duration
+	// PriorityHeader, when set, names a request header carrying the priority
+	// class of requests without a tiered key or plan. It is only read from
+	// trusted proxies (TRUSTED_PROXIES) and cannot claim critical.
+	PriorityHeader string
+	// TierPriorities maps API key tiers and JWT plans to priority classes.
+	TierPriorities map[string]Priority
+}
+
+func (c ShedConfig) enabled() bool {
+	return c.MaxInFlight > 0 || c.TargetLatency > 0
+}
+
+// Validate checks the shedding settings.
+func (c ShedConfig) Validate() error {
+	if c.MaxInFlight < 0 || c.TargetLatency < 0 || c.RetryAfter < 0 {
+		return fmt.Errorf("max in-flight, target latency and retry-after must be >= 0")
+	}
+	for tier, p := range c.TierPriorities {
+		if _, err := ParsePriority(string(p)); err != nil {
+			return fmt.Errorf("tier %q: %w", tier, err)
+		}
+	}
+	return nil
+}
+
+// parseTierPriorities parses "pro=high,free=low".
+func parseTierPriorities(raw string) (map[string]Priority, error) {
+	entries := splitCommaList(raw)
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]Priority, len(entries))
+	for _, entry := range entries {
+		tier, class, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(tier) == "" {
+			return nil, fmt.Errorf("invalid tier priority %q: want tier=class", entry)
+		}
+		p, err := ParsePriority(class)
+		if err != nil {
+			return nil, err
+		}
+		out[strings.TrimSpace(tier)] = p
+	}
+	return out, nil
+}
+
+// Shedder tracks gateway load across every route and rejects requests of
+// the lowest priority classes first as load rises.
+type Shedder struct {
+	cfg ShedConfig
+	clk chronoclock.Clock
+
+	mu          sync.Mutex
+	inFlight    int
+	windowStart time.Time
+	samples     int
+	latency     time.Duration
+	lastMean    time.Duration
+	shed        map[Priority]int64
+}
+
+// NewShedder returns nil when cfg does not enable shedding.
+func NewShedder(cfg ShedConfig, clk chronoclock.Clock) *Shedder {
+	if !cfg.enabled() {
+		return nil
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = time.Second
+	}
+	return &Shedder{cfg: cfg, clk: clk, windowStart: clk.Now().Truncate(shedLatencyWindow), shed: make(map[Priority]int64)}
+}
+
+// advance rolls the latency window. Callers hold s.mu.
+func (s *Shedder) advance(now time.Time) {
+	if now.Before(s.windowStart.Add(shedLatencyWindow)) {
+		return
+	}
+	s.lastMean = 0
+	if s.samples > 0 && now.Before(s.windowStart.Add(2*shedLatencyWindow)) {
+		s.lastMean = s.latency / time.Duration(s.samples)
+	}
+	s.windowStart = now.Truncate(shedLatencyWindow)
+	s.samples, s.latency = 0, 0
+}
+
+// load is the larger of the in-flight and latency ratios. Callers hold s.mu.
+func (s *Shedder) load() float64 {
+	var load float64
+	if s.cfg.MaxInFlight > 0 {
+		load = float64(s.inFlight) / float64(s.cfg.MaxInFlight)
+	}
+	if s.cfg.TargetLatency > 0 {
+		load = math.Max(load, float64(s.lastMean)/float64(s.cfg.TargetLatency))
+	}
+	return load
+}
+
+// admit counts the request in flight unless its class is being shed.
+func (s *Shedder) admit(p Priority) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance(s.clk.Now())
+	if p != PriorityCritical && s.load() >= shedThresholds[p] {
+		s.shed[p]++
+		return false
+	}
+	s.inFlight++
+	return true
+}
+
+func (s *Shedder) done(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance(s.clk.Now())
+	s.inFlight--
+	s.samples++
+	s.latency += latency
+}
+
+// ShedStats reports current load and how many requests each class lost.
+type ShedStats struct {
+	InFlight int                `json:"in_flight"`
+	Load     float64            `json:"load"`
+	Shed     map[Priority]int64 `json:"shed"`
+}
+
+// Stats returns a snapshot of the shedder.
+func (s *Shedder) Stats() ShedStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance(s.clk.Now())
+	shed := make(map[Priority]int64, len(s.shed))
+	for p, n := range s.shed {
+		shed[p] = n
+	}
+	return ShedStats{InFlight: s.inFlight, Load: s.load(), Shed: shed}
+}
+
+// priority resolves a request's class: a critical route always wins, then
+// the key tier or token plan, the priority header sent by a trusted proxy,
+// the route's class, and finally normal. Clients cannot raise their own
+// class above what their key grants.
+func (s *Shedder) priority(r *http.Request, route Priority) Priority {
+	if route == PriorityCritical {
+		return PriorityCritical
+	}
+	if key, ok := APIKeyFromContext(r.Context()); ok {
+		if p, ok := s.cfg.TierPriorities[key.Tier]; ok {
+			return p
+		}
+	}
+	if id, ok := JWTIdentityFromContext(r.Context()); ok {
+		if p, ok := s.cfg.TierPriorities[id.Plan]; ok {
+			return p
+		}
+	}
+	if s.cfg.PriorityHeader != "" && fromTrustedProxy(r) {
+		if p, err := ParsePriority(r.Header.Get(s.cfg.PriorityHeader)); err == nil && p != PriorityCritical {
+			return p
+		}
+	}
+	if route != "" {
+		return route
+	}
+	return PriorityNormal
+}
+
+// Middleware sheds requests of a route whose class is route (empty =
+// normal), answering 503 with Retry-After.
+func (s *Shedder) Middleware(route Priority) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := s.priority(r, route)
+			if !s.admit(p) {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(s.cfg.RetryAfter.Seconds()))))
+				writeAPIError(w, r, APIError{
+					Status:     http.StatusServiceUnavailable,
+					Code:       ErrCodeOverloaded,
+					Detail:     fmt.Sprintf("gateway is overloaded; %s priority requests are being shed", p),
+					Extensions: map[string]any{"priority": p},
+				})
+				return
+			}
+			start := s.clk.Now()
+			defer func() { s.done(s.clk.Now().Sub(start)) }()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
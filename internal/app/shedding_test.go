@@ -0,0 +1,119 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+)
+
+func TestShedderShedsLowestClassesFirst(t *testing.T) {
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 2, 8, 22, 0, 0, 0, time.UTC))
+	s := NewShedder(ShedConfig{MaxInFlight: 10}, vc)
+
+	admitted := func(p Priority, n int) int {
+		count := 0
+		for i := 0; i < n; i++ {
+			if s.admit(p) {
+				count++
+			}
+		}
+		return count
+	}
+
+	if got := admitted(PriorityLow, 10); got != 5 {
+		t.Fatalf("low admitted %d, want 5 (shed from load 0.5)", got)
+	}
+	if got := admitted(PriorityNormal, 10); got != 3 {
+		t.Fatalf("normal admitted %d, want 3 (shed from load 0.8)", got)
+	}
+	if got := admitted(PriorityHigh, 10); got != 2 {
+		t.Fatalf("high admitted %d, want 2 (shed at capacity)", got)
+	}
+	if got := admitted(PriorityCritical, 3); got != 3 {
+		t.Fatalf("critical admitted %d, want all 3", got)
+	}
+
+	stats := s.Stats()
+	if stats.InFlight != 13 || stats.Shed[PriorityLow] != 5 || stats.Shed[PriorityNormal] != 7 || stats.Shed[PriorityHigh] != 8 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if NewShedder(ShedConfig{}, vc) != nil {
+		t.Fatal("NewShedder() without limits should be disabled")
+	}
+}
+
+func TestShedderLatencyAndPriorityResolution(t *testing.T) {
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 2, 8, 22, 0, 0, 0, time.UTC))
+	tiers, err := parseTierPriorities("pro=high, free=low")
+	if err != nil {
+		t.Fatalf("parseTierPriorities() error = %v", err)
+	}
+	s := NewShedder(ShedConfig{TargetLatency: 100 * time.Millisecond, RetryAfter: 1500 * time.Millisecond, PriorityHeader: "X-Priority", TierPriorities: tiers}, vc)
+
+	// httptest requests come from 192.0.2.1, the trusted proxy.
+	trusted := []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")}
+	handler := func(route Priority) http.Handler {
+		return trustedProxyMiddleware(trusted, s.Middleware(route)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+			vc.Advance(90 * time.Millisecond)
+		})))
+	}
+	serveFrom := func(remote string, route Priority, tier, header string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/profile", nil)
+		req.RemoteAddr = remote
+		if tier != "" {
+			req = req.WithContext(WithAPIKey(req.Context(), APIKey{ID: "key_1", Tier: tier}))
+		}
+		if header != "" {
+			req.Header.Set("X-Priority", header)
+		}
+		resp := httptest.NewRecorder()
+		handler(route).ServeHTTP(resp, req)
+		return resp
+	}
+	serve := func(route Priority, tier, header string) *httptest.ResponseRecorder {
+		return serveFrom("192.0.2.1:1234", route, tier, header)
+	}
+
+	// Fill one latency window at 90ms per request: load 0.9 for the next.
+	for i := 0; i < 3; i++ {
+		assertStatus(t, serve("", "", ""), http.StatusOK)
+	}
+	vc.Set(vc.Now().Truncate(time.Second).Add(time.Second))
+
+	resp := serve("", "", "")
+	assertStatus(t, resp, http.StatusServiceUnavailable)
+	if resp.Header().Get("Retry-After") != "2" {
+		t.Fatalf("Retry-After = %q, want 2", resp.Header().Get("Retry-After"))
+	}
+	var problem map[string]any
+	if err := json.Unmarshal(resp.Body.Bytes(), &problem); err != nil || problem["error"] != ErrCodeOverloaded || problem["priority"] != "normal" {
+		t.Fatalf("503 body = %s", resp.Body.String())
+	}
+
+	assertStatus(t, serve(PriorityHigh, "", ""), http.StatusOK)
+	assertStatus(t, serve(PriorityHigh, "free", ""), http.StatusServiceUnavailable)
+	assertStatus(t, serve(PriorityLow, "pro", ""), http.StatusOK)
+	assertStatus(t, serve("", "", "high"), http.StatusOK)
+	assertStatus(t, serve("", "", "critical"), http.StatusServiceUnavailable)
+	// The key tier outranks the header, which only trusted proxies may send.
+	assertStatus(t, serve("", "free", "high"), http.StatusServiceUnavailable)
+	assertStatus(t, serveFrom("203.0.113.9:1234", "", "", "high"), http.StatusServiceUnavailable)
+	assertStatus(t, serve(PriorityCritical, "free", "low"), http.StatusOK)
+
+	// Two quiet windows clear the latency signal.
+	vc.Advance(2 * time.Second)
+	assertStatus(t, serve(PriorityLow, "", ""), http.StatusOK)
+}
+
+func TestHealthRouteIsCritical(t *testing.T) {
+	for _, route := range DefaultRouteTable() {
+		if route.Endpoint == "GET /health" && route.Priority != PriorityCritical {
+			t.Fatalf("GET /health priority = %q, want critical", route.Priority)
+		}
+	}
+}
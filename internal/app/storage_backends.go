@@ -1,13 +1,18 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"strconv"
 	"sync"
 	"time"
 
 	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
 	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
 	chronostorage "github.com/SmitUplenchwar2687/Chrono/pkg/storage"
+	"github.com/redis/go-redis/v9"
 )
 
 // StorageLimiterSet holds storage-backed limiters for memory/redis/crdt validation.
@@ -22,6 +27,17 @@ type StorageLimiterSet struct {
 
 	RedisErr error
 	CRDTErr  error
+
+	// Quotas holds quota counters: in Redis with the redis backend,
+	// otherwise in QUOTA_FILE or memory. It is nil when quotas are off, and
+	// QuotaErr says why it could not be opened.
+	Quotas   QuotaStore
+	QuotaErr error
+
+	// Usage holds usage rollups, in USAGE_FILE or memory. It is nil when
+	// accounting is off, and UsageErr says why the file could not be opened.
+	Usage    UsageStore
+	UsageErr error
 }
 
 func NewStorageLimiterSet(cfg Config, clk chronoclock.Clock) *StorageLimiterSet {
@@ -86,9 +102,93 @@ func NewStorageLimiterSet(cfg Config, clk chronoclock.Clock) *StorageLimiterSet
 		}
 	}
 
+	set.Quotas, set.QuotaErr = openQuotaStore(cfg)
+	set.Usage, set.UsageErr = openUsageStore(cfg, clk)
+
 	return set
 }
 
+// Defaults Chrono's Redis backend applies to unset RedisConfig fields.
+const (
+	defaultRedisPoolSize    = 20
+	defaultRedisMaxRetries  = 3
+	defaultRedisDialTimeout = 5 * time.Second
+)
+
+// newRedisClient connects to the Redis server or cluster of cfg the way
+// Chrono's Redis backend does, with the same defaults and checks, for
+// gateway state kept beside the limiter's counters.
+func newRedisClient(cfg *chronostorage.RedisConfig) (redis.UniversalClient, error) {
+	if cfg == nil {
+		return nil, errors.New("redis config is required")
+	}
+	conf := *cfg
+	if conf.PoolSize <= 0 {
+		conf.PoolSize = defaultRedisPoolSize
+	}
+	if conf.MaxRetries <= 0 {
+		conf.MaxRetries = defaultRedisMaxRetries
+	}
+	if conf.DialTimeout <= 0 {
+		conf.DialTimeout = defaultRedisDialTimeout
+	}
+
+	var client redis.UniversalClient
+	switch {
+	case conf.Cluster && len(conf.ClusterNodes) == 0:
+		return nil, errors.New("cluster_nodes is required when cluster=true")
+	case conf.Cluster:
+		client = redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:       conf.ClusterNodes,
+			Password:    conf.Password,
+			PoolSize:    conf.PoolSize,
+			MaxRetries:  conf.MaxRetries,
+			DialTimeout: conf.DialTimeout,
+		})
+	case conf.Host == "":
+		return nil, errors.New("host is required when cluster=false")
+	case conf.Port <= 0:
+		return nil, fmt.Errorf("port must be positive when cluster=false, got %d", conf.Port)
+	default:
+		client = redis.NewClient(&redis.Options{
+			Addr:        net.JoinHostPort(conf.Host, strconv.Itoa(conf.Port)),
+			Password:    conf.Password,
+			DB:          conf.DB,
+			PoolSize:    conf.PoolSize,
+			MaxRetries:  conf.MaxRetries,
+			DialTimeout: conf.DialTimeout,
+		})
+	}
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("redis ping failed: %w", err)
+	}
+	return client, nil
+}
+
+// store returns the named backend, or the error that kept it from starting.
+func (s *StorageLimiterSet) store(backend string) (chronostorage.Storage, error) {
+	var (
+		store chronostorage.Storage
+		err   error
+	)
+	switch backend {
+	case chronostorage.BackendMemory:
+		store = s.memoryStore
+	case chronostorage.BackendRedis:
+		store, err = s.redisStore, s.RedisErr
+	case chronostorage.BackendCRDT:
+		store, err = s.crdtStore, s.CRDTErr
+	}
+	if store == nil {
+		if err == nil {
+			err = fmt.Errorf("storage backend %q is not available", backend)
+		}
+		return nil, err
+	}
+	return store, nil
+}
+
 func (s *StorageLimiterSet) Close() error {
 	stores := []chronostorage.Storage{s.memoryStore, s.redisStore, s.crdtStore}
 	var (
@@ -112,6 +212,16 @@ func (s *StorageLimiterSet) Close() error {
 		}(st)
 	}
 	wg.Wait()
+	if s.Quotas != nil {
+		if err := s.Quotas.Close(); err != nil {
+			errL = append(errL, err)
+		}
+	}
+	if s.Usage != nil {
+		if err := s.Usage.Close(); err != nil {
+			errL = append(errL, err)
+		}
+	}
 
 	if len(errL) == 0 {
 		return nil
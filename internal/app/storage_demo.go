@@ -22,41 +22,34 @@ type storageIncrementRequest struct {
 	TTL   string `json:"ttl"`
 }
 
-func storageDemoHandler(store chronokv.Storage) http.HandlerFunc {
+func storageReadHandler(store chronokv.Storage) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodGet:
-			handleStorageRead(w, r, store)
-		case http.MethodPut:
-			handleStorageWrite(w, r, store)
-		case http.MethodPost:
-			handleStorageIncrement(w, r, store)
-		default:
-			w.Header().Set("Allow", "GET, PUT, POST")
-			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{
-				"error":   "method_not_allowed",
-				"message": "use GET (read), PUT (write), POST (increment)",
-			})
-		}
+		handleStorageRead(w, r, store)
+	}
+}
+
+func storageWriteHandler(store chronokv.Storage) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handleStorageWrite(w, r, store)
+	}
+}
+
+func storageIncrementHandler(store chronokv.Storage) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handleStorageIncrement(w, r, store)
 	}
 }
 
 func handleStorageRead(w http.ResponseWriter, r *http.Request, store chronokv.Storage) {
 	key := strings.TrimSpace(r.URL.Query().Get("key"))
 	if key == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error":   "invalid_request",
-			"message": "query parameter 'key' is required",
-		})
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "query parameter 'key' is required")
 		return
 	}
 
-	value, err := store.Get(r.Context(), key)
+	value, err := store.Get(r.Context(), tenantKey(r.Context(), key))
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{
-			"error":   "storage_error",
-			"message": err.Error(),
-		})
+		writeError(w, r, http.StatusInternalServerError, ErrCodeStorageError, err.Error())
 		return
 	}
 
@@ -75,36 +68,24 @@ func handleStorageRead(w http.ResponseWriter, r *http.Request, store chronokv.St
 func handleStorageWrite(w http.ResponseWriter, r *http.Request, store chronokv.Storage) {
 	var req storageWriteRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error":   "invalid_request",
-			"message": "invalid JSON body",
-		})
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid JSON body")
 		return
 	}
 
 	key := strings.TrimSpace(req.Key)
 	if key == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error":   "invalid_request",
-			"message": "field 'key' is required",
-		})
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "field 'key' is required")
 		return
 	}
 
 	ttl, err := parseOptionalDuration(req.TTL)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error":   "invalid_request",
-			"message": err.Error(),
-		})
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
 		return
 	}
 
-	if err := store.Set(r.Context(), key, []byte(req.Value), ttl); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{
-			"error":   "storage_error",
-			"message": err.Error(),
-		})
+	if err := store.Set(r.Context(), tenantKey(r.Context(), key), []byte(req.Value), ttl); err != nil {
+		writeError(w, r, http.StatusInternalServerError, ErrCodeStorageError, err.Error())
 		return
 	}
 
@@ -119,28 +100,19 @@ func handleStorageWrite(w http.ResponseWriter, r *http.Request, store chronokv.S
 func handleStorageIncrement(w http.ResponseWriter, r *http.Request, store chronokv.Storage) {
 	var req storageIncrementRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error":   "invalid_request",
-			"message": "invalid JSON body",
-		})
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "invalid JSON body")
 		return
 	}
 
 	key := strings.TrimSpace(req.Key)
 	if key == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error":   "invalid_request",
-			"message": "field 'key' is required",
-		})
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, "field 'key' is required")
 		return
 	}
 
 	ttl, err := parseOptionalDuration(req.TTL)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{
-			"error":   "invalid_request",
-			"message": err.Error(),
-		})
+		writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
 		return
 	}
 
@@ -149,12 +121,9 @@ func handleStorageIncrement(w http.ResponseWriter, r *http.Request, store chrono
 		delta = 1
 	}
 
-	value, err := store.Increment(r.Context(), key, delta, ttl)
+	value, err := store.Increment(r.Context(), tenantKey(r.Context(), key), delta, ttl)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{
-			"error":   "storage_error",
-			"message": err.Error(),
-		})
+		writeError(w, r, http.StatusInternalServerError, ErrCodeStorageError, err.Error())
 		return
 	}
 
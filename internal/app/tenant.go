@@ -0,0 +1,295 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+)
+
+// Tenant is one team sharing the deployment, with its own config section.
+type Tenant struct {
+	ID string `json:"-"`
+	// Hosts and PathPrefix select the tenant's requests; the prefix is
+	// stripped before routing, so "/team-a/api/profile" serves GET /api/profile.
+	Hosts      []string `json:"hosts,omitempty"`
+	PathPrefix string   `json:"path_prefix,omitempty"`
+	// Limits replace the configured rate, window and burst for the tenant;
+	// token claim limits still take precedence.
+	Limits *PlanLimits `json:"limits,omitempty"`
+	// Quota replaces the default daily and monthly quotas for the tenant.
+	Quota *QuotaLimits `json:"quota,omitempty"`
+}
+
+// TenantConfig namespaces limiter, quota and storage keys, recordings and
+// replay history per tenant. Requests matching no tenant use the default
+// namespace, which behaves as a single-tenant deployment.
+type TenantConfig struct {
+	// Header names the tenant by ID for requests whose host and path match
+	// no tenant. It is only read from trusted proxies (TRUSTED_PROXIES), and
+	// a header naming another tenant than the host or path is rejected.
+	Header  string            `json:"header,omitempty"`
+	Tenants map[string]Tenant `json:"tenants,omitempty"`
+}
+
+var tenantIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// LoadTenants reads a tenants JSON file.
+func LoadTenants(path string) (TenantConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return TenantConfig{}, fmt.Errorf("read tenants file: %w", err)
+	}
+	var cfg TenantConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return TenantConfig{}, fmt.Errorf("decode tenants file: %w", err)
+	}
+	return cfg, nil
+}
+
+// Validate checks tenant IDs, which become key prefixes and directory names,
+// and rejects hosts or path prefixes claimed by two tenants.
+func (c TenantConfig) Validate() error {
+	hosts := make(map[string]string)
+	prefixes := make(map[string]string)
+	for _, id := range c.ids() {
+		t := c.Tenants[id]
+		if !tenantIDPattern.MatchString(id) {
+			return fmt.Errorf("invalid tenant ID %q: use letters, digits, '-' and '_'", id)
+		}
+		for _, host := range t.Hosts {
+			host = strings.ToLower(strings.TrimSpace(host))
+			if host == "" {
+				return fmt.Errorf("tenant %q: empty host", id)
+			}
+			if other, ok := hosts[host]; ok {
+				return fmt.Errorf("host %q is claimed by tenants %q and %q", host, other, id)
+			}
+			hosts[host] = id
+		}
+		if p := t.PathPrefix; p != "" {
+			if !strings.HasPrefix(p, "/") || strings.HasSuffix(p, "/") {
+				return fmt.Errorf("tenant %q: path prefix %q must start and not end with '/'", id, p)
+			}
+			if other, ok := prefixes[p]; ok {
+				return fmt.Errorf("path prefix %q is claimed by tenants %q and %q", p, other, id)
+			}
+			prefixes[p] = id
+		}
+		if t.Limits != nil {
+			if err := t.Limits.Validate(); err != nil {
+				return fmt.Errorf("tenant %q: %w", id, err)
+			}
+		}
+		if t.Quota != nil && (t.Quota.Daily < 0 || t.Quota.Monthly < 0) {
+			return fmt.Errorf("tenant %q: quota limits must be >= 0", id)
+		}
+	}
+	return nil
+}
+
+func (c TenantConfig) enabled() bool {
+	return len(c.Tenants) > 0
+}
+
+// ids returns the tenant IDs sorted, so matching is deterministic.
+func (c TenantConfig) ids() []string {
+	ids := make([]string, 0, len(c.Tenants))
+	for id := range c.Tenants {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// errTenantConflict is returned when the tenant header names another tenant
+// than the request's host or path.
+var errTenantConflict = errors.New("tenant header conflicts with the host or path")
+
+// resolve returns the tenant of r, or nil for the default namespace. A
+// header naming an unknown tenant, or another tenant than the host or path,
+// is an error.
+func (c TenantConfig) resolve(r *http.Request) (*Tenant, error) {
+	matched := c.match(r)
+	if c.Header == "" || !fromTrustedProxy(r) {
+		return matched, nil
+	}
+	id := strings.TrimSpace(r.Header.Get(c.Header))
+	if id == "" {
+		return matched, nil
+	}
+	t, ok := c.Tenants[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown tenant %q", id)
+	}
+	if matched != nil && matched.ID != id {
+		return nil, fmt.Errorf("%w: header names %q, request is for %q", errTenantConflict, id, matched.ID)
+	}
+	t.ID = id
+	return &t, nil
+}
+
+// match returns the tenant selected by r's host, then its path prefix.
+func (c TenantConfig) match(r *http.Request) *Tenant {
+	host := strings.ToLower(r.Host)
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	for _, id := range c.ids() {
+		t := c.Tenants[id]
+		for _, h := range t.Hosts {
+			if strings.ToLower(strings.TrimSpace(h)) == host {
+				t.ID = id
+				return &t
+			}
+		}
+	}
+	for _, id := range c.ids() {
+		t := c.Tenants[id]
+		if p := t.PathPrefix; p != "" && (r.URL.Path == p || strings.HasPrefix(r.URL.Path, p+"/")) {
+			t.ID = id
+			return &t
+		}
+	}
+	return nil
+}
+
+type tenantContextKey struct{}
+
+// tenantMiddleware resolves each request's tenant and strips its path prefix
+// before routing.
+func tenantMiddleware(cfg TenantConfig, next http.Handler) http.Handler {
+	if !cfg.enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t, err := cfg.resolve(r)
+		if err != nil {
+			code := ErrCodeUnknownTenant
+			if errors.Is(err, errTenantConflict) {
+				code = ErrCodeTenantConflict
+			}
+			writeError(w, r, http.StatusBadRequest, code, err.Error())
+			return
+		}
+		if t == nil {
+			t = &Tenant{}
+		}
+		r = r.WithContext(context.WithValue(r.Context(), tenantContextKey{}, t))
+		if p := t.PathPrefix; p != "" && (r.URL.Path == p || strings.HasPrefix(r.URL.Path, p+"/")) {
+			u := *r.URL
+			u.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(u.Path, p), "/")
+			u.RawPath = ""
+			r.URL = &u
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// TenantFromContext returns the tenant resolved for the request; ok is false
+// in the default namespace.
+func TenantFromContext(ctx context.Context) (Tenant, bool) {
+	t, ok := ctx.Value(tenantContextKey{}).(*Tenant)
+	if !ok || t.ID == "" {
+		return Tenant{}, false
+	}
+	return *t, true
+}
+
+// tenantConfig returns cfg with the request tenant's limits and quota in
+// place of the configured ones.
+func tenantConfig(r *http.Request, cfg Config) Config {
+	t, ok := TenantFromContext(r.Context())
+	if !ok {
+		return cfg
+	}
+	if t.Limits != nil {
+		cfg = t.Limits.Apply(cfg)
+	}
+	if t.Quota != nil {
+		cfg.Quota.QuotaLimits = *t.Quota
+	}
+	return cfg
+}
+
+// defaultNamespace escapes default-namespace keys that would read as another
+// tenant's key. Tenant IDs cannot start with '_', so these never collide.
+const defaultNamespace = "_"
+
+// tenantKey namespaces key by the request's tenant, e.g. "team-a/client-a".
+// Keys in the default namespace are unchanged, so turning tenants on keeps
+// existing counters, unless they start with something that looks like a
+// tenant ID and a '/': those are prefixed with "_/". Without tenants keys are
+// unchanged.
+func tenantKey(ctx context.Context, key string) string {
+	t, ok := ctx.Value(tenantContextKey{}).(*Tenant)
+	switch {
+	case !ok:
+		return key
+	case t.ID == "":
+		if head, _, found := strings.Cut(key, "/"); found && (head == defaultNamespace || tenantIDPattern.MatchString(head)) {
+			return defaultNamespace + "/" + key
+		}
+		return key
+	}
+	return t.ID + "/" + key
+}
+
+// tenantScope is the per-tenant state of a handler: recordings and replay
+// history.
+type tenantScope struct {
+	limitedRec   *RecordingState
+	unlimitedRec *RecordingState
+	replayState  *ReplayState
+	replayJobs   *ReplayJobManager
+}
+
+// tenantScopes creates each tenant's scope on first use. The default
+// namespace uses the scope it is built with, and every tenant's replay jobs
+// share its worker slots.
+type tenantScopes struct {
+	cfg Config
+	clk chronoclock.Clock
+
+	mu     sync.Mutex
+	scopes map[string]*tenantScope
+}
+
+func newTenantScopes(cfg Config, clk chronoclock.Clock, defaultScope *tenantScope) *tenantScopes {
+	return &tenantScopes{cfg: cfg, clk: clk, scopes: map[string]*tenantScope{"": defaultScope}}
+}
+
+// forRequest returns the scope of r's tenant.
+func (s *tenantScopes) forRequest(r *http.Request) *tenantScope {
+	t, _ := TenantFromContext(r.Context())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if scope, ok := s.scopes[t.ID]; ok {
+		return scope
+	}
+	// Tenant replay history lives in a subdirectory, which the default
+	// namespace's history listing skips.
+	replayCfg := s.cfg
+	if dir := strings.TrimSpace(replayCfg.ReplayHistoryDir); dir != "" {
+		replayCfg.ReplayHistoryDir = filepath.Join(dir, "tenants", t.ID)
+	}
+	replayState := NewReplayState(newReplayStore(replayCfg))
+	scope := &tenantScope{
+		limitedRec:   NewRecordingState(nil, true),
+		unlimitedRec: NewRecordingState(nil, true),
+		replayState:  replayState,
+		replayJobs:   s.scopes[""].replayJobs.withState(replayState),
+	}
+	s.scopes[t.ID] = scope
+	return scope
+}
@@ -0,0 +1,204 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+)
+
+func tenantTestConfig(t *testing.T) Config {
+	t.Helper()
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.Rate = 1
+	cfg.Window = time.Minute
+	cfg.Tenants = TenantConfig{
+		Header: "X-Tenant",
+		Tenants: map[string]Tenant{
+			"team-a": {Hosts: []string{"a.example.com"}},
+			"team-b": {PathPrefix: "/team-b", Limits: &PlanLimits{Rate: 3}},
+		},
+	}
+	// httptest requests come from 192.0.2.1; trust it to send X-Tenant.
+	cfg.TrustedProxies = []string{"192.0.2.0/24"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	return cfg
+}
+
+func newTenantTestHandler(t *testing.T) http.Handler {
+	t.Helper()
+	return newAuthTestHandlerAt(t, tenantTestConfig(t), time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC))
+}
+
+func tenantRequest(handler http.Handler, method, target, host, tenant, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("X-API-Key", "client-a")
+	if host != "" {
+		req.Host = host
+	}
+	if tenant != "" {
+		req.Header.Set("X-Tenant", tenant)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	return resp
+}
+
+func TestTenantsNamespaceLimiterAndStorageKeys(t *testing.T) {
+	handler := newTenantTestHandler(t)
+
+	// The same key is limited separately in each namespace.
+	assertStatus(t, tenantRequest(handler, http.MethodGet, "/api/profile", "", "", ""), http.StatusOK)
+	assertStatus(t, tenantRequest(handler, http.MethodGet, "/api/profile", "a.example.com:8080", "", ""), http.StatusOK)
+	assertStatus(t, tenantRequest(handler, http.MethodGet, "/api/profile", "", "team-a", ""), http.StatusTooManyRequests)
+	assertStatus(t, tenantRequest(handler, http.MethodGet, "/api/profile", "", "", ""), http.StatusTooManyRequests)
+
+	// team-b is selected by path prefix, which is stripped before routing, and
+	// has its own limits section.
+	for i := 0; i < 3; i++ {
+		resp := tenantRequest(handler, http.MethodGet, "/team-b/api/profile", "", "", "")
+		assertStatus(t, resp, http.StatusOK)
+		if got := resp.Header().Get("X-RateLimit-Limit"); got != "3" {
+			t.Fatalf("team-b X-RateLimit-Limit = %q, want 3", got)
+		}
+	}
+	assertStatus(t, tenantRequest(handler, http.MethodGet, "/team-b/api/profile", "", "", ""), http.StatusTooManyRequests)
+
+	resp := tenantRequest(handler, http.MethodGet, "/api/profile", "", "team-c", "")
+	assertStatus(t, resp, http.StatusBadRequest)
+	if !strings.Contains(resp.Body.String(), ErrCodeUnknownTenant) {
+		t.Fatalf("unknown tenant body = %s", resp.Body.String())
+	}
+
+	// The header cannot move a request out of its host's or path's tenant.
+	resp = tenantRequest(handler, http.MethodGet, "/team-b/api/profile", "", "team-a", "")
+	assertStatus(t, resp, http.StatusBadRequest)
+	if !strings.Contains(resp.Body.String(), ErrCodeTenantConflict) {
+		t.Fatalf("conflicting tenant body = %s", resp.Body.String())
+	}
+
+	assertStatus(t, tenantRequest(handler, http.MethodPut, "/api/storage/demo", "", "team-a", `{"key":"shared","value":"a"}`), http.StatusOK)
+	resp = tenantRequest(handler, http.MethodGet, "/api/storage/demo?key=shared", "", "team-b", "")
+	assertStatus(t, resp, http.StatusOK)
+	if strings.Contains(resp.Body.String(), `"exists":true`) {
+		t.Fatalf("team-b sees team-a's storage key: %s", resp.Body.String())
+	}
+	resp = tenantRequest(handler, http.MethodGet, "/api/storage/demo?key=shared", "a.example.com", "", "")
+	if !strings.Contains(resp.Body.String(), `"value":"a"`) {
+		t.Fatalf("team-a storage read = %s", resp.Body.String())
+	}
+}
+
+func TestTenantsIsolateRecordings(t *testing.T) {
+	handler := newTenantTestHandler(t)
+
+	tenantRequest(handler, http.MethodGet, "/api/profile", "", "team-a", "")
+	tenantRequest(handler, http.MethodGet, "/team-b/api/profile", "", "", "")
+	tenantRequest(handler, http.MethodGet, "/team-b/api/profile", "", "", "")
+
+	// The envelope records the limits the tenant ran under.
+	for _, tc := range []struct {
+		tenant string
+		want   int
+		rate   int
+	}{{"team-a", 1, 1}, {"team-b", 2, 3}, {"", 0, 1}} {
+		resp := tenantRequest(handler, http.MethodPost, "/api/record/stop", "", tc.tenant, "")
+		assertStatus(t, resp, http.StatusOK)
+		var stopped struct {
+			Config  *RecordingConfig               `json:"config"`
+			Records []chronorecorder.TrafficRecord `json:"records"`
+		}
+		if err := json.Unmarshal(resp.Body.Bytes(), &stopped); err != nil {
+			t.Fatalf("decode stop: %v", err)
+		}
+		if len(stopped.Records) != tc.want {
+			t.Fatalf("tenant %q recorded %d, want %d", tc.tenant, len(stopped.Records), tc.want)
+		}
+		if stopped.Config == nil || stopped.Config.Rate != tc.rate {
+			t.Fatalf("tenant %q config = %+v, want rate %d", tc.tenant, stopped.Config, tc.rate)
+		}
+		for _, rec := range stopped.Records {
+			if rec.Key != "client-a" || rec.Endpoint != "GET /api/profile" {
+				t.Fatalf("tenant %q record = %+v, want the un-namespaced key and route", tc.tenant, rec)
+			}
+		}
+	}
+}
+
+func TestTenantConfigValidate(t *testing.T) {
+	for name, cfg := range map[string]TenantConfig{
+		"bad id":         {Tenants: map[string]Tenant{"team/a": {}}},
+		"shared host":    {Tenants: map[string]Tenant{"a": {Hosts: []string{"x.example.com"}}, "b": {Hosts: []string{"X.example.com"}}}},
+		"trailing slash": {Tenants: map[string]Tenant{"a": {PathPrefix: "/a/"}}},
+		"negative quota": {Tenants: map[string]Tenant{"a": {Quota: &QuotaLimits{Daily: -1}}}},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("%s: Validate() = nil, want error", name)
+		}
+	}
+}
+
+func TestTenantHeaderNeedsTrustedProxy(t *testing.T) {
+	cfg := tenantTestConfig(t)
+	cfg.TrustedProxies = nil
+	handler := newAuthTestHandler(t, cfg)
+
+	// From an untrusted peer the header is ignored: this is the default
+	// namespace, whose budget the first request spends.
+	assertStatus(t, tenantRequest(handler, http.MethodGet, "/api/profile", "", "team-a", ""), http.StatusOK)
+	assertStatus(t, tenantRequest(handler, http.MethodGet, "/api/profile", "", "team-c", ""), http.StatusTooManyRequests)
+	assertStatus(t, tenantRequest(handler, http.MethodGet, "/api/profile", "a.example.com", "", ""), http.StatusOK)
+}
+
+func TestTenantKeysDoNotCollideWithDefaultNamespace(t *testing.T) {
+	handler := newTenantTestHandler(t)
+
+	// "team-a/client-a" in the default namespace is not team-a's client-a.
+	req := httptest.NewRequest(http.MethodGet, "/api/profile", nil)
+	req.Header.Set("X-API-Key", "team-a/client-a")
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, req)
+	assertStatus(t, resp, http.StatusOK)
+	assertStatus(t, tenantRequest(handler, http.MethodGet, "/api/profile", "a.example.com", "", ""), http.StatusOK)
+
+	ctx := context.WithValue(context.Background(), tenantContextKey{}, &Tenant{})
+	if got := tenantKey(ctx, "team-a/client-a"); got != "_/team-a/client-a" {
+		t.Fatalf("default namespace key = %q", got)
+	}
+	if got := tenantKey(ctx, "client-a"); got != "client-a" {
+		t.Fatalf("default namespace key = %q", got)
+	}
+	if got := tenantKey(ctx, "2001:db8:1:2::/64"); got != "2001:db8:1:2::/64" {
+		t.Fatalf("default namespace prefix key = %q", got)
+	}
+	if got := tenantKey(context.Background(), "client-a"); got != "client-a" {
+		t.Fatalf("single-tenant key = %q", got)
+	}
+}
+
+func TestTenantReplayJobsShareWorkers(t *testing.T) {
+	cfg := tenantTestConfig(t)
+	cfg.ReplayWorkers = 1
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 2, 8, 10, 0, 0, 0, time.UTC))
+	base := NewReplayJobManager(cfg.ReplayWorkers, NewReplayState(nil), vc)
+	scopes := newTenantScopes(cfg, vc, &tenantScope{replayJobs: base})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/replay/jobs", nil)
+	req = req.WithContext(context.WithValue(req.Context(), tenantContextKey{}, &Tenant{ID: "team-a"}))
+	jobs := scopes.forRequest(req).replayJobs
+	if jobs == base || cap(jobs.slots) != 1 || jobs.slots != base.slots {
+		t.Fatal("tenant replay jobs do not share the default worker slots")
+	}
+}
@@ -0,0 +1,348 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+)
+
+const (
+	// DistributionPoisson emits requests with exponential inter-arrival times.
+	DistributionPoisson = "poisson"
+	// DistributionBursty alternates Poisson "on" periods with silent "off" periods.
+	DistributionBursty = "bursty"
+	// DistributionDiurnal modulates the Poisson rate with a daily cosine cycle.
+	DistributionDiurnal = "diurnal"
+
+	defaultGenerateMaxRecords = 1000000
+)
+
+// DefaultEndpointMix mirrors ChronoGate's own rate-limited routes.
+var DefaultEndpointMix = []EndpointWeight{
+	{Endpoint: "GET /api/profile", Weight: 0.7},
+	{Endpoint: "POST /api/orders", Weight: 0.3},
+}
+
+// TrafficSpec describes synthetic traffic for GenerateTraffic. Rates are mean
+// requests per second per key.
+type TrafficSpec struct {
+	Start      time.Time        `json:"start"`
+	Duration   SpecDuration     `json:"duration"`
+	Seed       int64            `json:"seed"`
+	Keys       int              `json:"keys"`
+	KeyPrefix  string           `json:"key_prefix"`
+	Rate       float64          `json:"rate"`
+	RateSpread float64          `json:"rate_spread"`
+	Pattern    TrafficPattern   `json:"pattern"`
+	Endpoints  []EndpointWeight `json:"endpoints"`
+	// KeyOverrides replace the shared rate/pattern for individual keys.
+	KeyOverrides []KeyTrafficSpec `json:"key_overrides,omitempty"`
+	MaxRecords   int              `json:"max_records"`
+}
+
+// TrafficPattern selects a rate distribution and its shape parameters.
+type TrafficPattern struct {
+	Distribution string `json:"distribution"`
+
+	// Bursty: mean length of on and off periods.
+	OnPeriod  SpecDuration `json:"on_period,omitempty"`
+	OffPeriod SpecDuration `json:"off_period,omitempty"`
+
+	// Diurnal: cycle length (default 24h), relative amplitude in [0,1] and
+	// offset from Start of the daily peak.
+	Period    SpecDuration `json:"period,omitempty"`
+	Amplitude float64      `json:"amplitude,omitempty"`
+	PeakAt    SpecDuration `json:"peak_at,omitempty"`
+}
+
+// KeyTrafficSpec overrides traffic for one generated key.
+type KeyTrafficSpec struct {
+	Key     string          `json:"key"`
+	Rate    float64         `json:"rate,omitempty"`
+	Pattern *TrafficPattern `json:"pattern,omitempty"`
+}
+
+// EndpointWeight is one entry of the endpoint mix.
+type EndpointWeight struct {
+	Endpoint string  `json:"endpoint"`
+	Weight   float64 `json:"weight"`
+}
+
+// SpecDuration is a time.Duration that reads and writes Go duration strings in JSON.
+type SpecDuration time.Duration
+
+func (d SpecDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *SpecDuration) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("duration must be a string like \"10m\": %w", err)
+	}
+	parsed, err := time.ParseDuration(strings.TrimSpace(raw))
+	if err != nil {
+		return err
+	}
+	*d = SpecDuration(parsed)
+	return nil
+}
+
+// DefaultTrafficSpec returns a small deterministic Poisson spec.
+func DefaultTrafficSpec() TrafficSpec {
+	return TrafficSpec{
+		Duration:  SpecDuration(5 * time.Minute),
+		Seed:      1,
+		Keys:      3,
+		KeyPrefix: "client-",
+		Rate:      1,
+		Pattern: TrafficPattern{
+			Distribution: DistributionPoisson,
+			OnPeriod:     SpecDuration(10 * time.Second),
+			OffPeriod:    SpecDuration(50 * time.Second),
+			Period:       SpecDuration(24 * time.Hour),
+			Amplitude:    0.8,
+		},
+		Endpoints:  append([]EndpointWeight(nil), DefaultEndpointMix...),
+		MaxRecords: defaultGenerateMaxRecords,
+	}
+}
+
+// LoadTrafficSpec reads a JSON spec file on top of DefaultTrafficSpec.
+func LoadTrafficSpec(path string) (TrafficSpec, error) {
+	spec := DefaultTrafficSpec()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return TrafficSpec{}, fmt.Errorf("read traffic spec: %w", err)
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return TrafficSpec{}, fmt.Errorf("decode traffic spec: %w", err)
+	}
+	return spec, nil
+}
+
+// Validate checks the spec before generation.
+func (s TrafficSpec) Validate() error {
+	if s.Keys <= 0 {
+		return fmt.Errorf("keys must be > 0, got %d", s.Keys)
+	}
+	if s.Duration <= 0 {
+		return fmt.Errorf("duration must be > 0, got %s", time.Duration(s.Duration))
+	}
+	if s.Rate <= 0 {
+		return fmt.Errorf("rate must be > 0, got %g", s.Rate)
+	}
+	if s.RateSpread < 0 || s.RateSpread >= 1 {
+		return fmt.Errorf("rate_spread must be in [0,1), got %g", s.RateSpread)
+	}
+	if err := s.Pattern.validate(); err != nil {
+		return err
+	}
+	for _, o := range s.KeyOverrides {
+		if strings.TrimSpace(o.Key) == "" {
+			return fmt.Errorf("key override requires a key")
+		}
+		if o.Rate < 0 {
+			return fmt.Errorf("key %q: rate must be >= 0, got %g", o.Key, o.Rate)
+		}
+		if o.Pattern != nil {
+			if err := o.Pattern.validate(); err != nil {
+				return fmt.Errorf("key %q: %w", o.Key, err)
+			}
+		}
+	}
+	if len(s.Endpoints) == 0 {
+		return fmt.Errorf("endpoint mix must not be empty")
+	}
+	for _, e := range s.Endpoints {
+		if _, _, err := splitEndpoint(e.Endpoint); err != nil {
+			return err
+		}
+		if e.Weight <= 0 {
+			return fmt.Errorf("endpoint %q: weight must be > 0, got %g", e.Endpoint, e.Weight)
+		}
+	}
+	return nil
+}
+
+func (p TrafficPattern) validate() error {
+	switch p.Distribution {
+	case DistributionPoisson:
+	case DistributionBursty:
+		if p.OnPeriod <= 0 || p.OffPeriod <= 0 {
+			return fmt.Errorf("bursty distribution requires on_period and off_period > 0")
+		}
+	case DistributionDiurnal:
+		if p.Period <= 0 {
+			return fmt.Errorf("diurnal distribution requires period > 0")
+		}
+		if p.Amplitude < 0 || p.Amplitude > 1 {
+			return fmt.Errorf("diurnal amplitude must be in [0,1], got %g", p.Amplitude)
+		}
+	default:
+		return fmt.Errorf("invalid distribution %q: use %s|%s|%s", p.Distribution, DistributionPoisson, DistributionBursty, DistributionDiurnal)
+	}
+	return nil
+}
+
+// GenerateTraffic synthesizes timestamp-ordered traffic records from spec. The
+// same spec (including Seed and Start) always yields the same records.
+func GenerateTraffic(spec TrafficSpec) ([]chronorecorder.TrafficRecord, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	if spec.Start.IsZero() {
+		spec.Start = time.Now().UTC().Truncate(time.Second)
+	}
+	if spec.MaxRecords <= 0 {
+		spec.MaxRecords = defaultGenerateMaxRecords
+	}
+
+	overrides := make(map[string]KeyTrafficSpec, len(spec.KeyOverrides))
+	for _, o := range spec.KeyOverrides {
+		overrides[o.Key] = o
+	}
+
+	var totalWeight float64
+	for _, e := range spec.Endpoints {
+		totalWeight += e.Weight
+	}
+
+	duration := time.Duration(spec.Duration).Seconds()
+	var records []chronorecorder.TrafficRecord
+	for i := 0; i < spec.Keys; i++ {
+		key := spec.KeyPrefix + strconv.Itoa(i+1)
+		// Per-key sources keep a key's traffic stable when other keys are added.
+		rng := rand.New(rand.NewSource(spec.Seed*1000003 + int64(i)))
+
+		rate := spec.Rate
+		if spec.RateSpread > 0 {
+			rate *= 1 + spec.RateSpread*(2*rng.Float64()-1)
+		}
+		pattern := spec.Pattern
+		if o, ok := overrides[key]; ok {
+			if o.Rate > 0 {
+				rate = o.Rate
+			}
+			if o.Pattern != nil {
+				pattern = *o.Pattern
+			}
+		}
+
+		for _, offset := range arrivalOffsets(rng, pattern, rate, duration) {
+			if len(records) >= spec.MaxRecords {
+				return nil, fmt.Errorf("generated traffic exceeds max_records=%d; lower rate, keys or duration", spec.MaxRecords)
+			}
+			records = append(records, chronorecorder.TrafficRecord{
+				Timestamp: spec.Start.Add(time.Duration(offset * float64(time.Second))),
+				Key:       key,
+				Endpoint:  pickEndpoint(rng, spec.Endpoints, totalWeight),
+			})
+		}
+	}
+
+	sortRecords(records)
+	return records, nil
+}
+
+// arrivalOffsets returns request offsets in seconds within [0, duration).
+func arrivalOffsets(rng *rand.Rand, p TrafficPattern, rate, duration float64) []float64 {
+	var out []float64
+	switch p.Distribution {
+	case DistributionBursty:
+		on := time.Duration(p.OnPeriod).Seconds()
+		off := time.Duration(p.OffPeriod).Seconds()
+		// Scale the on-period rate so the long-run mean still equals rate.
+		onRate := rate * (on + off) / on
+		t := 0.0
+		for t < duration {
+			end := math.Min(t+rng.ExpFloat64()*on, duration)
+			for next := t + rng.ExpFloat64()/onRate; next < end; next += rng.ExpFloat64() / onRate {
+				out = append(out, next)
+			}
+			t = end + rng.ExpFloat64()*off
+		}
+	case DistributionDiurnal:
+		period := time.Duration(p.Period).Seconds()
+		peak := time.Duration(p.PeakAt).Seconds()
+		maxRate := rate * (1 + p.Amplitude)
+		// Lewis-Shedler thinning of a Poisson process at maxRate.
+		for t := rng.ExpFloat64() / maxRate; t < duration; t += rng.ExpFloat64() / maxRate {
+			current := rate * (1 + p.Amplitude*math.Cos(2*math.Pi*(t-peak)/period))
+			if rng.Float64()*maxRate < current {
+				out = append(out, t)
+			}
+		}
+	default:
+		for t := rng.ExpFloat64() / rate; t < duration; t += rng.ExpFloat64() / rate {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func pickEndpoint(rng *rand.Rand, mix []EndpointWeight, total float64) string {
+	target := rng.Float64() * total
+	for _, e := range mix {
+		target -= e.Weight
+		if target < 0 {
+			return e.Endpoint
+		}
+	}
+	return mix[len(mix)-1].Endpoint
+}
+
+// ParseEndpointMix parses "GET /a=0.7,POST /b=0.3"; a missing weight counts as 1.
+func ParseEndpointMix(raw string) ([]EndpointWeight, error) {
+	var out []EndpointWeight
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		endpoint, weight := part, 1.0
+		if idx := strings.LastIndex(part, "="); idx >= 0 {
+			w, err := strconv.ParseFloat(strings.TrimSpace(part[idx+1:]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid weight in %q: %w", part, err)
+			}
+			endpoint, weight = strings.TrimSpace(part[:idx]), w
+		}
+		out = append(out, EndpointWeight{Endpoint: endpoint, Weight: weight})
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("endpoint mix must not be empty")
+	}
+	return out, nil
+}
+
+// splitEndpoint splits a recorded endpoint ("GET /api/profile") into method and path.
+func splitEndpoint(endpoint string) (string, string, error) {
+	method, path, ok := strings.Cut(strings.TrimSpace(endpoint), " ")
+	if !ok || method == "" || !strings.HasPrefix(path, "/") || strings.ContainsAny(path, " \t") {
+		return "", "", fmt.Errorf("malformed endpoint %q: want \"METHOD /path\"", endpoint)
+	}
+	if strings.ToUpper(method) != method {
+		return "", "", fmt.Errorf("malformed endpoint %q: method must be upper case", endpoint)
+	}
+	return method, path, nil
+}
+
+// WriteRecordsJSON writes records as the legacy (version 0) bare JSON array,
+// which replay and every recordings command still read.
+func WriteRecordsJSON(w io.Writer, records []chronorecorder.TrafficRecord) error {
+	if records == nil {
+		records = []chronorecorder.TrafficRecord{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
@@ -0,0 +1,153 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+)
+
+func TestGenerateTrafficIsDeterministicAndReplayable(t *testing.T) {
+	spec := DefaultTrafficSpec()
+	spec.Start = time.Date(2026, 2, 8, 12, 0, 0, 0, time.UTC)
+	spec.Seed = 42
+	spec.Keys = 3
+	spec.Rate = 2
+	spec.Duration = SpecDuration(10 * time.Minute)
+
+	first, err := GenerateTraffic(spec)
+	if err != nil {
+		t.Fatalf("GenerateTraffic() error = %v", err)
+	}
+	second, err := GenerateTraffic(spec)
+	if err != nil {
+		t.Fatalf("GenerateTraffic() error = %v", err)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Fatal("same spec and seed should generate identical traffic")
+	}
+
+	// 3 keys * 2 rps * 600s = 3600 expected; Poisson noise stays well within 10%.
+	if n := len(first); n < 3240 || n > 3960 {
+		t.Fatalf("len(records) = %d, want about 3600", n)
+	}
+
+	profile := 0
+	for i, rec := range first {
+		if i > 0 && rec.Timestamp.Before(first[i-1].Timestamp) {
+			t.Fatalf("records not in timestamp order at %d", i)
+		}
+		if rec.Timestamp.Before(spec.Start) || !rec.Timestamp.Before(spec.Start.Add(10*time.Minute)) {
+			t.Fatalf("record %d timestamp %s outside spec window", i, rec.Timestamp)
+		}
+		if rec.Endpoint == "GET /api/profile" {
+			profile++
+		}
+	}
+	if share := float64(profile) / float64(len(first)); share < 0.65 || share > 0.75 {
+		t.Fatalf("GET /api/profile share = %.2f, want about 0.7", share)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteRecordsJSON(&buf, first); err != nil {
+		t.Fatalf("WriteRecordsJSON() error = %v", err)
+	}
+	loaded, err := chronorecorder.LoadJSON(&buf)
+	if err != nil {
+		t.Fatalf("LoadJSON() error = %v", err)
+	}
+	summary, err := RunReplayRecords(context.Background(), loaded, ReplayOptions{
+		Algorithm: limiter.AlgorithmFixedWindow,
+		Rate:      60,
+		Window:    time.Minute,
+		Burst:     60,
+	}, nil)
+	if err != nil {
+		t.Fatalf("RunReplayRecords() error = %v", err)
+	}
+	if summary.Replayed != len(first) {
+		t.Fatalf("Replayed = %d, want %d", summary.Replayed, len(first))
+	}
+}
+
+func TestGenerateTrafficPatterns(t *testing.T) {
+	start := time.Date(2026, 2, 8, 0, 0, 0, 0, time.UTC)
+
+	bursty := DefaultTrafficSpec()
+	bursty.Start = start
+	bursty.Keys = 1
+	bursty.Rate = 1
+	bursty.Duration = SpecDuration(time.Hour)
+	bursty.Pattern.Distribution = DistributionBursty
+	bursty.Pattern.OnPeriod = SpecDuration(5 * time.Second)
+	bursty.Pattern.OffPeriod = SpecDuration(55 * time.Second)
+
+	records, err := GenerateTraffic(bursty)
+	if err != nil {
+		t.Fatalf("GenerateTraffic(bursty) error = %v", err)
+	}
+	longGaps := 0
+	for i := 1; i < len(records); i++ {
+		if records[i].Timestamp.Sub(records[i-1].Timestamp) > 20*time.Second {
+			longGaps++
+		}
+	}
+	if longGaps < 20 {
+		t.Fatalf("bursty traffic has %d gaps > 20s, want off periods to show up", longGaps)
+	}
+
+	diurnal := DefaultTrafficSpec()
+	diurnal.Start = start
+	diurnal.Keys = 1
+	diurnal.Rate = 0.1
+	diurnal.Duration = SpecDuration(24 * time.Hour)
+	diurnal.Pattern.Distribution = DistributionDiurnal
+	diurnal.Pattern.Amplitude = 0.9
+	diurnal.Pattern.PeakAt = SpecDuration(12 * time.Hour)
+
+	records, err = GenerateTraffic(diurnal)
+	if err != nil {
+		t.Fatalf("GenerateTraffic(diurnal) error = %v", err)
+	}
+	var night, midday int
+	for _, rec := range records {
+		switch h := rec.Timestamp.Sub(start).Hours(); {
+		case h < 3 || h >= 21:
+			night++
+		case h >= 9 && h < 15:
+			midday++
+		}
+	}
+	if midday < 3*night {
+		t.Fatalf("diurnal midday=%d night=%d, want traffic concentrated around the peak", midday, night)
+	}
+}
+
+func TestGenerateTrafficKeyOverridesAndValidation(t *testing.T) {
+	spec := DefaultTrafficSpec()
+	spec.Start = time.Date(2026, 2, 8, 12, 0, 0, 0, time.UTC)
+	spec.Keys = 2
+	spec.Rate = 0.1
+	spec.KeyOverrides = []KeyTrafficSpec{{Key: "client-2", Rate: 5}}
+
+	records, err := GenerateTraffic(spec)
+	if err != nil {
+		t.Fatalf("GenerateTraffic() error = %v", err)
+	}
+	counts := map[string]int{}
+	for _, rec := range records {
+		counts[rec.Key]++
+	}
+	if counts["client-2"] < 10*counts["client-1"] {
+		t.Fatalf("per-key counts = %v, want client-2 override to dominate", counts)
+	}
+
+	spec.Endpoints = []EndpointWeight{{Endpoint: "/api/profile", Weight: 1}}
+	if _, err := GenerateTraffic(spec); err == nil {
+		t.Fatal("expected malformed endpoint to be rejected")
+	}
+}
@@ -0,0 +1,272 @@
+package app
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+)
+
+// Usage export formats.
+const (
+	UsageFormatJSON = "json"
+	UsageFormatCSV  = "csv"
+)
+
+// UsageConfig turns on usage accounting for billing.
+type UsageConfig struct {
+	// Enabled keeps rollups in memory; File turns accounting on as well.
+	Enabled bool
+	// File persists rollups as JSON lines; empty keeps them in memory.
+	File string
+	// Retention drops rollups older than this; zero keeps them forever.
+	Retention time.Duration
+}
+
+func (c UsageConfig) enabled() bool {
+	return c.Enabled || strings.TrimSpace(c.File) != ""
+}
+
+// Validate rejects a negative retention.
+func (c UsageConfig) Validate() error {
+	if c.Retention < 0 {
+		return fmt.Errorf("retention must be >= 0, got %s", c.Retention)
+	}
+	return nil
+}
+
+// UsageTotals sums the rows of a usage report.
+type UsageTotals struct {
+	Allowed int64 `json:"allowed"`
+	Denied  int64 `json:"denied"`
+}
+
+// UsageReport is the body of GET /api/usage.
+type UsageReport struct {
+	Key    string      `json:"key,omitempty"`
+	From   *time.Time  `json:"from,omitempty"`
+	To     *time.Time  `json:"to,omitempty"`
+	Rows   []UsageRow  `json:"rows"`
+	Totals UsageTotals `json:"totals"`
+}
+
+// NewUsageReport sums rows selected by q.
+func NewUsageReport(q UsageQuery, rows []UsageRow) UsageReport {
+	report := UsageReport{Key: q.Key, Rows: rows}
+	if report.Rows == nil {
+		report.Rows = []UsageRow{}
+	}
+	if !q.From.IsZero() {
+		report.From = &q.From
+	}
+	if !q.To.IsZero() {
+		report.To = &q.To
+	}
+	for _, row := range rows {
+		report.Totals.Allowed += row.Allowed
+		report.Totals.Denied += row.Denied
+	}
+	return report
+}
+
+// WriteUsageCSV writes rows with a header line, hours in RFC3339 UTC.
+func WriteUsageCSV(w io.Writer, rows []UsageRow) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"hour", "key", "route", "allowed", "denied"}); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := cw.Write([]string{
+			row.Hour.UTC().Format(time.RFC3339),
+			row.Key,
+			row.Route,
+			strconv.FormatInt(row.Allowed, 10),
+			strconv.FormatInt(row.Denied, 10),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Usage aggregates allowed and denied requests per key, route and hour. It
+// sees the same records as the recording middleware but runs whether or not
+// a recording is in progress.
+type Usage struct {
+	clk       chronoclock.Clock
+	store     UsageStore
+	retention time.Duration
+
+	mu sync.Mutex
+	// prunedFor is the hour whose expired rollups were last pruned.
+	prunedFor time.Time
+}
+
+// NewUsage returns nil when cfg leaves accounting off. Rollups older than the
+// retention are pruned now and whenever the hour rolls over.
+func NewUsage(cfg UsageConfig, clk chronoclock.Clock, store UsageStore) *Usage {
+	if !cfg.enabled() {
+		return nil
+	}
+	u := &Usage{clk: clk, store: store, retention: cfg.Retention}
+	u.prune(clk.Now())
+	return u
+}
+
+// openUsageStore opens the store usage rollups live in: USAGE_FILE or
+// memory. It is nil when accounting is off.
+func openUsageStore(cfg Config, clk chronoclock.Clock) (UsageStore, error) {
+	if !cfg.Usage.enabled() {
+		return nil, nil
+	}
+	path := strings.TrimSpace(cfg.Usage.File)
+	if path == "" {
+		return NewMemoryUsageStore(), nil
+	}
+	store, err := NewFileUsageStore(path, clk)
+	if err != nil {
+		return nil, fmt.Errorf("usage file %s: %w", path, err)
+	}
+	return store, nil
+}
+
+// newUsage builds the configured accounting on store, the storage set's
+// usage store. Without one, or if it failed to open (which is logged),
+// rollups are kept in memory.
+func newUsage(cfg UsageConfig, clk chronoclock.Clock, store UsageStore, err error) *Usage {
+	if !cfg.enabled() {
+		return nil
+	}
+	if err != nil {
+		log.Printf("%v; usage will not survive a restart", err)
+	}
+	if store == nil {
+		store = NewMemoryUsageStore()
+	}
+	return NewUsage(cfg, clk, store)
+}
+
+// prune drops rollups past the retention once per hour.
+func (u *Usage) prune(now time.Time) {
+	if u.retention <= 0 {
+		return
+	}
+	hour := now.UTC().Truncate(time.Hour)
+	u.mu.Lock()
+	if hour.Equal(u.prunedFor) {
+		u.mu.Unlock()
+		return
+	}
+	u.prunedFor = hour
+	u.mu.Unlock()
+	if err := u.store.Prune(hour.Add(-u.retention)); err != nil {
+		log.Printf("prune usage: %v", err)
+	}
+}
+
+// Record counts rec as allowed or denied in its hour.
+func (u *Usage) Record(rec chronorecorder.TrafficRecord, denied bool) error {
+	u.prune(rec.Timestamp)
+	row := UsageRow{Hour: rec.Timestamp, Key: rec.Key, Route: rec.Endpoint}
+	if denied {
+		row.Denied = 1
+	} else {
+		row.Allowed = 1
+	}
+	return u.store.Add(row)
+}
+
+// Query returns the rollups selected by q.
+func (u *Usage) Query(q UsageQuery) ([]UsageRow, error) {
+	return u.store.Query(q)
+}
+
+type usageDeniedContextKey struct{}
+
+// markUsageDenied tells the usage middleware that the rate limiter or a quota
+// denied the request.
+func markUsageDenied(ctx context.Context) {
+	if denied, ok := ctx.Value(usageDeniedContextKey{}).(*bool); ok {
+		*denied = true
+	}
+}
+
+// Middleware counts every request that reaches it, as denied when the rate
+// limiter or a quota inside it rejected the request.
+func (u *Usage) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := newTrafficRecord(r, u.clk)
+		rec.Key = tenantKey(r.Context(), rec.Key)
+		denied := false
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), usageDeniedContextKey{}, &denied)))
+		if err := u.Record(rec, denied); err != nil {
+			log.Printf("record usage: %v", err)
+		}
+	})
+}
+
+// usageHandler serves GET /api/usage?key=&from=&to=[&format=csv]: the caller's
+// own usage, or any key's (every key's without ?key=) for admins or anyone
+// when auth is off. Keys are read in the caller's tenant namespace.
+func usageHandler(u *Usage, authEnabled bool) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		q := UsageQuery{Key: strings.TrimSpace(query.Get("key"))}
+		if authEnabled && !hasAdminScope(r) {
+			own := clientKeyFromRequest(r)
+			if q.Key != "" && q.Key != own {
+				writeError(w, r, http.StatusForbidden, ErrCodeInsufficientScope, "reading another key's usage requires the admin scope")
+				return
+			}
+			q.Key = own
+		}
+		if t, ok := TenantFromContext(r.Context()); ok {
+			q.Tenant = t.ID
+		}
+		if q.Key != "" {
+			q.Key = tenantKey(r.Context(), q.Key)
+		}
+		for _, bound := range []struct {
+			name string
+			dst  *time.Time
+		}{{"from", &q.From}, {"to", &q.To}} {
+			raw := strings.TrimSpace(query.Get(bound.name))
+			if raw == "" {
+				continue
+			}
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("invalid %s %q: want RFC3339", bound.name, raw))
+				return
+			}
+			*bound.dst = t
+		}
+
+		rows, err := u.Query(q)
+		if err != nil {
+			writeError(w, r, http.StatusServiceUnavailable, ErrCodeUsageUnavailable, err.Error())
+			return
+		}
+		switch format := strings.TrimSpace(query.Get("format")); format {
+		case "", UsageFormatJSON:
+			writeJSON(w, http.StatusOK, NewUsageReport(q, rows))
+		case UsageFormatCSV:
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			if err := WriteUsageCSV(w, rows); err != nil {
+				log.Printf("write usage csv: %v", err)
+			}
+		default:
+			writeError(w, r, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("invalid format %q: use %s|%s", format, UsageFormatJSON, UsageFormatCSV))
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+)
+
+// UsageRow counts one key's requests to one route in one UTC hour.
+type UsageRow struct {
+	Hour    time.Time `json:"hour"`
+	Key     string    `json:"key"`
+	Route   string    `json:"route"`
+	Allowed int64     `json:"allowed"`
+	Denied  int64     `json:"denied"`
+}
+
+// UsageQuery selects usage rows. An hour is included when it starts in
+// [From, To); zero bounds are open.
+type UsageQuery struct {
+	Key string
+	// Tenant keeps only keys in the tenant's namespace.
+	Tenant string
+	From   time.Time
+	To     time.Time
+}
+
+func (q UsageQuery) match(row UsageRow) bool {
+	if q.Key != "" && row.Key != q.Key {
+		return false
+	}
+	if q.Tenant != "" && !strings.HasPrefix(row.Key, q.Tenant+"/") {
+		return false
+	}
+	if !q.From.IsZero() && row.Hour.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !row.Hour.Before(q.To) {
+		return false
+	}
+	return true
+}
+
+// UsageStore persists hourly usage rollups.
+type UsageStore interface {
+	// Add adds row's counts to the rollup of its hour, key and route.
+	Add(row UsageRow) error
+	// Query returns matching rollups ordered by hour, key and route.
+	Query(q UsageQuery) ([]UsageRow, error)
+	// Prune drops rollups of hours before cutoff.
+	Prune(cutoff time.Time) error
+	// Close writes out anything buffered and releases the store.
+	Close() error
+}
+
+type usageBucket struct {
+	hour       int64
+	key, route string
+}
+
+// MemoryUsageStore keeps usage rollups in memory.
+type MemoryUsageStore struct {
+	mu   sync.Mutex
+	rows map[usageBucket]UsageRow
+}
+
+// NewMemoryUsageStore creates an empty in-memory store.
+func NewMemoryUsageStore() *MemoryUsageStore {
+	return &MemoryUsageStore{rows: make(map[usageBucket]UsageRow)}
+}
+
+func (s *MemoryUsageStore) Add(row UsageRow) error {
+	row.Hour = row.Hour.UTC().Truncate(time.Hour)
+	b := usageBucket{row.Hour.Unix(), row.Key, row.Route}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cur, ok := s.rows[b]; ok {
+		row.Allowed += cur.Allowed
+		row.Denied += cur.Denied
+	}
+	s.rows[b] = row
+	return nil
+}
+
+func (s *MemoryUsageStore) Query(q UsageQuery) ([]UsageRow, error) {
+	s.mu.Lock()
+	out := make([]UsageRow, 0, len(s.rows))
+	for _, row := range s.rows {
+		if q.match(row) {
+			out = append(out, row)
+		}
+	}
+	s.mu.Unlock()
+	sortUsageRows(out)
+	return out, nil
+}
+
+func (s *MemoryUsageStore) Prune(cutoff time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for b, row := range s.rows {
+		if row.Hour.Before(cutoff) {
+			delete(s.rows, b)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryUsageStore) Close() error { return nil }
+
+func sortUsageRows(rows []UsageRow) {
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if !a.Hour.Equal(b.Hour) {
+			return a.Hour.Before(b.Hour)
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		return a.Route < b.Route
+	})
+}
+
+// usageFlushInterval is how long FileUsageStore buffers rollup changes
+// before appending them to its file.
+const usageFlushInterval = time.Minute
+
+// FileUsageStore keeps usage rollups in memory and in an append log of
+// UsageRow deltas. Changes are buffered per rollup and appended at most once
+// every usageFlushInterval, so the file grows by rollups, not by requests.
+type FileUsageStore struct {
+	mem *MemoryUsageStore
+	log *appendLog[UsageRow]
+	clk chronoclock.Clock
+
+	mu sync.Mutex
+	// pending holds the deltas not yet appended, one row per rollup.
+	pending   map[usageBucket]UsageRow
+	flushedAt time.Time
+}
+
+// NewFileUsageStore opens (or creates) the usage file at path.
+func NewFileUsageStore(path string, clk chronoclock.Clock) (*FileUsageStore, error) {
+	s := &FileUsageStore{
+		mem:       NewMemoryUsageStore(),
+		clk:       clk,
+		pending:   make(map[usageBucket]UsageRow),
+		flushedAt: clk.Now(),
+	}
+	log, err := openAppendLog("usage", path, func(row UsageRow) { _ = s.mem.Add(row) }, s.rows)
+	if err != nil {
+		return nil, err
+	}
+	s.log = log
+	return s, nil
+}
+
+// LoadUsageFile reads the rollups of a usage file without opening it for
+// writing, so it is safe while a server appends to it. Changes the server
+// has not flushed yet are not included.
+func LoadUsageFile(path string, q UsageQuery) ([]UsageRow, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open usage file: %w", err)
+	}
+	defer f.Close()
+	mem := NewMemoryUsageStore()
+	if err := readAppendLog(f, "usage", func(row UsageRow) { _ = mem.Add(row) }); err != nil {
+		return nil, err
+	}
+	return mem.Query(q)
+}
+
+// rows returns one row per rollup.
+func (s *FileUsageStore) rows() []UsageRow {
+	rows, _ := s.mem.Query(UsageQuery{})
+	return rows
+}
+
+func (s *FileUsageStore) Add(row UsageRow) error {
+	row.Hour = row.Hour.UTC().Truncate(time.Hour)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = s.mem.Add(row)
+	b := usageBucket{row.Hour.Unix(), row.Key, row.Route}
+	if cur, ok := s.pending[b]; ok {
+		row.Allowed += cur.Allowed
+		row.Denied += cur.Denied
+	}
+	s.pending[b] = row
+	if s.clk.Now().Sub(s.flushedAt) < usageFlushInterval {
+		return nil
+	}
+	return s.flushLocked()
+}
+
+// flushLocked appends the pending deltas; on failure they are kept for the
+// next flush. Callers hold s.mu.
+func (s *FileUsageStore) flushLocked() error {
+	s.flushedAt = s.clk.Now()
+	if len(s.pending) == 0 {
+		return nil
+	}
+	rows := make([]UsageRow, 0, len(s.pending))
+	for _, row := range s.pending {
+		rows = append(rows, row)
+	}
+	sortUsageRows(rows)
+	if err := s.log.Append(nil, rows...); err != nil {
+		return err
+	}
+	s.pending = make(map[usageBucket]UsageRow)
+	return nil
+}
+
+func (s *FileUsageStore) Query(q UsageQuery) ([]UsageRow, error) {
+	return s.mem.Query(q)
+}
+
+// Prune compacts the file to the remaining rollups, which also writes out
+// the pending deltas.
+func (s *FileUsageStore) Prune(cutoff time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.log.Compact(func() []UsageRow {
+		_ = s.mem.Prune(cutoff)
+		return s.rows()
+	})
+	if err != nil {
+		return err
+	}
+	s.pending = make(map[usageBucket]UsageRow)
+	s.flushedAt = s.clk.Now()
+	return nil
+}
+
+// Close appends the pending deltas and closes the usage file.
+func (s *FileUsageStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.flushLocked()
+	if closeErr := s.log.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
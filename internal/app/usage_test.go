@@ -0,0 +1,210 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+)
+
+func TestUsageRollsUpPerKeyRouteAndHourWithoutRecording(t *testing.T) {
+	cfg := mustTestConfig(limiter.AlgorithmFixedWindow)
+	cfg.Rate = 2
+	cfg.Window = time.Minute
+	cfg.Usage = UsageConfig{File: filepath.Join(t.TempDir(), "usage.jsonl")}
+
+	start := time.Date(2026, 3, 2, 9, 15, 0, 0, time.UTC)
+	vc := chronoclock.NewVirtualClock(start)
+	// Each handler is a process: building the next closes the stores of
+	// the last, which flushes its buffered rollups.
+	var closeStores func()
+	newHandler := func() http.Handler {
+		if closeStores != nil {
+			closeStores()
+		}
+		mainLimiter, mainStorage, err := NewStorageBackedLimiter(cfg, vc)
+		if err != nil {
+			t.Fatalf("NewStorageBackedLimiter() error = %v", err)
+		}
+		t.Cleanup(func() { _ = mainStorage.Close() })
+		storageSet, cleanup := newMemoryOnlyStorageSet(t, cfg, vc)
+		storageSet.Usage, storageSet.UsageErr = openUsageStore(cfg, vc)
+		closeStores = cleanup
+		return NewHandler(cfg, mainLimiter, vc, chronorecorder.New(nil), storageSet)
+	}
+	handler := newHandler()
+	t.Cleanup(func() { closeStores() })
+
+	// Accounting does not depend on a recording being in progress.
+	assertStatus(t, executeRequest(handler, http.MethodPost, "/api/record/stop", "", "", "", ""), http.StatusOK)
+
+	for i := 0; i < 3; i++ {
+		executeRequest(handler, http.MethodGet, "/api/profile", "billing", "", "", "")
+	}
+	executeRequest(handler, http.MethodGet, "/public", "billing", "", "", "")
+	executeRequest(handler, http.MethodGet, "/api/profile", "other", "", "", "")
+	vc.Advance(time.Hour)
+	executeRequest(handler, http.MethodGet, "/api/profile", "billing", "", "", "")
+
+	// A restart keeps the rollups.
+	handler = newHandler()
+	resp := executeRequest(handler, http.MethodGet, "/api/usage?key=billing", "", "", "", "")
+	assertStatus(t, resp, http.StatusOK)
+	var report UsageReport
+	if err := json.Unmarshal(resp.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decode usage: %v", err)
+	}
+	hour := start.Truncate(time.Hour)
+	want := []UsageRow{
+		{Hour: hour, Key: "billing", Route: "GET /api/profile", Allowed: 2, Denied: 1},
+		{Hour: hour, Key: "billing", Route: "GET /public", Allowed: 1},
+		{Hour: hour.Add(time.Hour), Key: "billing", Route: "GET /api/profile", Allowed: 1},
+	}
+	if len(report.Rows) != len(want) {
+		t.Fatalf("rows = %+v, want %+v", report.Rows, want)
+	}
+	for i := range want {
+		if !report.Rows[i].Hour.Equal(want[i].Hour) || report.Rows[i].Key != want[i].Key || report.Rows[i].Route != want[i].Route ||
+			report.Rows[i].Allowed != want[i].Allowed || report.Rows[i].Denied != want[i].Denied {
+			t.Fatalf("row %d = %+v, want %+v", i, report.Rows[i], want[i])
+		}
+	}
+	if report.Totals != (UsageTotals{Allowed: 4, Denied: 1}) {
+		t.Fatalf("totals = %+v", report.Totals)
+	}
+
+	resp = executeRequest(handler, http.MethodGet, "/api/usage?from="+hour.Add(time.Hour).Format(time.RFC3339)+"&format=csv", "", "", "", "")
+	assertStatus(t, resp, http.StatusOK)
+	wantCSV := "hour,key,route,allowed,denied\n2026-03-02T10:00:00Z,billing,GET /api/profile,1,0\n"
+	if got := resp.Body.String(); got != wantCSV {
+		t.Fatalf("csv = %q, want %q", got, wantCSV)
+	}
+
+	rows, err := LoadUsageFile(cfg.Usage.File, UsageQuery{Key: "other"})
+	if err != nil || len(rows) != 1 || rows[0].Allowed != 1 {
+		t.Fatalf("LoadUsageFile() = %+v, %v", rows, err)
+	}
+
+	resp = executeRequest(handler, http.MethodGet, "/api/usage?to=yesterday", "", "", "", "")
+	assertStatus(t, resp, http.StatusBadRequest)
+}
+
+func TestUsageHandlerScopesNonAdminsToTheirOwnKey(t *testing.T) {
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC))
+	u := NewUsage(UsageConfig{Enabled: true, Retention: 2 * time.Hour}, vc, NewMemoryUsageStore())
+	for _, key := range []string{"key_a", "key_b"} {
+		if err := u.Record(chronorecorder.TrafficRecord{Timestamp: vc.Now(), Key: key, Endpoint: "GET /api/profile"}, false); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	handler := http.HandlerFunc(usageHandler(u, true))
+
+	serve := func(target string, key APIKey) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req.WithContext(WithAPIKey(req.Context(), key)))
+		return resp
+	}
+	resp := serve("/api/usage", APIKey{ID: "key_a"})
+	assertStatus(t, resp, http.StatusOK)
+	if !strings.Contains(resp.Body.String(), `"key":"key_a"`) || strings.Contains(resp.Body.String(), "key_b") {
+		t.Fatalf("own usage = %s", resp.Body.String())
+	}
+	assertStatus(t, serve("/api/usage?key=key_b", APIKey{ID: "key_a"}), http.StatusForbidden)
+
+	resp = serve("/api/usage", APIKey{ID: "ops", Scopes: []string{ScopeAdmin}})
+	var report UsageReport
+	if err := json.Unmarshal(resp.Body.Bytes(), &report); err != nil || len(report.Rows) != 2 {
+		t.Fatalf("admin usage = %s", resp.Body.String())
+	}
+
+	// Rollups past the retention are pruned once the hour rolls over.
+	vc.Advance(3 * time.Hour)
+	_ = u.Record(chronorecorder.TrafficRecord{Timestamp: vc.Now(), Key: "key_a", Endpoint: "GET /api/profile"}, true)
+	if rows, _ := u.Query(UsageQuery{}); len(rows) != 1 || rows[0].Denied != 1 {
+		t.Fatalf("rows after retention = %+v", rows)
+	}
+}
+
+func TestUsageFileBuffersRollups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC))
+	store, err := NewFileUsageStore(path, vc)
+	if err != nil {
+		t.Fatalf("NewFileUsageStore() error = %v", err)
+	}
+	defer store.Close()
+	add := func() {
+		t.Helper()
+		if err := store.Add(UsageRow{Hour: vc.Now(), Key: "k", Route: "GET /api/profile", Allowed: 1}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	lines := func() int {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Count(string(data), "\n")
+	}
+
+	for i := 0; i < 5; i++ {
+		add()
+	}
+	if n := lines(); n != 0 {
+		t.Fatalf("usage file has %d lines before the flush interval, want 0", n)
+	}
+	vc.Advance(usageFlushInterval)
+	add()
+	if n := lines(); n != 1 {
+		t.Fatalf("usage file has %d lines after a flush, want one per rollup", n)
+	}
+	rows, err := LoadUsageFile(path, UsageQuery{})
+	if err != nil || len(rows) != 1 || rows[0].Allowed != 6 {
+		t.Fatalf("LoadUsageFile() = %+v, %v", rows, err)
+	}
+}
+
+func TestUsageFileKeepsAppendingAfterAFailedCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	vc := chronoclock.NewVirtualClock(time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC))
+	store, err := NewFileUsageStore(path, vc)
+	if err != nil {
+		t.Fatalf("NewFileUsageStore() error = %v", err)
+	}
+	hour := vc.Now()
+	if err := store.Add(UsageRow{Hour: hour, Key: "k", Route: "GET /api/profile", Allowed: 1}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	// A directory in the way of the compacted file makes the rewrite fail.
+	if err := os.Mkdir(path+".tmp", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Prune(hour); err == nil {
+		t.Fatal("Prune() = nil, want the compaction error")
+	}
+	if err := store.Add(UsageRow{Hour: hour, Key: "k", Route: "GET /api/profile", Allowed: 1}); err != nil {
+		t.Fatalf("Add() after a failed compaction error = %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	rows, err := LoadUsageFile(path, UsageQuery{})
+	if err != nil {
+		t.Fatalf("LoadUsageFile() error = %v", err)
+	}
+	if len(rows) != 1 || rows[0].Allowed != 2 {
+		t.Fatalf("usage file rows = %+v, want one rollup of 2", rows)
+	}
+}
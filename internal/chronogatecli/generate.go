@@ -0,0 +1,146 @@
+package chronogatecli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/SmitUplenchwar2687/ChronoGate/internal/app"
+	"github.com/spf13/cobra"
+)
+
+func newGenerateCmd() *cobra.Command {
+	var (
+		specPath     string
+		out          string
+		keys         int
+		keyPrefix    string
+		rate         float64
+		rateSpread   float64
+		duration     string
+		start        string
+		seed         int64
+		distribution string
+		onPeriod     string
+		offPeriod    string
+		period       string
+		amplitude    float64
+		peakAt       string
+		endpoints    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "generate",
+		Short: "Synthesize recorded traffic for replay from a traffic spec",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			spec := app.DefaultTrafficSpec()
+			if strings.TrimSpace(specPath) != "" {
+				loaded, err := app.LoadTrafficSpec(strings.TrimSpace(specPath))
+				if err != nil {
+					return err
+				}
+				spec = loaded
+			}
+
+			flags := cmd.Flags()
+			if flags.Changed("keys") {
+				spec.Keys = keys
+			}
+			if flags.Changed("key-prefix") {
+				spec.KeyPrefix = keyPrefix
+			}
+			if flags.Changed("rate") {
+				spec.Rate = rate
+			}
+			if flags.Changed("rate-spread") {
+				spec.RateSpread = rateSpread
+			}
+			if flags.Changed("seed") {
+				spec.Seed = seed
+			}
+			if flags.Changed("distribution") {
+				spec.Pattern.Distribution = strings.TrimSpace(distribution)
+			}
+			if flags.Changed("amplitude") {
+				spec.Pattern.Amplitude = amplitude
+			}
+			for _, d := range []struct {
+				flag   string
+				raw    string
+				target *app.SpecDuration
+			}{
+				{flag: "duration", raw: duration, target: &spec.Duration},
+				{flag: "on-period", raw: onPeriod, target: &spec.Pattern.OnPeriod},
+				{flag: "off-period", raw: offPeriod, target: &spec.Pattern.OffPeriod},
+				{flag: "period", raw: period, target: &spec.Pattern.Period},
+				{flag: "peak-at", raw: peakAt, target: &spec.Pattern.PeakAt},
+			} {
+				if !flags.Changed(d.flag) {
+					continue
+				}
+				parsed, err := time.ParseDuration(strings.TrimSpace(d.raw))
+				if err != nil {
+					return fmt.Errorf("parse --%s: %w", d.flag, err)
+				}
+				*d.target = app.SpecDuration(parsed)
+			}
+			if flags.Changed("start") {
+				parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(start))
+				if err != nil {
+					return fmt.Errorf("parse --start: %w", err)
+				}
+				spec.Start = parsed
+			}
+			if flags.Changed("endpoints") {
+				mix, err := app.ParseEndpointMix(endpoints)
+				if err != nil {
+					return fmt.Errorf("parse --endpoints: %w", err)
+				}
+				spec.Endpoints = mix
+			}
+
+			records, err := app.GenerateTraffic(spec)
+			if err != nil {
+				return err
+			}
+
+			var w io.Writer = cmd.OutOrStdout()
+			if strings.TrimSpace(out) != "" {
+				f, err := os.Create(strings.TrimSpace(out))
+				if err != nil {
+					return fmt.Errorf("create output file: %w", err)
+				}
+				defer f.Close()
+				w = f
+			}
+			if err := app.WriteRecordsJSON(w, records); err != nil {
+				return fmt.Errorf("write records: %w", err)
+			}
+
+			fmt.Fprintf(cmd.ErrOrStderr(), "Generated %d records for %d keys over %s (%s, seed=%d)\n",
+				len(records), spec.Keys, time.Duration(spec.Duration), spec.Pattern.Distribution, spec.Seed)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&specPath, "spec", "", "JSON traffic spec file; flags override its values")
+	cmd.Flags().StringVar(&out, "out", "", "output recordings JSON file (default: stdout)")
+	cmd.Flags().IntVar(&keys, "keys", 0, "number of client keys")
+	cmd.Flags().StringVar(&keyPrefix, "key-prefix", "", "prefix for generated keys (default client-)")
+	cmd.Flags().Float64Var(&rate, "rate", 0, "mean requests per second per key")
+	cmd.Flags().Float64Var(&rateSpread, "rate-spread", 0, "vary per-key rates uniformly by ±fraction of --rate, in [0,1)")
+	cmd.Flags().StringVar(&duration, "duration", "", "traffic duration (default 5m)")
+	cmd.Flags().StringVar(&start, "start", "", "RFC3339 start time (default: now; set for reproducible output)")
+	cmd.Flags().Int64Var(&seed, "seed", 0, "random seed (default 1)")
+	cmd.Flags().StringVar(&distribution, "distribution", "", "rate distribution: poisson|bursty|diurnal")
+	cmd.Flags().StringVar(&onPeriod, "on-period", "", "bursty: mean on-period length (default 10s)")
+	cmd.Flags().StringVar(&offPeriod, "off-period", "", "bursty: mean off-period length (default 50s)")
+	cmd.Flags().StringVar(&period, "period", "", "diurnal: cycle length (default 24h)")
+	cmd.Flags().Float64Var(&amplitude, "amplitude", 0, "diurnal: relative amplitude in [0,1] (default 0.8)")
+	cmd.Flags().StringVar(&peakAt, "peak-at", "", "diurnal: offset from start of the peak")
+	cmd.Flags().StringVar(&endpoints, "endpoints", "", `endpoint mix, e.g. "GET /api/profile=0.7,POST /api/orders=0.3"`)
+
+	return cmd
+}
@@ -0,0 +1,109 @@
+package chronogatecli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	chronoclock "github.com/SmitUplenchwar2687/Chrono/pkg/clock"
+	"github.com/SmitUplenchwar2687/ChronoGate/internal/app"
+	"github.com/spf13/cobra"
+)
+
+func newKeysCmd() *cobra.Command {
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Manage API keys in an API_KEYS_FILE",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if strings.TrimSpace(file) == "" {
+				file = strings.TrimSpace(os.Getenv("API_KEYS_FILE"))
+			}
+			if file == "" {
+				return fmt.Errorf("--file or API_KEYS_FILE is required")
+			}
+			return nil
+		},
+	}
+	cmd.PersistentFlags().StringVar(&file, "file", "", "API keys JSON file (default: API_KEYS_FILE)")
+
+	store := func() *app.FileAPIKeyStore { return app.NewFileAPIKeyStore(file, chronoclock.NewRealClock()) }
+	cmd.AddCommand(newKeysCreateCmd(store))
+	cmd.AddCommand(newKeysListCmd(store))
+	cmd.AddCommand(newKeysRevokeCmd(store))
+
+	return cmd
+}
+
+func newKeysCreateCmd(store func() *app.FileAPIKeyStore) *cobra.Command {
+	var (
+		owner  string
+		tier   string
+		scopes string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a key and print its secret (shown only once)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			key, secret, err := app.NewAPIKey(owner, tier, splitCSV(scopes), time.Now())
+			if err != nil {
+				return err
+			}
+			if err := store().Save(key); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "Created %s for %s; store the secret now, it is not kept:\n", key.ID, key.Owner)
+			fmt.Fprintln(cmd.OutOrStdout(), secret)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&owner, "owner", "", "key owner (required)")
+	cmd.Flags().StringVar(&tier, "tier", "", "key tier, e.g. free or pro")
+	cmd.Flags().StringVar(&scopes, "scopes", "", "comma-separated scopes (admin grants all)")
+	return cmd
+}
+
+func newKeysListCmd(store func() *app.FileAPIKeyStore) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List keys without their hashes",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			keys, err := store().List()
+			if err != nil {
+				return err
+			}
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tOWNER\tTIER\tSCOPES\tREVOKED\tCREATED")
+			for _, key := range keys {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\t%s\n", key.ID, key.Owner, key.Tier, strings.Join(key.Scopes, ","), key.Revoked, key.CreatedAt.Format(time.RFC3339))
+			}
+			return tw.Flush()
+		},
+	}
+}
+
+func newKeysRevokeCmd(store func() *app.FileAPIKeyStore) *cobra.Command {
+	return &cobra.Command{
+		Use:   "revoke ID",
+		Short: "Revoke a key; running servers pick it up on the next request",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, ok, err := store().Revoke(args[0])
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("api key %q not found", args[0])
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Revoked %s\n", args[0])
+			return nil
+		},
+	}
+}
@@ -0,0 +1,351 @@
+package chronogatecli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+	"github.com/SmitUplenchwar2687/ChronoGate/internal/app"
+	"github.com/spf13/cobra"
+)
+
+func newRecordingsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "recordings",
+		Short: "Inspect and transform recordings JSON files",
+	}
+
+	cmd.AddCommand(newRecordingsMergeCmd())
+	cmd.AddCommand(newRecordingsSliceCmd())
+	cmd.AddCommand(newRecordingsAnonymizeCmd())
+	cmd.AddCommand(newRecordingsShiftCmd())
+	cmd.AddCommand(newRecordingsStatsCmd())
+	cmd.AddCommand(newRecordingsValidateCmd())
+
+	return cmd
+}
+
+func newRecordingsMergeCmd() *cobra.Command {
+	var out string
+
+	cmd := &cobra.Command{
+		Use:   "merge FILE [FILE...]",
+		Short: "Merge recordings in timestamp order",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var merged app.RecordingFile
+			sets := make([][]chronorecorder.TrafficRecord, 0, len(args))
+			for i, path := range args {
+				file, err := loadRecording(cmd, path)
+				if err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+				if i == 0 {
+					merged = file
+				}
+				sets = append(sets, file.Records)
+			}
+			merged.Records = app.MergeRecordings(sets...)
+			return writeRecordings(cmd, out, merged)
+		},
+	}
+
+	cmd.Flags().StringVar(&out, "out", "", "output file (default: stdout)")
+	return cmd
+}
+
+func newRecordingsSliceCmd() *cobra.Command {
+	var (
+		out       string
+		from      string
+		to        string
+		keys      string
+		endpoints string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "slice FILE",
+		Short: "Select records by time range, key and endpoint",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := loadRecording(cmd, args[0])
+			if err != nil {
+				return err
+			}
+
+			slice := app.RecordSlice{Keys: splitCSV(keys), Endpoints: splitCSV(endpoints)}
+			if slice.From, err = parseOptionalTime("from", from); err != nil {
+				return err
+			}
+			if slice.To, err = parseOptionalTime("to", to); err != nil {
+				return err
+			}
+			file.Records = app.SliceRecords(file.Records, slice)
+			return writeRecordings(cmd, out, file)
+		},
+	}
+
+	cmd.Flags().StringVar(&out, "out", "", "output file (default: stdout)")
+	cmd.Flags().StringVar(&from, "from", "", "keep records at or after this RFC3339 time")
+	cmd.Flags().StringVar(&to, "to", "", "keep records before this RFC3339 time")
+	cmd.Flags().StringVar(&keys, "keys", "", "comma-separated key filter")
+	cmd.Flags().StringVar(&endpoints, "endpoints", "", "comma-separated endpoint filter")
+	return cmd
+}
+
+func newRecordingsAnonymizeCmd() *cobra.Command {
+	var (
+		out          string
+		mode         string
+		salt         string
+		prefix       string
+		keepMetadata bool
+		mappingOut   string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "anonymize FILE",
+		Short: "Hash or pseudonymize record keys (API keys, IPs)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := loadRecording(cmd, args[0])
+			if err != nil {
+				return err
+			}
+
+			if mode == app.AnonymizeHash && salt == "" {
+				salt = os.Getenv("CHRONOGATE_ANONYMIZE_SALT")
+			}
+			anonymized, mapping, err := app.AnonymizeRecords(file.Records, app.AnonymizeOptions{
+				Mode:         strings.TrimSpace(mode),
+				Salt:         salt,
+				Prefix:       prefix,
+				KeepMetadata: keepMetadata,
+			})
+			if err != nil {
+				return err
+			}
+
+			if strings.TrimSpace(mappingOut) != "" {
+				if err := writeKeyMapping(strings.TrimSpace(mappingOut), mapping); err != nil {
+					return err
+				}
+			}
+			file.Records = anonymized
+			file.Host = ""
+			return writeRecordings(cmd, out, file)
+		},
+	}
+
+	cmd.Flags().StringVar(&out, "out", "", "output file (default: stdout)")
+	cmd.Flags().StringVar(&mode, "mode", app.AnonymizePseudonym, "anonymization mode: hash|pseudonym")
+	cmd.Flags().StringVar(&salt, "salt", "", "hash mode secret (default: CHRONOGATE_ANONYMIZE_SALT)")
+	cmd.Flags().StringVar(&prefix, "prefix", "", "pseudonym prefix (default key-)")
+	cmd.Flags().BoolVar(&keepMetadata, "keep-metadata", false, "keep record metadata (may contain identifiers)")
+	cmd.Flags().StringVar(&mappingOut, "mapping-out", "", "write the original-to-anonymized key mapping to this JSON file")
+	return cmd
+}
+
+func newRecordingsShiftCmd() *cobra.Command {
+	var (
+		out   string
+		shift string
+		scale float64
+		start string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "shift FILE",
+		Short: "Time-shift and/or scale record timestamps",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := loadRecording(cmd, args[0])
+			if err != nil {
+				return err
+			}
+
+			transform := app.TimeTransform{Scale: scale}
+			if strings.TrimSpace(shift) != "" {
+				transform.Shift, err = time.ParseDuration(strings.TrimSpace(shift))
+				if err != nil {
+					return fmt.Errorf("parse --by: %w", err)
+				}
+			}
+			if transform.Start, err = parseOptionalTime("start", start); err != nil {
+				return err
+			}
+
+			if file.Records, err = app.TransformTimestamps(file.Records, transform); err != nil {
+				return err
+			}
+			return writeRecordings(cmd, out, file)
+		},
+	}
+
+	cmd.Flags().StringVar(&out, "out", "", "output file (default: stdout)")
+	cmd.Flags().StringVar(&shift, "by", "", "shift all timestamps by this duration (may be negative, e.g. -24h)")
+	cmd.Flags().Float64Var(&scale, "scale", 1, "multiply gaps between records (0.5 = twice as dense)")
+	cmd.Flags().StringVar(&start, "start", "", "move the first record to this RFC3339 time")
+	return cmd
+}
+
+func newRecordingsStatsCmd() *cobra.Command {
+	var (
+		asJSON   bool
+		prefixes app.IPKeyPrefixes
+	)
+
+	cmd := &cobra.Command{
+		Use:   "stats FILE",
+		Short: "Print records per key, per endpoint and peak RPS",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := loadRecording(cmd, args[0])
+			if err != nil {
+				return err
+			}
+
+			if err := prefixes.Validate(); err != nil {
+				return err
+			}
+			stats := app.ComputeRecordingStats(app.AggregateIPKeys(file.Records, prefixes))
+			if asJSON {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(stats)
+			}
+			app.PrintRecordingStats(cmd.OutOrStdout(), stats)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print stats as JSON")
+	cmd.Flags().IntVar(&prefixes.IPv4, "ipv4-prefix", 0, "group IPv4 keys by this prefix length, e.g. 24")
+	cmd.Flags().IntVar(&prefixes.IPv6, "ipv6-prefix", 0, "group IPv6 keys by this prefix length, e.g. 64")
+	return cmd
+}
+
+func newRecordingsValidateCmd() *cobra.Command {
+	var (
+		strict    bool
+		asJSON    bool
+		endpoints string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "validate FILE",
+		Short: "Check a recording for zero timestamps, bad ordering and unknown or malformed endpoints",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				return fmt.Errorf("open recording file: %w", err)
+			}
+			file, validation, err := app.ReadRecordingFileWithEndpoints(data, splitCSV(endpoints))
+			if err != nil {
+				return err
+			}
+
+			if asJSON {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(validation); err != nil {
+					return err
+				}
+			} else {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s: format version %d, %d records, %d errors, %d warnings\n",
+					args[0], file.Version, validation.Records, validation.Errors, validation.Warnings)
+				for _, issue := range validation.Issues {
+					fmt.Fprintf(cmd.OutOrStdout(), "  %s\n", issue)
+				}
+			}
+
+			if validation.Err(strict) != nil {
+				return fmt.Errorf("%s failed validation", args[0])
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&strict, "strict", false, "treat warnings (out-of-order records, unknown endpoints) as failures")
+	cmd.Flags().BoolVar(&asJSON, "json", false, "print the validation report as JSON")
+	cmd.Flags().StringVar(&endpoints, "endpoints", "", "comma-separated known endpoints (default: the recording's captured routes, else the gateway routes)")
+	return cmd
+}
+
+// loadRecording reads a recording leniently: these tools are how problem files
+// get fixed, so only unreadable JSON stops them.
+func loadRecording(cmd *cobra.Command, path string) (app.RecordingFile, error) {
+	file, validation, err := app.LoadRecordingFile(path)
+	if err != nil {
+		return app.RecordingFile{}, err
+	}
+	if validation.Errors > 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s has %d record errors; see `chronogate recordings validate %s`\n", path, validation.Errors, path)
+	}
+	return file, nil
+}
+
+// writeRecordings keeps the input's format: legacy arrays stay arrays and
+// versioned recordings keep their envelope.
+func writeRecordings(cmd *cobra.Command, outPath string, file app.RecordingFile) error {
+	var w io.Writer = cmd.OutOrStdout()
+	if strings.TrimSpace(outPath) != "" {
+		f, err := os.Create(strings.TrimSpace(outPath))
+		if err != nil {
+			return fmt.Errorf("create output file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	var err error
+	if file.Version == 0 {
+		err = app.WriteRecordsJSON(w, file.Records)
+	} else {
+		err = app.WriteRecordingFile(w, file)
+	}
+	if err != nil {
+		return fmt.Errorf("write records: %w", err)
+	}
+	if strings.TrimSpace(outPath) != "" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Wrote %d records to %s\n", len(file.Records), outPath)
+	}
+	return nil
+}
+
+func writeKeyMapping(path string, mapping map[string]string) error {
+	type entry struct {
+		Original   string `json:"original"`
+		Anonymized string `json:"anonymized"`
+	}
+	entries := make([]entry, 0, len(mapping))
+	for original, anonymized := range mapping {
+		entries = append(entries, entry{Original: original, Anonymized: anonymized})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Anonymized < entries[j].Anonymized })
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode key mapping: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("write key mapping: %w", err)
+	}
+	return nil
+}
+
+func parseOptionalTime(flag, raw string) (time.Time, error) {
+	if strings.TrimSpace(raw) == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, strings.TrimSpace(raw))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse --%s: %w", flag, err)
+	}
+	return t, nil
+}
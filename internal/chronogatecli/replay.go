@@ -2,10 +2,13 @@ package chronogatecli
 
 import (
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/SmitUplenchwar2687/Chrono/pkg/limiter"
+	chronorecorder "github.com/SmitUplenchwar2687/Chrono/pkg/recorder"
+	chronoreplay "github.com/SmitUplenchwar2687/Chrono/pkg/replay"
 	"github.com/SmitUplenchwar2687/ChronoGate/internal/app"
 	"github.com/spf13/cobra"
 )
@@ -20,6 +23,14 @@ func newReplayCmd() *cobra.Command {
 		speed      float64
 		keys       string
 		endpoints  string
+		storage    string
+		compare    string
+		timeline   string
+		bucket     string
+		timeOut    string
+		historyDir string
+		strict     bool
+		baseline   bool
 		configPath string
 	)
 
@@ -32,19 +43,47 @@ func newReplayCmd() *cobra.Command {
 				return fmt.Errorf("load config: %w", err)
 			}
 
-			algoValue := cfg.Algorithm
+			recording, err := loadReplayRecording(cmd, strings.TrimSpace(file), strict)
+			if err != nil {
+				return err
+			}
+			records := recording.Records
+
+			// Limits captured with the recording take precedence over the
+			// config file; flags override both.
+			defaults := app.ReplayOptions{Algorithm: cfg.Algorithm, Rate: cfg.Rate, Window: cfg.Window, Burst: cfg.Burst}
+			var captured *app.ReplayOptions
+			if recording.Config != nil {
+				opts, err := recording.Config.ReplayOptions()
+				if err != nil {
+					return err
+				}
+				defaults = opts
+				captured = &opts
+				fmt.Fprintf(cmd.ErrOrStderr(), "Recording limits: %s rate=%d window=%s burst=%d\n", opts.Algorithm, opts.Rate, opts.Window, opts.Burst)
+			}
+			if baseline {
+				if captured == nil {
+					return fmt.Errorf("--baseline needs a recording exported with its config (format version 1 or later)")
+				}
+				if cmd.Flags().Changed("compare") || cmd.Flags().Changed("timeline") {
+					return fmt.Errorf("--baseline cannot be combined with --compare or --timeline")
+				}
+			}
+
+			algoValue := defaults.Algorithm
 			if cmd.Flags().Changed("algorithm") {
 				algoValue = limiter.Algorithm(strings.TrimSpace(algorithm))
 			}
-			rateValue := cfg.Rate
+			rateValue := defaults.Rate
 			if cmd.Flags().Changed("rate") {
 				rateValue = rate
 			}
-			burstValue := cfg.Burst
+			burstValue := defaults.Burst
 			if cmd.Flags().Changed("burst") {
 				burstValue = burst
 			}
-			windowValue := cfg.Window
+			windowValue := defaults.Window
 			if cmd.Flags().Changed("window") {
 				parsed, parseErr := time.ParseDuration(strings.TrimSpace(window))
 				if parseErr != nil {
@@ -53,6 +92,11 @@ func newReplayCmd() *cobra.Command {
 				windowValue = parsed
 			}
 
+			storageValue := ""
+			if cmd.Flags().Changed("storage-backend") {
+				storageValue = strings.TrimSpace(storage)
+			}
+
 			tmp := app.Config{
 				Algorithm:      algoValue,
 				Rate:           rateValue,
@@ -62,11 +106,14 @@ func newReplayCmd() *cobra.Command {
 				StorageBackend: cfg.StorageBackend,
 				Storage:        cfg.Storage,
 			}
+			if storageValue != "" {
+				tmp.StorageBackend = storageValue
+			}
 			if err := tmp.Validate(); err != nil {
 				return err
 			}
 
-			_, err = app.RunReplay(cmd.Context(), app.ReplayOptions{
+			opts := app.ReplayOptions{
 				File:      strings.TrimSpace(file),
 				Algorithm: algoValue,
 				Rate:      rateValue,
@@ -75,8 +122,53 @@ func newReplayCmd() *cobra.Command {
 				Speed:     speed,
 				Keys:      splitCSV(keys),
 				Endpoints: splitCSV(endpoints),
-			}, cmd.OutOrStdout())
-			return err
+
+				StorageBackend: storageValue,
+				Storage:        cfg.Storage,
+			}
+
+			historyValue := cfg.ReplayHistoryDir
+			if cmd.Flags().Changed("history-dir") {
+				historyValue = strings.TrimSpace(historyDir)
+			}
+
+			if baseline {
+				comparison, err := app.RunReplayBaseline(cmd.Context(), records, *captured, opts, cmd.OutOrStdout())
+				if err != nil {
+					return err
+				}
+				return saveComparisonHistory(cmd, historyValue, records, comparison)
+			}
+
+			if cmd.Flags().Changed("compare") {
+				algos, parseErr := app.ParseAlgorithmList(splitCSV(compare))
+				if parseErr != nil {
+					return fmt.Errorf("parse --compare: %w", parseErr)
+				}
+				for _, algo := range algos {
+					tmp.Algorithm = algo
+					if err := tmp.Validate(); err != nil {
+						return err
+					}
+				}
+				opts.Compare = algos
+
+				comparison, err := app.RunReplayComparison(cmd.Context(), records, opts, cmd.OutOrStdout())
+				if err != nil {
+					return err
+				}
+				return saveComparisonHistory(cmd, historyValue, records, comparison)
+			}
+
+			if cmd.Flags().Changed("timeline") {
+				return runReplayTimeline(cmd, records, opts, timeline, bucket, timeOut, historyValue)
+			}
+
+			summary, err := app.RunReplayRecords(cmd.Context(), records, opts, cmd.OutOrStdout())
+			if err != nil {
+				return err
+			}
+			return saveReplayHistory(cmd, historyValue, records, opts, summary)
 		},
 	}
 
@@ -88,12 +180,120 @@ func newReplayCmd() *cobra.Command {
 	cmd.Flags().Float64Var(&speed, "speed", 0, "replay speed multiplier (0 = instant)")
 	cmd.Flags().StringVar(&keys, "keys", "", "comma-separated key filter")
 	cmd.Flags().StringVar(&endpoints, "endpoints", "", "comma-separated endpoint filter")
+	cmd.Flags().StringVar(&storage, "storage-backend", "", "replay through a storage-backed limiter: memory|redis|crdt (default: direct algorithm)")
+	cmd.Flags().StringVar(&compare, "compare", "", "comma-separated algorithms to replay side-by-side, e.g. token_bucket,sliding_window,fixed_window")
+	cmd.Flags().StringVar(&timeline, "timeline", "", "emit a per-bucket allowed/denied time series: csv|json")
+	cmd.Flags().StringVar(&bucket, "timeline-bucket", "1m", "timeline bucket size")
+	cmd.Flags().StringVar(&timeOut, "timeline-out", "", "write the timeline to this file (default: stdout, summary goes to stderr)")
+	cmd.Flags().StringVar(&historyDir, "history-dir", "", "also store the run in this replay history directory (default: REPLAY_HISTORY_DIR)")
+	cmd.Flags().BoolVar(&baseline, "baseline", false, "compare this replay against the limits captured with the recording")
+	cmd.Flags().BoolVar(&strict, "strict", false, "refuse recordings with validation warnings (out-of-order records, unknown endpoints)")
 	cmd.Flags().StringVar(&configPath, "config", "", "path to Chrono JSON config file")
 	_ = cmd.MarkFlagRequired("file")
 
 	return cmd
 }
 
+func runReplayTimeline(cmd *cobra.Command, records []chronorecorder.TrafficRecord, opts app.ReplayOptions, format, bucket, outPath, historyDir string) error {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format != app.TimelineFormatCSV && format != app.TimelineFormatJSON {
+		return fmt.Errorf("invalid --timeline %q: use csv or json", format)
+	}
+	size, err := time.ParseDuration(strings.TrimSpace(bucket))
+	if err != nil {
+		return fmt.Errorf("parse --timeline-bucket: %w", err)
+	}
+	opts.TimelineBucket = size
+	opts.TimelineFormat = format
+
+	// Keep stdout clean for the series when it is not going to a file.
+	summaryOut := cmd.OutOrStdout()
+	if strings.TrimSpace(outPath) == "" {
+		summaryOut = cmd.ErrOrStderr()
+	}
+	summary, series, err := app.RunReplayTimeline(cmd.Context(), records, opts, summaryOut)
+	if err != nil {
+		return err
+	}
+	if err := saveReplayHistory(cmd, historyDir, records, opts, summary); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(outPath) == "" {
+		return app.WriteTimeline(cmd.OutOrStdout(), series, format)
+	}
+
+	f, err := os.Create(strings.TrimSpace(outPath))
+	if err != nil {
+		return fmt.Errorf("create timeline file: %w", err)
+	}
+	if err := app.WriteTimeline(f, series, format); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write timeline: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close timeline file: %w", err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Timeline written to %s\n", outPath)
+	return nil
+}
+
+// loadReplayRecording validates the recording before replay: record errors
+// (and warnings under --strict) fail, remaining warnings are printed to stderr.
+func loadReplayRecording(cmd *cobra.Command, path string, strict bool) (app.RecordingFile, error) {
+	file, validation, err := app.LoadRecordingFile(path)
+	if err != nil {
+		return app.RecordingFile{}, err
+	}
+	if err := validation.Err(strict); err != nil {
+		return app.RecordingFile{}, err
+	}
+	for _, issue := range validation.WarningIssues() {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s: %s\n", path, issue)
+	}
+	return file, nil
+}
+
+func saveReplayHistory(cmd *cobra.Command, dir string, records []chronorecorder.TrafficRecord, opts app.ReplayOptions, summary *chronoreplay.Summary) error {
+	if strings.TrimSpace(dir) == "" {
+		return nil
+	}
+
+	run, err := app.NewReplayRun(records, opts, summary, time.Now())
+	if err != nil {
+		return err
+	}
+	return saveReplayRuns(cmd, dir, []app.ReplayRun{run}, nil)
+}
+
+// saveComparisonHistory stores one run per compared setting.
+func saveComparisonHistory(cmd *cobra.Command, dir string, records []chronorecorder.TrafficRecord, comparison *app.ReplayComparison) error {
+	if strings.TrimSpace(dir) == "" {
+		return nil
+	}
+
+	runs, err := app.NewComparisonRuns(records, comparison, time.Now())
+	if err != nil {
+		return err
+	}
+	return saveReplayRuns(cmd, dir, runs, comparison.Labels)
+}
+
+func saveReplayRuns(cmd *cobra.Command, dir string, runs []app.ReplayRun, labels []string) error {
+	store := app.NewFileReplayStore(dir, 0)
+	for i, run := range runs {
+		if err := store.Save(run); err != nil {
+			return fmt.Errorf("save replay history: %w", err)
+		}
+		if labels != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Replay %s (%s) saved to %s\n", run.ID, labels[i], dir)
+			continue
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Replay %s saved to %s\n", run.ID, dir)
+	}
+	return nil
+}
+
 func splitCSV(raw string) []string {
 	trimmed := strings.TrimSpace(raw)
 	if trimmed == "" {
@@ -23,6 +23,10 @@ func NewRootCmd() *cobra.Command {
 
 	root.AddCommand(newServeCmd())
 	root.AddCommand(newReplayCmd())
+	root.AddCommand(newGenerateCmd())
+	root.AddCommand(newRecordingsCmd())
+	root.AddCommand(newKeysCmd())
+	root.AddCommand(newUsageCmd())
 
 	sdk := chronocli.NewRootCmd()
 	sdk.Use = "chrono-sdk"
@@ -30,6 +30,9 @@ func newServeCmd() *cobra.Command {
 		configPath  string
 		embedChrono bool
 		chronoAddr  string
+		historyDir  string
+		workers     int
+		routesPath  string
 	)
 
 	cmd := &cobra.Command{
@@ -67,6 +70,18 @@ func newServeCmd() *cobra.Command {
 			if cmd.Flags().Changed("config") {
 				cfg.ConfigPath = strings.TrimSpace(configPath)
 			}
+			if cmd.Flags().Changed("replay-history-dir") {
+				cfg.ReplayHistoryDir = strings.TrimSpace(historyDir)
+			}
+			if cmd.Flags().Changed("replay-workers") {
+				cfg.ReplayWorkers = workers
+			}
+			if cmd.Flags().Changed("routes") {
+				cfg.Routes, err = app.LoadRouteTable(strings.TrimSpace(routesPath))
+				if err != nil {
+					return err
+				}
+			}
 
 			if err := cfg.Validate(); err != nil {
 				return err
@@ -83,6 +98,9 @@ func newServeCmd() *cobra.Command {
 	cmd.Flags().StringVar(&window, "window", "", "rate limit window duration")
 	cmd.Flags().IntVar(&burst, "burst", 0, "token bucket burst size")
 	cmd.Flags().StringVar(&storage, "storage-backend", "", "storage backend: memory|redis|crdt")
+	cmd.Flags().StringVar(&historyDir, "replay-history-dir", "", "persist replay runs as JSON files in this directory (default: in memory)")
+	cmd.Flags().IntVar(&workers, "replay-workers", 0, "maximum concurrent asynchronous replay jobs")
+	cmd.Flags().StringVar(&routesPath, "routes", "", "JSON routes file overriding which routes are limited and recorded (default: ROUTES_FILE)")
 	cmd.Flags().BoolVar(&embedChrono, "embed-chrono", false, "start Chrono SDK server alongside ChronoGate")
 	cmd.Flags().StringVar(&chronoAddr, "chrono-addr", ":9090", "embedded Chrono server address")
 
@@ -111,6 +129,9 @@ func runServe(ctx context.Context, cfg app.Config, embedChrono bool, chronoAddr
 			_ = storageSet.Close()
 		}
 	}()
+	if storageSet.QuotaErr != nil {
+		return fmt.Errorf("open quota store: %w", storageSet.QuotaErr)
+	}
 
 	rec := chronorecorder.New(nil)
 	handler := app.NewHandler(cfg, mainLimiter, clk, rec, storageSet)
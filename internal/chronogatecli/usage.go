@@ -0,0 +1,96 @@
+package chronogatecli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/SmitUplenchwar2687/ChronoGate/internal/app"
+	"github.com/spf13/cobra"
+)
+
+func newUsageCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Report usage rollups from a USAGE_FILE",
+	}
+
+	cmd.AddCommand(newUsageExportCmd())
+	return cmd
+}
+
+func newUsageExportCmd() *cobra.Command {
+	var (
+		file   string
+		format string
+		out    string
+		key    string
+		from   string
+		to     string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export allowed and denied requests per key, route and hour",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if strings.TrimSpace(file) == "" {
+				file = strings.TrimSpace(os.Getenv("USAGE_FILE"))
+			}
+			if file == "" {
+				return fmt.Errorf("--file or USAGE_FILE is required")
+			}
+			format = strings.TrimSpace(format)
+			if format != app.UsageFormatCSV && format != app.UsageFormatJSON {
+				return fmt.Errorf("invalid --format %q: use %s|%s", format, app.UsageFormatCSV, app.UsageFormatJSON)
+			}
+
+			q := app.UsageQuery{Key: strings.TrimSpace(key)}
+			var err error
+			if q.From, err = parseOptionalTime("from", from); err != nil {
+				return err
+			}
+			if q.To, err = parseOptionalTime("to", to); err != nil {
+				return err
+			}
+			rows, err := app.LoadUsageFile(file, q)
+			if err != nil {
+				return err
+			}
+
+			var w io.Writer = cmd.OutOrStdout()
+			if strings.TrimSpace(out) != "" {
+				f, err := os.Create(strings.TrimSpace(out))
+				if err != nil {
+					return fmt.Errorf("create output file: %w", err)
+				}
+				defer f.Close()
+				w = f
+			}
+			if format == app.UsageFormatCSV {
+				err = app.WriteUsageCSV(w, rows)
+			} else {
+				enc := json.NewEncoder(w)
+				enc.SetIndent("", "  ")
+				err = enc.Encode(app.NewUsageReport(q, rows))
+			}
+			if err != nil {
+				return fmt.Errorf("write usage: %w", err)
+			}
+			if strings.TrimSpace(out) != "" {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Wrote %d usage rows to %s\n", len(rows), out)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&file, "file", "", "usage JSON-lines file (default: USAGE_FILE)")
+	cmd.Flags().StringVar(&format, "format", app.UsageFormatCSV, "output format: csv|json")
+	cmd.Flags().StringVar(&out, "out", "", "output file (default: stdout)")
+	cmd.Flags().StringVar(&key, "key", "", "only this rate-limit key")
+	cmd.Flags().StringVar(&from, "from", "", "keep hours starting at or after this RFC3339 time")
+	cmd.Flags().StringVar(&to, "to", "", "keep hours starting before this RFC3339 time")
+	return cmd
+}